@@ -0,0 +1,171 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/axpira/backend/entity"
+)
+
+// CSVMapping says in which column, counting from 0, each field of the expense is
+type CSVMapping struct {
+	// Comma separates the columns, ',' when 0
+	Comma rune
+	// Header skips the first row
+	Header     bool
+	DateColumn int
+	// DateFormat is like dd/mm/yyyy, with yyyy, yy, mm and dd, yyyy-mm-dd when empty
+	DateFormat   string
+	AmountColumn int
+	// DecimalSeparator is "." or ",", the other one is the thousands separator, like 1.234,56
+	DecimalSeparator  string
+	DescriptionColumn int
+	Currency          entity.Currency
+	// PaymentMethodId is the account of the statement, set on every expense
+	PaymentMethodId string
+	// Signed amounts are negative on debits, which are expenses, and positive on credits, which are incomes.
+	// Every row is an expense when it's not set
+	Signed bool
+}
+
+var dateFormatTokens = strings.NewReplacer("yyyy", "2006", "yy", "06", "mm", "01", "dd", "02")
+
+func (m CSVMapping) Validate() error {
+	var err error
+	if m.DateColumn < 0 {
+		err = entity.NewFieldError(err, "dateColumn", "invalid", "can't be negative")
+	}
+	if m.AmountColumn < 0 {
+		err = entity.NewFieldError(err, "amountColumn", "invalid", "can't be negative")
+	}
+	if m.DescriptionColumn < 0 {
+		err = entity.NewFieldError(err, "descriptionColumn", "invalid", "can't be negative")
+	}
+	if m.DateFormat != "" && dateFormatTokens.Replace(m.DateFormat) == m.DateFormat {
+		err = entity.NewFieldError(err, "dateFormat", "invalid", "use yyyy, yy, mm and dd like dd/mm/yyyy")
+	}
+	if m.DecimalSeparator != "" && m.DecimalSeparator != "." && m.DecimalSeparator != "," {
+		err = entity.NewFieldError(err, "decimalSeparator", "invalid", `must be "." or ","`)
+	}
+	if !m.Currency.IsValid() {
+		err = entity.NewFieldError(err, "currency", "invalid", fmt.Sprintf("unknown ISO 4217 currency %q", m.Currency))
+	}
+	return err
+}
+
+func (m CSVMapping) dateLayout() string {
+	if m.DateFormat == "" {
+		return "2006-01-02"
+	}
+	return dateFormatTokens.Replace(m.DateFormat)
+}
+
+// normalizeDecimal drops the thousands separator and turns the decimal separator into a dot
+func (m CSVMapping) normalizeDecimal(value string) string {
+	value = strings.TrimSpace(value)
+	if m.DecimalSeparator == "," {
+		return strings.ReplaceAll(strings.ReplaceAll(value, ".", ""), ",", ".")
+	}
+	return strings.ReplaceAll(value, ",", "")
+}
+
+// ParseCSV reads every row of r into a record, the rows that aren't expenses have their FieldErrors.
+// It fails only when the mapping is invalid or r can't be read
+func ParseCSV(r io.Reader, mapping CSVMapping) ([]Record, error) {
+	if err := mapping.Validate(); err != nil {
+		return nil, err
+	}
+	reader := csv.NewReader(r)
+	if mapping.Comma != 0 {
+		reader.Comma = mapping.Comma
+	}
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	records := make([]Record, 0)
+	for row := 1; ; row++ {
+		columns, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			records = append(records, Record{Row: row, Err: entity.NewFieldError(nil, "row", "invalid", parseErr.Err.Error())})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if row == 1 && mapping.Header {
+			continue
+		}
+		records = append(records, mapping.record(row, columns))
+	}
+}
+
+func (m CSVMapping) record(row int, columns []string) Record {
+	if isBlank(columns) {
+		return Record{Row: row, Skip: true}
+	}
+	var err error
+	column := func(field string, i int) string {
+		if i >= len(columns) {
+			err = entity.NewFieldError(err, field, "missing", fmt.Sprintf("row has no column %d", i))
+			return ""
+		}
+		return strings.TrimSpace(columns[i])
+	}
+	date, amount, description := column("date", m.DateColumn), column("amount", m.AmountColumn), column("description", m.DescriptionColumn)
+	if err != nil {
+		return Record{Row: row, Err: err}
+	}
+
+	expense := entity.Expense{
+		Kind:            entity.EXPENSE,
+		Currency:        m.Currency,
+		What:            description,
+		PaymentMethodId: m.PaymentMethodId,
+	}
+	when, dateErr := time.Parse(m.dateLayout(), date)
+	if dateErr != nil {
+		err = entity.NewFieldError(err, "date", "invalid", fmt.Sprintf("%q is not like %s", date, m.dateFormat()))
+	}
+	expense.When = when.UTC()
+	money, amountErr := entity.ParseMoney(m.normalizeDecimal(amount), m.Currency)
+	err = entity.MergeFieldErrors(err, entity.RenameFieldError(amountErr, "amount"))
+	expense.Amount = money.Amount
+	if m.Signed && expense.Amount > 0 {
+		expense.Kind = entity.INCOME
+	}
+	if expense.Amount < 0 {
+		expense.Amount = -expense.Amount
+	}
+	if expense.Amount == 0 && amountErr == nil {
+		err = entity.NewFieldError(err, "amount", "invalid", "can't be zero")
+	}
+	err = entity.MergeFieldErrors(err, expense.ValidateKind())
+	if err != nil {
+		return Record{Row: row, Err: err}
+	}
+	return Record{Row: row, Expense: expense}
+}
+
+func (m CSVMapping) dateFormat() string {
+	if m.DateFormat == "" {
+		return "yyyy-mm-dd"
+	}
+	return m.DateFormat
+}
+
+func isBlank(columns []string) bool {
+	for _, c := range columns {
+		if strings.TrimSpace(c) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,99 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/axpira/backend/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCSV(t *testing.T) {
+	statement := `Data;Histórico;Valor
+02/03/2021;PADARIA REAL;-1.234,56
+05/03/2021;SALARIO;5.000,00
+;;
+2021-03-07;MERCADO;-10,00
+08/03/2021;FARMACIA;-10,001
+09/03/2021;TAXA
+`
+	records, err := ParseCSV(strings.NewReader(statement), CSVMapping{
+		Comma:             ';',
+		Header:            true,
+		DateColumn:        0,
+		DateFormat:        "dd/mm/yyyy",
+		DescriptionColumn: 1,
+		AmountColumn:      2,
+		DecimalSeparator:  ",",
+		Currency:          "BRL",
+		PaymentMethodId:   "checking",
+		Signed:            true,
+	})
+	if !assert.NoError(t, err) || !assert.Len(t, records, 6) {
+		return
+	}
+	assert.Equal(t, Record{Row: 2, Expense: entity.Expense{
+		Kind:            entity.EXPENSE,
+		Amount:          123456,
+		Currency:        "BRL",
+		When:            time.Date(2021, 3, 2, 0, 0, 0, 0, time.UTC),
+		What:            "PADARIA REAL",
+		PaymentMethodId: "checking",
+	}}, records[0])
+	assert.Equal(t, entity.INCOME, records[1].Expense.Kind, "credits are incomes when signed")
+	assert.Equal(t, int64(500000), records[1].Expense.Amount)
+	assert.Equal(t, Record{Row: 4, Skip: true}, records[2])
+
+	fieldErrors := entity.UnwrapFieldErrors(records[3].Err)
+	if assert.Len(t, fieldErrors, 1) {
+		assert.Equal(t, "date", fieldErrors[0].Field())
+		assert.Equal(t, `"2021-03-07" is not like dd/mm/yyyy`, fieldErrors[0].Description())
+	}
+	fieldErrors = entity.UnwrapFieldErrors(records[4].Err)
+	if assert.Len(t, fieldErrors, 1) {
+		assert.Equal(t, "amount", fieldErrors[0].Field())
+		assert.Equal(t, "invalid_precision", fieldErrors[0].Code())
+	}
+	fieldErrors = entity.UnwrapFieldErrors(records[5].Err)
+	if assert.Len(t, fieldErrors, 1) {
+		assert.Equal(t, "amount", fieldErrors[0].Field())
+		assert.Equal(t, "missing", fieldErrors[0].Code())
+	}
+}
+
+func TestParseCSVUnsigned(t *testing.T) {
+	records, err := ParseCSV(strings.NewReader("2021-03-02,1,234.50,bakery\n"), CSVMapping{
+		DateColumn:        0,
+		AmountColumn:      1,
+		DescriptionColumn: 3,
+		Currency:          "USD",
+	})
+	if assert.NoError(t, err) && assert.Len(t, records, 1) {
+		assert.Equal(t, int64(100), records[0].Expense.Amount, "the amount column doesn't take the next one")
+		assert.Equal(t, entity.EXPENSE, records[0].Expense.Kind)
+	}
+
+	records, err = ParseCSV(strings.NewReader(`2021-03-02,"-1,234.50",bakery`+"\n"), CSVMapping{
+		AmountColumn:      1,
+		DescriptionColumn: 2,
+		Currency:          "USD",
+	})
+	if assert.NoError(t, err) && assert.Len(t, records, 1) {
+		assert.Equal(t, int64(123450), records[0].Expense.Amount, "every row is an expense when not signed")
+		assert.Equal(t, entity.EXPENSE, records[0].Expense.Kind)
+	}
+}
+
+func TestCSVMappingValidate(t *testing.T) {
+	_, err := ParseCSV(strings.NewReader(""), CSVMapping{
+		DateColumn:       -1,
+		DateFormat:       "%d/%m/%Y",
+		DecimalSeparator: ";",
+	})
+	fields := make([]string, 0)
+	for _, f := range entity.UnwrapFieldErrors(err) {
+		fields = append(fields, f.Field())
+	}
+	assert.Equal(t, []string{"currency", "decimalSeparator", "dateFormat", "dateColumn"}, fields)
+}
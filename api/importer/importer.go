@@ -0,0 +1,92 @@
+// Package importer reads bank statements into expenses and creates them row by row,
+// reporting what happened to each one
+package importer
+
+import (
+	"context"
+	"errors"
+
+	"github.com/axpira/backend/entity"
+	"github.com/rs/zerolog/log"
+)
+
+type ExpenseRepository interface {
+	Create(ctx context.Context, expense entity.Expense) (string, error)
+}
+
+type RowStatus string
+
+const (
+	CREATED RowStatus = "created"
+	SKIPPED RowStatus = "skipped"
+	FAILED  RowStatus = "failed"
+)
+
+// Record is a row of a statement, Err has the FieldErrors of a row that isn't an expense
+type Record struct {
+	Row     int
+	Expense entity.Expense
+	Err     error
+	// Skip is set on the rows with nothing to import, like blank lines
+	Skip bool
+}
+
+type RowResult struct {
+	Row    int
+	Status RowStatus
+	Id     string
+	Err    error
+}
+
+type Report struct {
+	Created int
+	Skipped int
+	Failed  int
+	Rows    []RowResult
+}
+
+func (r *Report) add(result RowResult) {
+	switch result.Status {
+	case CREATED:
+		r.Created++
+	case SKIPPED:
+		r.Skipped++
+	case FAILED:
+		r.Failed++
+	}
+	r.Rows = append(r.Rows, result)
+}
+
+// Import validates and creates the expense of every record, the ones refused as a conflict were
+// already imported and are skipped. It only stops on a context error, every other error fails just its row
+func Import(ctx context.Context, repo ExpenseRepository, records []Record) (Report, error) {
+	report := Report{Rows: make([]RowResult, 0, len(records))}
+	for _, record := range records {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		result := RowResult{Row: record.Row}
+		if !record.Skip && record.Err == nil {
+			record.Err = record.Expense.Validate()
+		}
+		switch {
+		case record.Skip:
+			result.Status = SKIPPED
+		case record.Err != nil:
+			result.Status, result.Err = FAILED, record.Err
+		default:
+			id, err := repo.Create(ctx, record.Expense)
+			switch {
+			case err == nil:
+				result.Status, result.Id = CREATED, id
+			case errors.Is(err, entity.ErrConflict):
+				result.Status = SKIPPED
+			default:
+				log.Ctx(ctx).Err(err).Int("row", record.Row).Msg("error on import")
+				result.Status, result.Err = FAILED, err
+			}
+		}
+		report.add(result)
+	}
+	return report, nil
+}
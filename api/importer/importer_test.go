@@ -0,0 +1,64 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/axpira/backend/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockExpenseRepo struct {
+	mock.Mock
+}
+
+func (m *mockExpenseRepo) Create(ctx context.Context, expense entity.Expense) (string, error) {
+	args := m.Called(ctx, expense)
+	return args.String(0), args.Error(1)
+}
+
+func TestImport(t *testing.T) {
+	bakery := entity.Expense{Amount: 1000, Currency: "BRL", What: "bakery"}
+	imported := entity.Expense{Amount: 2000, Currency: "BRL", What: "imported"}
+	broken := entity.Expense{Amount: 3000, Currency: "BRL", What: "broken"}
+	tooLong := entity.Expense{Amount: 4000, Currency: "BRL", What: strings.Repeat("a", 256)}
+	invalid := entity.NewFieldError(nil, "date", "invalid", "not a date")
+	dbErr := errors.New("connection refused")
+
+	repo := new(mockExpenseRepo)
+	repo.On("Create", mock.Anything, bakery).Return("bakery", nil)
+	repo.On("Create", mock.Anything, imported).Return("", entity.ErrConflict)
+	repo.On("Create", mock.Anything, broken).Return("", dbErr)
+
+	report, err := Import(context.Background(), repo, []Record{
+		{Row: 1, Expense: bakery},
+		{Row: 2, Skip: true},
+		{Row: 3, Err: invalid},
+		{Row: 4, Expense: imported},
+		{Row: 5, Expense: broken},
+		{Row: 6, Expense: tooLong},
+	})
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+	assert.Equal(t, Report{
+		Created: 1,
+		Skipped: 2,
+		Failed:  3,
+		Rows: []RowResult{
+			{Row: 1, Status: CREATED, Id: "bakery"},
+			{Row: 2, Status: SKIPPED},
+			{Row: 3, Status: FAILED, Err: invalid},
+			{Row: 4, Status: SKIPPED},
+			{Row: 5, Status: FAILED, Err: dbErr},
+			{Row: 6, Status: FAILED, Err: tooLong.Validate()},
+		},
+	}, report)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = Import(ctx, repo, []Record{{Row: 1, Expense: bakery}})
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,202 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/axpira/backend/entity"
+)
+
+var ErrNotOFX = errors.New("not an OFX file")
+
+type OFXOptions struct {
+	// Currency is used when the statement has no CURDEF
+	Currency entity.Currency
+	// PaymentMethodId is the account of the statement, set on every expense
+	PaymentMethodId string
+}
+
+// ofxStatement is what a STMTTRN needs from the statement it's in
+type ofxStatement struct {
+	bankId   string
+	acctId   string
+	currency entity.Currency
+}
+
+// ParseOFX reads every STMTTRN of an OFX 1.x (SGML) or 2.x (XML) statement into a record, debits are
+// expenses and credits are incomes. ExternalId is made of the bank, the account and the FITID, so
+// importing the same statement again is refused as a conflict. It fails only when r isn't OFX
+func ParseOFX(r io.Reader, options OFXOptions) ([]Record, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	content := string(data)
+	if !utf8.Valid(data) {
+		content = latin1(data)
+	}
+	start := strings.Index(strings.ToUpper(content), "<OFX>")
+	if start == -1 {
+		return nil, ErrNotOFX
+	}
+
+	records := make([]Record, 0)
+	statement := ofxStatement{currency: options.Currency}
+	var trn map[string]string
+	for _, t := range ofxTokens(content[start:]) {
+		switch {
+		case t.tag == "STMTRS" || t.tag == "CCSTMTRS":
+			statement = ofxStatement{currency: options.Currency}
+		case t.tag == "STMTTRN":
+			trn = make(map[string]string)
+		case t.tag == "/STMTTRN" && trn != nil:
+			records = append(records, statement.record(len(records)+1, trn, options))
+			trn = nil
+		case trn != nil:
+			if _, ok := trn[t.tag]; !ok {
+				trn[t.tag] = t.value
+			}
+		case t.tag == "BANKID":
+			statement.bankId = t.value
+		case t.tag == "ACCTID":
+			statement.acctId = t.value
+		case t.tag == "CURDEF":
+			statement.currency = entity.Currency(strings.ToUpper(t.value))
+		}
+	}
+	return records, nil
+}
+
+type ofxToken struct {
+	tag   string
+	value string
+}
+
+// ofxTokens returns the tags with the text following them, the closing tags start with a slash.
+// SGML elements have no closing tag, so the value of a tag ends on the next one
+func ofxTokens(content string) []ofxToken {
+	var tokens []ofxToken
+	for {
+		open := strings.Index(content, "<")
+		if open == -1 {
+			return tokens
+		}
+		content = content[open+1:]
+		end := strings.Index(content, ">")
+		if end == -1 {
+			return tokens
+		}
+		tag := strings.ToUpper(strings.TrimSpace(content[:end]))
+		content = content[end+1:]
+		if strings.HasPrefix(tag, "?") || strings.HasPrefix(tag, "!") {
+			continue
+		}
+		value := content
+		if next := strings.Index(content, "<"); next != -1 {
+			value = content[:next]
+		}
+		tokens = append(tokens, ofxToken{tag: tag, value: html.UnescapeString(strings.TrimSpace(value))})
+	}
+}
+
+func (s ofxStatement) record(row int, trn map[string]string, options OFXOptions) Record {
+	var err error
+	expense := entity.Expense{
+		Kind:            entity.EXPENSE,
+		Currency:        s.currency,
+		What:            trn["NAME"],
+		PaymentMethodId: options.PaymentMethodId,
+	}
+	if expense.What == "" {
+		expense.What = trn["MEMO"]
+	}
+	if !expense.Currency.IsValid() {
+		err = entity.NewFieldError(err, "currency", "invalid", fmt.Sprintf("unknown ISO 4217 currency %q", expense.Currency))
+	}
+
+	fitId := trn["FITID"]
+	if fitId == "" {
+		err = entity.NewFieldError(err, "fitid", "no_empty", "can't be empty")
+	}
+	expense.ExternalId = strings.Join([]string{"ofx", s.bankId, s.acctId, fitId}, ":")
+
+	when, dateErr := parseOFXDate(trn["DTPOSTED"])
+	if dateErr != nil {
+		err = entity.NewFieldError(err, "date", "invalid", dateErr.Error())
+	}
+	expense.When = when
+
+	amount := trn["TRNAMT"]
+	if !strings.Contains(amount, ".") {
+		amount = strings.Replace(amount, ",", ".", 1)
+	}
+	money, amountErr := entity.ParseMoney(amount, expense.Currency)
+	if amountErr != nil {
+		err = entity.MergeFieldErrors(err, entity.RenameFieldError(amountErr, "amount"))
+	} else if money.Amount == 0 {
+		err = entity.NewFieldError(err, "amount", "invalid", "can't be zero")
+	}
+	expense.Amount = money.Amount
+	if expense.Amount > 0 {
+		expense.Kind = entity.INCOME
+	} else {
+		expense.Amount = -expense.Amount
+	}
+
+	if err != nil {
+		return Record{Row: row, Err: err}
+	}
+	return Record{Row: row, Expense: expense}
+}
+
+// parseOFXDate reads YYYYMMDD[HHMMSS[.XXX]][offset:TZ], like 20210302120000.000[-3:BRT], in UTC when there's no offset
+func parseOFXDate(value string) (time.Time, error) {
+	invalid := fmt.Errorf("%q is not like YYYYMMDDHHMMSS[-3:BRT]", value)
+	datetime, zone := value, ""
+	if i := strings.Index(value, "["); i != -1 {
+		if !strings.HasSuffix(value, "]") {
+			return time.Time{}, invalid
+		}
+		datetime, zone = value[:i], value[i+1:len(value)-1]
+	}
+	if i := strings.Index(datetime, "."); i != -1 {
+		datetime = datetime[:i]
+	}
+	layout := "20060102150405"
+	if len(datetime) == len("20060102") {
+		datetime += "000000"
+	}
+	location := time.UTC
+	if zone != "" {
+		offset := zone
+		if i := strings.Index(zone, ":"); i != -1 {
+			offset = zone[:i]
+		}
+		hours, err := strconv.ParseFloat(offset, 64)
+		if err != nil {
+			return time.Time{}, invalid
+		}
+		location = time.FixedZone(zone, int(hours*3600))
+	}
+	t, err := time.ParseInLocation(layout, datetime, location)
+	if err != nil || len(datetime) != len(layout) {
+		return time.Time{}, invalid
+	}
+	return t.UTC(), nil
+}
+
+// latin1 decodes the OFX 1.x statements sent with CHARSET:1252 or ISO-8859-1
+func latin1(data []byte) string {
+	runes := make([]rune, len(data))
+	for i, b := range data {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
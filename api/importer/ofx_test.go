@@ -0,0 +1,144 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/axpira/backend/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+const sgmlStatement = `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+CHARSET:1252
+
+<OFX>
+<BANKMSGSRSV1><STMTTRNRS><STMTRS>
+<CURDEF>BRL
+<BANKACCTFROM>
+<BANKID>0341
+<ACCTID>12345-6
+</BANKACCTFROM>
+<BANKTRANLIST>
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20210302120000[-3:BRT]
+<TRNAMT>-1234.56
+<FITID>20210302001
+<NAME>PADARIA S&amp;A
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>CREDIT
+<DTPOSTED>20210305
+<TRNAMT>5000,00
+<FITID>20210305001
+<MEMO>SALARIO
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>yesterday
+<TRNAMT>-10.00
+</STMTTRN>
+</BANKTRANLIST>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1>
+</OFX>
+`
+
+const xmlStatement = `<?xml version="1.0" encoding="UTF-8"?>
+<?OFX OFXHEADER="200" VERSION="211" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>
+<OFX>
+  <CREDITCARDMSGSRSV1><CCSTMTTRNRS><CCSTMTRS>
+    <CURDEF>USD</CURDEF>
+    <CCACCTFROM><ACCTID>4111</ACCTID></CCACCTFROM>
+    <BANKTRANLIST>
+      <STMTTRN>
+        <TRNTYPE>DEBIT</TRNTYPE>
+        <DTPOSTED>20210310083000.000</DTPOSTED>
+        <TRNAMT>-25.90</TRNAMT>
+        <FITID>abc-1</FITID>
+        <NAME>Café</NAME>
+      </STMTTRN>
+    </BANKTRANLIST>
+  </CCSTMTRS></CCSTMTTRNRS></CREDITCARDMSGSRSV1>
+</OFX>
+`
+
+func TestParseOFX(t *testing.T) {
+	options := OFXOptions{Currency: "EUR", PaymentMethodId: "checking"}
+
+	t.Run("sgml", func(t *testing.T) {
+		records, err := ParseOFX(strings.NewReader(sgmlStatement), options)
+		if !assert.NoError(t, err) || !assert.Len(t, records, 3) {
+			return
+		}
+		assert.Equal(t, Record{Row: 1, Expense: entity.Expense{
+			Kind:            entity.EXPENSE,
+			Amount:          123456,
+			Currency:        "BRL",
+			When:            time.Date(2021, 3, 2, 15, 0, 0, 0, time.UTC),
+			What:            "PADARIA S&A",
+			PaymentMethodId: "checking",
+			ExternalId:      "ofx:0341:12345-6:20210302001",
+		}}, records[0])
+		assert.Equal(t, Record{Row: 2, Expense: entity.Expense{
+			Kind:            entity.INCOME,
+			Amount:          500000,
+			Currency:        "BRL",
+			When:            time.Date(2021, 3, 5, 0, 0, 0, 0, time.UTC),
+			What:            "SALARIO",
+			PaymentMethodId: "checking",
+			ExternalId:      "ofx:0341:12345-6:20210305001",
+		}}, records[1])
+		fields := make([]string, 0)
+		for _, f := range entity.UnwrapFieldErrors(records[2].Err) {
+			fields = append(fields, f.Field())
+		}
+		assert.Equal(t, []string{"date", "fitid"}, fields)
+	})
+
+	t.Run("xml", func(t *testing.T) {
+		records, err := ParseOFX(strings.NewReader(xmlStatement), options)
+		if assert.NoError(t, err) && assert.Len(t, records, 1) {
+			assert.Equal(t, Record{Row: 1, Expense: entity.Expense{
+				Kind:            entity.EXPENSE,
+				Amount:          2590,
+				Currency:        "USD",
+				When:            time.Date(2021, 3, 10, 8, 30, 0, 0, time.UTC),
+				What:            "Café",
+				PaymentMethodId: "checking",
+				ExternalId:      "ofx::4111:abc-1",
+			}}, records[0])
+		}
+	})
+
+	t.Run("latin1", func(t *testing.T) {
+		statement := strings.Replace(sgmlStatement, "PADARIA S&amp;A", "PADARIA S\xc3O JO\xc3O", 1)
+		records, err := ParseOFX(strings.NewReader(statement), options)
+		if assert.NoError(t, err) && assert.NotEmpty(t, records) {
+			assert.Equal(t, "PADARIA SÃO JOÃO", records[0].Expense.What)
+		}
+	})
+
+	_, err := ParseOFX(strings.NewReader("date,amount\n"), options)
+	assert.ErrorIs(t, err, ErrNotOFX)
+}
+
+func TestParseOFXDate(t *testing.T) {
+	tests := map[string]time.Time{
+		"20210302":                   time.Date(2021, 3, 2, 0, 0, 0, 0, time.UTC),
+		"20210302235959":             time.Date(2021, 3, 2, 23, 59, 59, 0, time.UTC),
+		"20210302120000.000[-3:BRT]": time.Date(2021, 3, 2, 15, 0, 0, 0, time.UTC),
+		"20210302120000[+5.5:IST]":   time.Date(2021, 3, 2, 6, 30, 0, 0, time.UTC),
+	}
+	for value, want := range tests {
+		got, err := parseOFXDate(value)
+		assert.NoError(t, err, value)
+		assert.Equal(t, want, got, value)
+	}
+	for _, value := range []string{"", "2021", "202103021200", "20210302[BRT]", "20210302[-3"} {
+		_, err := parseOFXDate(value)
+		assert.Error(t, err, value)
+	}
+}
@@ -0,0 +1,275 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/axpira/backend/entity"
+	"github.com/axpira/backend/entity/config"
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+type BudgetRepository interface {
+	Create(ctx context.Context, budget entity.Budget) (string, error)
+	Update(ctx context.Context, budget entity.Budget) error
+	Delete(ctx context.Context, id string) error
+	Get(ctx context.Context, id string) (entity.Budget, error)
+	List(ctx context.Context) ([]entity.Budget, error)
+}
+
+type BudgetRest struct {
+	Id         string      `json:"id,omitempty"`
+	Name       string      `json:"name,omitempty"`
+	CategoryId string      `json:"categoryId,omitempty"`
+	Tag        string      `json:"tag,omitempty"`
+	Period     string      `json:"period,omitempty"`
+	Limit      *amountRest `json:"limit,omitempty"`
+	Currency   string      `json:"currency,omitempty"`
+	Rollover   bool        `json:"rollover"`
+	Start      *time.Time  `json:"start,omitempty"`
+}
+
+func (b BudgetRest) ToBudget() (entity.Budget, error) {
+	budget := entity.Budget{
+		Id:         b.Id,
+		Name:       b.Name,
+		CategoryId: b.CategoryId,
+		Tag:        b.Tag,
+		Period:     entity.Frequency(b.Period),
+		Rollover:   b.Rollover,
+	}
+	currency := b.Currency
+	if currency == "" {
+		currency = config.Config.DefaultCurrency
+	}
+	limit, err := entity.ParseCurrency(currency)
+	if err != nil {
+		return entity.Budget{}, err
+	}
+	budget.Limit.Currency = limit
+	if b.Limit != nil {
+		money, err := entity.ParseMoney(b.Limit.raw, limit)
+		if err != nil {
+			return entity.Budget{}, entity.RenameFieldError(err, "limit")
+		}
+		budget.Limit = money
+	}
+	if b.Start != nil {
+		budget.Start = b.Start.UTC()
+	}
+	return budget, nil
+}
+
+func NewBudgetRestFromBudget(b entity.Budget) BudgetRest {
+	return BudgetRest{
+		Id:         b.Id,
+		Name:       b.Name,
+		CategoryId: b.CategoryId,
+		Tag:        b.Tag,
+		Period:     string(b.Period),
+		Limit:      NewAmountRest(b.Limit),
+		Currency:   string(b.Limit.Currency),
+		Rollover:   b.Rollover,
+		Start:      NewRestTime(b.Start),
+	}
+}
+
+type BudgetStatusRest struct {
+	PeriodStart time.Time   `json:"periodStart"`
+	PeriodEnd   time.Time   `json:"periodEnd"`
+	Available   *amountRest `json:"available,omitempty"`
+	Carried     *amountRest `json:"carried,omitempty"`
+	Spent       *amountRest `json:"spent,omitempty"`
+	Remaining   *amountRest `json:"remaining,omitempty"`
+	// Projected is the spending at the end of the period if it goes on at the same pace
+	Projected *amountRest `json:"projected,omitempty"`
+	Currency  string      `json:"currency"`
+	// Excluded is how many expenses in ExcludedCurrencies weren't counted
+	Excluded           int      `json:"excluded,omitempty"`
+	ExcludedCurrencies []string `json:"excludedCurrencies,omitempty"`
+}
+
+func NewBudgetStatusRestFromBudgetStatus(s entity.BudgetStatus) BudgetStatusRest {
+	res := BudgetStatusRest{
+		PeriodStart: s.PeriodStart,
+		PeriodEnd:   s.PeriodEnd,
+		Available:   NewAmountRest(s.Available),
+		Carried:     NewAmountRest(s.Carried),
+		Spent:       NewAmountRest(s.Spent),
+		Remaining:   NewAmountRest(s.Remaining),
+		Projected:   NewAmountRest(s.Projected),
+		Currency:    string(s.Available.Currency),
+		Excluded:    s.Excluded,
+	}
+	for _, c := range s.ExcludedCurrencies {
+		res.ExcludedCurrencies = append(res.ExcludedCurrencies, string(c))
+	}
+	return res
+}
+
+// budgetRoutes reads the spending of the budgets from the expenses of repo
+func budgetRoutes(repo BudgetRepository, expenses ExpenseRepository) func(r chi.Router) {
+	return func(r chi.Router) {
+		r.Post("/", createBudget(repo))
+		r.Get("/", listBudget(repo))
+		r.Route("/{budgetID}", func(r chi.Router) {
+			r.Get("/", getBudget(repo))
+			r.Put("/", updateBudget(repo))
+			r.Delete("/", deleteBudget(repo))
+			r.Get("/status", getBudgetStatus(repo, expenses))
+		})
+	}
+}
+
+func validateBudgetError(w http.ResponseWriter, err error) bool {
+	return validateResourceError(w, "budget", err)
+}
+
+func decodeBudget(w http.ResponseWriter, r *http.Request) (entity.Budget, bool) {
+	ctx := r.Context()
+	budgetRest := new(BudgetRest)
+	err := json.NewDecoder(r.Body).Decode(budgetRest)
+	if err != nil {
+		log.Ctx(ctx).Err(err).Msg("error on decode")
+		fillHttpError(w,
+			NewHttpError(http.StatusBadRequest, "",
+				NewError("INVALID_REQUEST", "invalid json"),
+			),
+		)
+		return entity.Budget{}, false
+	}
+	budget, err := budgetRest.ToBudget()
+	if err != nil {
+		log.Ctx(ctx).Err(err).Msg("error on validate")
+		fillHttpError(w,
+			NewHttpError(http.StatusBadRequest, "",
+				NewError("INVALID_REQUEST", "invalid budget").WithFields(err),
+			),
+		)
+		return entity.Budget{}, false
+	}
+	return budget, true
+}
+
+func createBudget(repo BudgetRepository) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		budget, ok := decodeBudget(w, r)
+		if !ok {
+			return
+		}
+		id, err := repo.Create(ctx, budget)
+		if validateBudgetError(w, err) {
+			log.Ctx(ctx).Err(err).Msg("error on create")
+			return
+		}
+		w.Write([]byte(`{"id":"` + id + `"}`))
+	}
+}
+
+func listBudget(repo BudgetRepository) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		budgets, err := repo.List(ctx)
+		if validateBudgetError(w, err) {
+			log.Ctx(ctx).Err(err).Msg("error on list")
+			return
+		}
+		res := make([]BudgetRest, len(budgets))
+		for i, budget := range budgets {
+			res[i] = NewBudgetRestFromBudget(budget)
+		}
+		err = json.NewEncoder(w).Encode(res)
+		if validateBudgetError(w, err) {
+			return
+		}
+	}
+}
+
+func getBudget(repo BudgetRepository) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		budget, err := repo.Get(ctx, chi.URLParam(r, "budgetID"))
+		if validateBudgetError(w, err) {
+			log.Ctx(ctx).Err(err).Msg("error on consult")
+			return
+		}
+		err = json.NewEncoder(w).Encode(NewBudgetRestFromBudget(budget))
+		if validateBudgetError(w, err) {
+			return
+		}
+	}
+}
+
+func updateBudget(repo BudgetRepository) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		budget, ok := decodeBudget(w, r)
+		if !ok {
+			return
+		}
+		budget.Id = chi.URLParam(r, "budgetID")
+		err := repo.Update(ctx, budget)
+		if validateBudgetError(w, err) {
+			log.Ctx(ctx).Err(err).Msg("error on update")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func deleteBudget(repo BudgetRepository) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		err := repo.Delete(ctx, chi.URLParam(r, "budgetID"))
+		if validateBudgetError(w, err) {
+			log.Ctx(ctx).Err(err).Msg("error on delete")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// getBudgetStatus computes the status now, or at the time of the query param at
+func getBudgetStatus(repo BudgetRepository, expenses ExpenseRepository) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		now := time.Now().UTC()
+		if at := r.URL.Query().Get("at"); at != "" {
+			t, err := parseTime(at)
+			if err != nil {
+				log.Ctx(ctx).Err(err).Msg("error on parse at")
+				fillHttpError(w,
+					NewHttpError(http.StatusBadRequest, "",
+						NewError("INVALID_REQUEST", "invalid budget status").
+							WithFields(entity.NewFieldError(nil, "at", "invalid", err.Error())),
+					),
+				)
+				return
+			}
+			now = t
+		}
+		budget, err := repo.Get(ctx, chi.URLParam(r, "budgetID"))
+		if validateBudgetError(w, err) {
+			log.Ctx(ctx).Err(err).Msg("error on consult")
+			return
+		}
+		filter, err := entity.NewExpenseFilter(budget.Filters(now)...)
+		if validateBudgetError(w, err) {
+			log.Ctx(ctx).Err(err).Msg("error on filter")
+			return
+		}
+		spending, err := expenses.Search(ctx, filter)
+		if validateBudgetError(w, err) {
+			log.Ctx(ctx).Err(err).Msg("error on search")
+			return
+		}
+		err = json.NewEncoder(w).Encode(NewBudgetStatusRestFromBudgetStatus(budget.Status(now, spending)))
+		if validateBudgetError(w, err) {
+			return
+		}
+	}
+}
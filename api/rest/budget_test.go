@@ -0,0 +1,214 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/axpira/backend/entity"
+	"github.com/axpira/backend/entity/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockBudgetRepo struct {
+	mock.Mock
+}
+
+func (m *mockBudgetRepo) Create(ctx context.Context, budget entity.Budget) (string, error) {
+	args := m.Called(ctx, budget)
+	return args.String(0), args.Error(1)
+}
+func (m *mockBudgetRepo) Update(ctx context.Context, budget entity.Budget) error {
+	args := m.Called(ctx, budget)
+	return args.Error(0)
+}
+func (m *mockBudgetRepo) Delete(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+func (m *mockBudgetRepo) Get(ctx context.Context, id string) (entity.Budget, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(entity.Budget), args.Error(1)
+}
+func (m *mockBudgetRepo) List(ctx context.Context) ([]entity.Budget, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]entity.Budget), args.Error(1)
+}
+
+func TestBudget(t *testing.T) {
+	defaultCurrency := config.Config.DefaultCurrency
+	config.Config.DefaultCurrency = "BRL"
+	defer func() { config.Config.DefaultCurrency = defaultCurrency }()
+
+	food := entity.Budget{
+		Name:       "Food",
+		CategoryId: "food",
+		Period:     entity.MONTHLY,
+		Limit:      entity.Money{Amount: 100000, Currency: "BRL"},
+		Start:      time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	stored := food
+	stored.Id = "food"
+	tests := map[string]struct {
+		method       string
+		path         string
+		sent         []byte
+		setup        func(*mockBudgetRepo)
+		setupExpense func(*mockExpenseRepo)
+		wantResult   []byte
+		wantStatus   int
+	}{
+		"create": {
+			method: http.MethodPost,
+			path:   "/api/budget",
+			sent:   []byte(`{"name": "Food", "categoryId": "food", "period": "monthly", "limit": "1000.00", "start": "2021-01-01T00:00:00Z"}`),
+			setup: func(m *mockBudgetRepo) {
+				m.On("Create", mock.Anything, food).Return("food", nil)
+			},
+			wantStatus: 200,
+			wantResult: []byte(`{"id": "food"}`),
+		},
+		"create with invalid limit": {
+			method:     http.MethodPost,
+			path:       "/api/budget",
+			sent:       []byte(`{"name": "Food", "categoryId": "food", "period": "monthly", "limit": "1000.001"}`),
+			wantStatus: 400,
+			wantResult: []byte(`{
+				"code": "INVALID_REQUEST",
+				"message": "invalid budget",
+				"fields": [
+					{"field": "limit", "code": "invalid_precision", "message": "BRL has 2 decimal places"}
+				]
+			}`),
+		},
+		"get": {
+			method: http.MethodGet,
+			path:   "/api/budget/food",
+			setup: func(m *mockBudgetRepo) {
+				m.On("Get", mock.Anything, "food").Return(stored, nil)
+			},
+			wantStatus: 200,
+			wantResult: []byte(`{
+				"id": "food",
+				"name": "Food",
+				"categoryId": "food",
+				"period": "monthly",
+				"limit": "1000.00",
+				"currency": "BRL",
+				"rollover": false,
+				"start": "2021-01-01T00:00:00Z"
+			}`),
+		},
+		"update": {
+			method: http.MethodPut,
+			path:   "/api/budget/food",
+			sent:   []byte(`{"name": "Food", "categoryId": "food", "period": "monthly", "limit": "1000.00", "start": "2021-01-01T00:00:00Z"}`),
+			setup: func(m *mockBudgetRepo) {
+				m.On("Update", mock.Anything, stored).Return(nil)
+			},
+			wantStatus: 204,
+		},
+		"delete": {
+			method: http.MethodDelete,
+			path:   "/api/budget/food",
+			setup: func(m *mockBudgetRepo) {
+				m.On("Delete", mock.Anything, "food").Return(nil)
+			},
+			wantStatus: 204,
+		},
+		"status": {
+			method: http.MethodGet,
+			path:   "/api/budget/food/status?at=2021-03-16",
+			setup: func(m *mockBudgetRepo) {
+				m.On("Get", mock.Anything, "food").Return(stored, nil)
+			},
+			setupExpense: func(m *mockExpenseRepo) {
+				m.On("Search", mock.Anything, mock.MatchedBy(func(f *entity.ExpenseFilter) bool {
+					return assert.ObjectsAreEqual([]entity.TransactionKind{entity.EXPENSE}, f.Kind) &&
+						assert.ObjectsAreEqual([]entity.StrFilter{{Type: entity.EQUALS, Value: "food"}}, f.Category)
+				})).Return([]entity.Expense{
+					{Amount: 15000, Currency: "BRL", When: time.Date(2021, 3, 2, 0, 0, 0, 0, time.UTC)},
+					{Amount: 15000, Currency: "BRL", When: time.Date(2021, 3, 10, 0, 0, 0, 0, time.UTC)},
+					{Amount: 4000, Currency: "USD", When: time.Date(2021, 3, 12, 0, 0, 0, 0, time.UTC)},
+				}, nil)
+			},
+			wantStatus: 200,
+			wantResult: []byte(`{
+				"periodStart": "2021-03-01T00:00:00Z",
+				"periodEnd": "2021-04-01T00:00:00Z",
+				"available": "1000.00",
+				"spent": "300.00",
+				"remaining": "700.00",
+				"projected": "620.00",
+				"currency": "BRL",
+				"excluded": 1,
+				"excludedCurrencies": ["USD"]
+			}`),
+		},
+		"status with invalid at": {
+			method:     http.MethodGet,
+			path:       "/api/budget/food/status?at=yesterday",
+			wantStatus: 400,
+			wantResult: []byte(`{
+				"code": "INVALID_REQUEST",
+				"message": "invalid budget status",
+				"fields": [
+					{"field": "at", "code": "invalid", "message": "invalid time \"yesterday\", use RFC3339 or YYYY-MM-DD"}
+				]
+			}`),
+		},
+		"status not found": {
+			method: http.MethodGet,
+			path:   "/api/budget/unknown/status",
+			setup: func(m *mockBudgetRepo) {
+				m.On("Get", mock.Anything, "unknown").Return(entity.Budget{}, entity.ErrNotFound)
+			},
+			wantStatus: 404,
+			wantResult: []byte(`{"code": "NOT_FOUND", "message": "budget not found"}`),
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockedRepo := new(mockBudgetRepo)
+			if tc.setup != nil {
+				tc.setup(mockedRepo)
+			}
+			mockedExpenseRepo := new(mockExpenseRepo)
+			if tc.setupExpense != nil {
+				tc.setupExpense(mockedExpenseRepo)
+			}
+			ts := httptest.NewServer(createHandler(context.Background(), mockedExpenseRepo, WithBudgetRepository(mockedRepo)))
+			defer ts.Close()
+
+			req, err := http.NewRequest(tc.method, ts.URL+tc.path, bytes.NewBuffer(tc.sent))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Accept", "application/json")
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tc.wantStatus, res.StatusCode)
+
+			got, err := io.ReadAll(res.Body)
+			res.Body.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			mockedRepo.AssertExpectations(t)
+			mockedExpenseRepo.AssertExpectations(t)
+			if tc.wantResult != nil {
+				assert.JSONEq(t, string(tc.wantResult), string(got))
+			} else {
+				assert.Empty(t, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/axpira/backend/entity"
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+type CategoryRepository interface {
+	Create(ctx context.Context, category entity.Category) (string, error)
+	Update(ctx context.Context, category entity.Category) error
+	Delete(ctx context.Context, id, reassignTo string, orphan bool) error
+	Get(ctx context.Context, id string) (entity.Category, error)
+	List(ctx context.Context) ([]entity.Category, error)
+}
+
+type CategoryRest struct {
+	Id       string `json:"id,omitempty"`
+	Name     string `json:"name,omitempty"`
+	ParentId string `json:"parentId,omitempty"`
+}
+
+func (c CategoryRest) ToCategory() entity.Category {
+	return entity.Category{
+		Id:       c.Id,
+		Name:     c.Name,
+		ParentId: c.ParentId,
+	}
+}
+
+func NewCategoryRestFromCategory(c entity.Category) CategoryRest {
+	return CategoryRest{
+		Id:       c.Id,
+		Name:     c.Name,
+		ParentId: c.ParentId,
+	}
+}
+
+func categoryRoutes(repo CategoryRepository) func(r chi.Router) {
+	return func(r chi.Router) {
+		r.Post("/", createCategory(repo))
+		r.Get("/", listCategory(repo))
+		r.Route("/{categoryID}", func(r chi.Router) {
+			r.Get("/", getCategory(repo))
+			r.Put("/", updateCategory(repo))
+			r.Delete("/", deleteCategory(repo))
+		})
+	}
+}
+
+func validateCategoryError(w http.ResponseWriter, err error) bool {
+	return validateResourceError(w, "category", err)
+}
+
+func decodeCategory(w http.ResponseWriter, r *http.Request) (entity.Category, bool) {
+	category := new(CategoryRest)
+	err := json.NewDecoder(r.Body).Decode(category)
+	if err != nil {
+		log.Ctx(r.Context()).Err(err).Msg("error on decode")
+		fillHttpError(w,
+			NewHttpError(http.StatusBadRequest, "",
+				NewError("INVALID_REQUEST", "invalid json"),
+			),
+		)
+		return entity.Category{}, false
+	}
+	return category.ToCategory(), true
+}
+
+func createCategory(repo CategoryRepository) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		category, ok := decodeCategory(w, r)
+		if !ok {
+			return
+		}
+		id, err := repo.Create(ctx, category)
+		if validateCategoryError(w, err) {
+			log.Ctx(ctx).Err(err).Msg("error on create")
+			return
+		}
+		w.Write([]byte(`{"id":"` + id + `"}`))
+	}
+}
+
+func listCategory(repo CategoryRepository) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		categories, err := repo.List(ctx)
+		if validateCategoryError(w, err) {
+			log.Ctx(ctx).Err(err).Msg("error on list")
+			return
+		}
+		res := make([]CategoryRest, len(categories))
+		for i, category := range categories {
+			res[i] = NewCategoryRestFromCategory(category)
+		}
+		err = json.NewEncoder(w).Encode(res)
+		if validateCategoryError(w, err) {
+			return
+		}
+	}
+}
+
+func getCategory(repo CategoryRepository) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		category, err := repo.Get(ctx, chi.URLParam(r, "categoryID"))
+		if validateCategoryError(w, err) {
+			log.Ctx(ctx).Err(err).Msg("error on consult")
+			return
+		}
+		err = json.NewEncoder(w).Encode(NewCategoryRestFromCategory(category))
+		if validateCategoryError(w, err) {
+			return
+		}
+	}
+}
+
+func updateCategory(repo CategoryRepository) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		category, ok := decodeCategory(w, r)
+		if !ok {
+			return
+		}
+		category.Id = chi.URLParam(r, "categoryID")
+		err := repo.Update(ctx, category)
+		if validateCategoryError(w, err) {
+			log.Ctx(ctx).Err(err).Msg("error on update")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// deleteCategory needs reassignTo=<categoryID> or orphan=true when the category has expenses
+func deleteCategory(repo CategoryRepository) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		query := r.URL.Query()
+		orphan := false
+		if v := query.Get("orphan"); v != "" {
+			var err error
+			orphan, err = strconv.ParseBool(v)
+			if err != nil {
+				fillHttpError(w,
+					NewHttpError(http.StatusBadRequest, "",
+						NewError("INVALID_REQUEST", "invalid category").WithFields(
+							entity.NewFieldError(nil, "orphan", "invalid", "must be a boolean"),
+						),
+					),
+				)
+				return
+			}
+		}
+		err := repo.Delete(ctx, chi.URLParam(r, "categoryID"), query.Get("reassignTo"), orphan)
+		if validateCategoryError(w, err) {
+			log.Ctx(ctx).Err(err).Msg("error on delete")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,191 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/axpira/backend/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockCategoryRepo struct {
+	mock.Mock
+}
+
+func (m *mockCategoryRepo) Create(ctx context.Context, category entity.Category) (string, error) {
+	args := m.Called(ctx, category)
+	return args.String(0), args.Error(1)
+}
+func (m *mockCategoryRepo) Update(ctx context.Context, category entity.Category) error {
+	args := m.Called(ctx, category)
+	return args.Error(0)
+}
+func (m *mockCategoryRepo) Delete(ctx context.Context, id, reassignTo string, orphan bool) error {
+	args := m.Called(ctx, id, reassignTo, orphan)
+	return args.Error(0)
+}
+func (m *mockCategoryRepo) Get(ctx context.Context, id string) (entity.Category, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(entity.Category), args.Error(1)
+}
+func (m *mockCategoryRepo) List(ctx context.Context) ([]entity.Category, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]entity.Category), args.Error(1)
+}
+
+func TestCategoryNotMounted(t *testing.T) {
+	ts := httptest.NewServer(createHandler(context.Background(), new(mockExpenseRepo)))
+	defer ts.Close()
+	res, err := http.Get(ts.URL + "/api/category")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+}
+
+func TestCategory(t *testing.T) {
+	tests := map[string]struct {
+		method     string
+		path       string
+		sent       []byte
+		setup      func(*mockCategoryRepo)
+		wantResult []byte
+		wantStatus int
+	}{
+		"create": {
+			method: http.MethodPost,
+			path:   "/api/category",
+			sent:   []byte(`{"name": "Bakery", "parentId": "groceries"}`),
+			setup: func(m *mockCategoryRepo) {
+				m.On("Create", mock.Anything, entity.Category{Name: "Bakery", ParentId: "groceries"}).Return("bakery", nil)
+			},
+			wantStatus: 200,
+			wantResult: []byte(`{"id": "bakery"}`),
+		},
+		"create with unknown parent": {
+			method: http.MethodPost,
+			path:   "/api/category",
+			sent:   []byte(`{"name": "Bakery", "parentId": "unknown"}`),
+			setup: func(m *mockCategoryRepo) {
+				m.On("Create", mock.Anything, entity.Category{Name: "Bakery", ParentId: "unknown"}).
+					Return("", entity.NewFieldError(nil, "parentId", "not_found", "reference not found"))
+			},
+			wantStatus: 400,
+			wantResult: []byte(`{
+				"code": "INVALID_REQUEST",
+				"message": "invalid category",
+				"fields": [{"field": "parentId", "code": "not_found", "message": "reference not found"}]
+			}`),
+		},
+		"list": {
+			method: http.MethodGet,
+			path:   "/api/category",
+			setup: func(m *mockCategoryRepo) {
+				m.On("List", mock.Anything).Return([]entity.Category{
+					{Id: "food", Name: "Food"},
+					{Id: "groceries", Name: "Groceries", ParentId: "food"},
+				}, nil)
+			},
+			wantStatus: 200,
+			wantResult: []byte(`[
+				{"id": "food", "name": "Food"},
+				{"id": "groceries", "name": "Groceries", "parentId": "food"}
+			]`),
+		},
+		"get not found": {
+			method: http.MethodGet,
+			path:   "/api/category/unknown",
+			setup: func(m *mockCategoryRepo) {
+				m.On("Get", mock.Anything, "unknown").Return(entity.Category{}, entity.ErrNotFound)
+			},
+			wantStatus: 404,
+			wantResult: []byte(`{"code": "NOT_FOUND", "message": "category not found"}`),
+		},
+		"update": {
+			method: http.MethodPut,
+			path:   "/api/category/bakery",
+			sent:   []byte(`{"name": "Bakery", "parentId": "food"}`),
+			setup: func(m *mockCategoryRepo) {
+				m.On("Update", mock.Anything, entity.Category{Id: "bakery", Name: "Bakery", ParentId: "food"}).Return(nil)
+			},
+			wantStatus: 204,
+		},
+		"delete with expenses": {
+			method: http.MethodDelete,
+			path:   "/api/category/food",
+			setup: func(m *mockCategoryRepo) {
+				m.On("Delete", mock.Anything, "food", "", false).
+					Return(fmt.Errorf("category food has expenses, reassign or orphan them: %w", entity.ErrConflict))
+			},
+			wantStatus: 409,
+			wantResult: []byte(`{"code": "CONFLICT", "message": "category is in use or already exists"}`),
+		},
+		"delete reassigning": {
+			method: http.MethodDelete,
+			path:   "/api/category/food?reassignTo=other",
+			setup: func(m *mockCategoryRepo) {
+				m.On("Delete", mock.Anything, "food", "other", false).Return(nil)
+			},
+			wantStatus: 204,
+		},
+		"delete orphaning": {
+			method: http.MethodDelete,
+			path:   "/api/category/food?orphan=true",
+			setup: func(m *mockCategoryRepo) {
+				m.On("Delete", mock.Anything, "food", "", true).Return(nil)
+			},
+			wantStatus: 204,
+		},
+		"delete invalid orphan": {
+			method:     http.MethodDelete,
+			path:       "/api/category/food?orphan=maybe",
+			wantStatus: 400,
+			wantResult: []byte(`{
+				"code": "INVALID_REQUEST",
+				"message": "invalid category",
+				"fields": [{"field": "orphan", "code": "invalid", "message": "must be a boolean"}]
+			}`),
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockedRepo := new(mockCategoryRepo)
+			if tc.setup != nil {
+				tc.setup(mockedRepo)
+			}
+			ts := httptest.NewServer(createHandler(context.Background(), new(mockExpenseRepo), WithCategoryRepository(mockedRepo)))
+			defer ts.Close()
+
+			req, err := http.NewRequest(tc.method, ts.URL+tc.path, bytes.NewBuffer(tc.sent))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Accept", "application/json")
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tc.wantStatus, res.StatusCode)
+
+			got, err := io.ReadAll(res.Body)
+			res.Body.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			mockedRepo.AssertExpectations(t)
+			if len(tc.wantResult) == 0 {
+				assert.Empty(t, got)
+			} else {
+				assert.JSONEq(t, string(tc.wantResult), string(got))
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package rest
+
+import (
+	"fmt"
+
+	"github.com/axpira/backend/entity"
+)
+
+type DetailRest struct {
+	Description string `json:"description"`
+	// Quantity is a decimal like "1.5", 1 when it's omitted
+	Quantity   string      `json:"quantity,omitempty"`
+	UnitPrice  *amountRest `json:"unitPrice,omitempty"`
+	CategoryId string      `json:"categoryId,omitempty"`
+	Tags       []string    `json:"tags,omitempty"`
+	// Total is read only, Quantity times UnitPrice
+	Total *amountRest `json:"total,omitempty"`
+}
+
+func (d DetailRest) toDetail(field string, currency entity.Currency) (entity.Detail, error) {
+	var err error
+	detail := entity.Detail{
+		Description: d.Description,
+		Quantity:    entity.QuantityUnit,
+		CategoryId:  d.CategoryId,
+	}
+	if d.Quantity != "" {
+		quantity, quantityErr := entity.ParseQuantity(d.Quantity)
+		err = entity.MergeFieldErrors(err, entity.RenameFieldError(quantityErr, field+".quantity"))
+		detail.Quantity = quantity
+	}
+	if d.UnitPrice != nil {
+		money, priceErr := entity.ParseMoney(d.UnitPrice.raw, currency)
+		err = entity.MergeFieldErrors(err, entity.RenameFieldError(priceErr, field+".unitPrice"))
+		detail.UnitPrice = money.Amount
+	}
+	if d.Tags != nil {
+		tags, tagsErr := entity.NewTags(d.Tags...)
+		err = entity.MergeFieldErrors(err, entity.RenameFieldError(tagsErr, field+".tags"))
+		detail.Tags = tags
+	}
+	return detail, err
+}
+
+// toDetails scales the unit prices with currency, the field errors say which line item is wrong
+func toDetails(details []DetailRest, currency entity.Currency) ([]entity.Detail, error) {
+	var err error
+	res := make([]entity.Detail, len(details))
+	for i, d := range details {
+		detail, detailErr := d.toDetail(fmt.Sprintf("details[%d]", i), currency)
+		err = entity.MergeFieldErrors(err, detailErr)
+		res[i] = detail
+	}
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func newDetailRestsFromDetails(details []entity.Detail, currency entity.Currency) []DetailRest {
+	res := make([]DetailRest, len(details))
+	for i, d := range details {
+		res[i] = DetailRest{
+			Description: d.Description,
+			Quantity:    entity.FormatQuantity(d.Quantity),
+			UnitPrice:   NewAmountRest(entity.Money{Amount: d.UnitPrice, Currency: currency}),
+			CategoryId:  d.CategoryId,
+			Total:       NewAmountRest(entity.Money{Amount: d.Total(), Currency: currency}),
+		}
+		if d.Tags != nil {
+			res[i].Tags = d.Tags.Value()
+		}
+	}
+	return res
+}
@@ -0,0 +1,162 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/axpira/backend/entity"
+	"github.com/axpira/backend/entity/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestDetails(t *testing.T) {
+	defaultCurrency := config.Config.DefaultCurrency
+	config.Config.DefaultCurrency = "BRL"
+	defer func() { config.Config.DefaultCurrency = defaultCurrency }()
+
+	groceries := entity.Expense{
+		Id:       "1",
+		Kind:     entity.EXPENSE,
+		Amount:   750,
+		Currency: "BRL",
+		Details: []entity.Detail{
+			{Description: "bread", Quantity: 1500, UnitPrice: 300, CategoryId: "bakery", Tags: entity.MustNewTags("breakfast")},
+			{Description: "milk", Quantity: 1000, UnitPrice: 300},
+		},
+	}
+	tests := map[string]struct {
+		method     string
+		path       string
+		sent       []byte
+		setup      func(*mockExpenseRepo)
+		wantResult []byte
+		wantStatus int
+	}{
+		"create with details": {
+			method: http.MethodPost,
+			path:   "/api/expense",
+			sent: []byte(`{"amount": "7.50", "details": [
+				{"description": "bread", "quantity": "1.5", "unitPrice": "3.00", "categoryId": "bakery", "tags": ["breakfast"]},
+				{"description": "milk", "unitPrice": "3.00"}
+			]}`),
+			setup: func(m *mockExpenseRepo) {
+				e := groceries
+				e.Id = ""
+				m.On("Create", mock.Anything, e).Return("1", nil)
+			},
+			wantStatus: 200,
+			wantResult: []byte(`{"id": "1"}`),
+		},
+		"create with details not summing the amount": {
+			method:     http.MethodPost,
+			path:       "/api/expense",
+			sent:       []byte(`{"amount": "10.00", "details": [{"description": "bread", "quantity": "1.5", "unitPrice": "3.00"}]}`),
+			wantStatus: 400,
+			wantResult: []byte(`{
+				"code": "INVALID_REQUEST",
+				"message": "invalid expense",
+				"fields": [
+					{"field": "details", "code": "invalid_sum", "message": "the totals sum 4.50, not the amount"}
+				]
+			}`),
+		},
+		"create with invalid details": {
+			method:     http.MethodPost,
+			path:       "/api/expense",
+			sent:       []byte(`{"amount": "10.00", "details": [{"description": "bread", "quantity": "1.0001"}, {"description": "milk", "unitPrice": "3.001"}]}`),
+			wantStatus: 400,
+			wantResult: []byte(`{
+				"code": "INVALID_REQUEST",
+				"message": "invalid expense",
+				"fields": [
+					{"field": "details[0].quantity", "code": "invalid", "message": "\"1.0001\" is not a quantity with up to 3 decimal places"},
+					{"field": "details[1].unitPrice", "code": "invalid_precision", "message": "BRL has 2 decimal places"}
+				]
+			}`),
+		},
+		"get with details": {
+			method: http.MethodGet,
+			path:   "/api/expense/1",
+			setup: func(m *mockExpenseRepo) {
+				m.On("Get", mock.Anything, "1").Return(groceries, nil)
+			},
+			wantStatus: 200,
+			wantResult: []byte(`{
+				"id": "1",
+				"amount": "7.50",
+				"currency": "BRL",
+				"details": [
+					{"description": "bread", "quantity": "1.5", "unitPrice": "3.00", "categoryId": "bakery", "tags": ["breakfast"], "total": "4.50"},
+					{"description": "milk", "quantity": "1", "unitPrice": "3.00", "total": "3.00"}
+				]
+			}`),
+		},
+		"update amount must keep summing the stored details": {
+			method: http.MethodPatch,
+			path:   "/api/expense/1",
+			sent:   []byte(`{"amount": "8.00"}`),
+			setup: func(m *mockExpenseRepo) {
+				m.On("Get", mock.Anything, "1").Return(groceries, nil)
+			},
+			wantStatus: 400,
+			wantResult: []byte(`{
+				"code": "INVALID_REQUEST",
+				"message": "invalid expense",
+				"fields": [
+					{"field": "details", "code": "invalid_sum", "message": "the totals sum 7.50, not the amount"}
+				]
+			}`),
+		},
+		"search by detail": {
+			method: http.MethodGet,
+			path:   "/api/expense?detail[regex]=^bread",
+			setup: func(m *mockExpenseRepo) {
+				m.On("SearchPage", mock.Anything, entity.MustNewExpenseFilter(
+					entity.FilterDetail(entity.REGEX, "^bread"),
+					entity.FilterKind(entity.EXPENSE),
+				), defaultPage).Return(entity.Page{}, nil)
+			},
+			wantStatus: 200,
+			wantResult: []byte(`{"items": []}`),
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockedRepo := new(mockExpenseRepo)
+			if tc.setup != nil {
+				tc.setup(mockedRepo)
+			}
+			ts := httptest.NewServer(createHandler(context.Background(), mockedRepo))
+			defer ts.Close()
+
+			req, err := http.NewRequest(tc.method, ts.URL+tc.path, bytes.NewBuffer(tc.sent))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Accept", "application/json")
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tc.wantStatus, res.StatusCode)
+
+			got, err := io.ReadAll(res.Body)
+			res.Body.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			mockedRepo.AssertExpectations(t)
+			if tc.wantResult != nil {
+				assert.JSONEq(t, string(tc.wantResult), string(got))
+			} else {
+				assert.Empty(t, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,289 @@
+package rest
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/axpira/backend/entity"
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	rateDateLayout = "2006-01-02"
+	// rateDecimals is how many decimal places a rate is written with
+	rateDecimals = 12
+	// rateIntegers is how many integer digits a rate has at most, the rest of NUMERIC(24, 12)
+	rateIntegers = 12
+)
+
+// exchangeRateCSVColumns are the columns of the import header, in any order
+var exchangeRateCSVColumns = []string{"date", "from", "to", "rate"}
+
+type ExchangeRateRepository interface {
+	Save(ctx context.Context, rates ...entity.ExchangeRate) error
+	Find(ctx context.Context, from, to entity.Currency, on time.Time) (entity.ExchangeRate, error)
+	List(ctx context.Context, from, to entity.Currency) ([]entity.ExchangeRate, error)
+}
+
+type ExchangeRateRest struct {
+	Date string `json:"date"`
+	From string `json:"from"`
+	To   string `json:"to"`
+	Rate string `json:"rate"`
+}
+
+func (e ExchangeRateRest) ToExchangeRate() (entity.ExchangeRate, error) {
+	var err error
+	rate := entity.ExchangeRate{
+		From: entity.Currency(strings.ToUpper(strings.TrimSpace(e.From))),
+		To:   entity.Currency(strings.ToUpper(strings.TrimSpace(e.To))),
+	}
+	date, err1 := time.Parse(rateDateLayout, strings.TrimSpace(e.Date))
+	if err1 != nil {
+		err = entity.NewFieldError(err, "date", "invalid", "use YYYY-MM-DD")
+	}
+	rate.Date = date
+	value, err1 := parseRate(e.Rate)
+	if err1 != nil {
+		err = entity.NewFieldError(err, "rate", "invalid", err1.Error())
+	}
+	rate.Rate = value
+	if err != nil {
+		return entity.ExchangeRate{}, err
+	}
+	return rate, rate.Validate()
+}
+
+// parseRate reads a decimal the rate column holds, with up to rateDecimals decimal places
+// and less than 10^rateIntegers, so it's never rounded when stored
+func parseRate(value string) (*big.Rat, error) {
+	value = strings.TrimSpace(value)
+	if !decimalPattern.MatchString(value) {
+		return nil, fmt.Errorf("%q is not a decimal number", value)
+	}
+	parts := strings.SplitN(value, ".", 2)
+	integer, fraction := parts[0], ""
+	if len(parts) == 2 {
+		fraction = parts[1]
+	}
+	if len(fraction) > rateDecimals {
+		return nil, fmt.Errorf("%q has more than %d decimal places", value, rateDecimals)
+	}
+	if len(strings.TrimLeft(strings.TrimPrefix(integer, "-"), "0")) > rateIntegers {
+		return nil, fmt.Errorf("%q has more than %d integer digits", value, rateIntegers)
+	}
+	rate, _ := new(big.Rat).SetString(value)
+	return rate, nil
+}
+
+// formatRate writes the rate as a decimal without trailing zeros
+func formatRate(rate *big.Rat) string {
+	s := rate.FloatString(rateDecimals)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+func NewExchangeRateRestFromExchangeRate(e entity.ExchangeRate) ExchangeRateRest {
+	return ExchangeRateRest{
+		Date: e.Date.Format(rateDateLayout),
+		From: string(e.From),
+		To:   string(e.To),
+		Rate: formatRate(e.Rate),
+	}
+}
+
+// ConvertedRest is an expense amount in the base currency
+type ConvertedRest struct {
+	Amount   *amountRest `json:"amount"`
+	Currency string      `json:"currency"`
+	Rate     string      `json:"rate"`
+}
+
+func exchangeRateRoutes(repo ExchangeRateRepository) func(r chi.Router) {
+	return func(r chi.Router) {
+		r.Post("/", saveExchangeRates(repo))
+		r.Get("/", listExchangeRates(repo))
+		r.Post("/import", importExchangeRates(repo))
+	}
+}
+
+func validateExchangeRateError(w http.ResponseWriter, err error) bool {
+	return validateResourceError(w, "exchange rate", err)
+}
+
+func invalidExchangeRates(w http.ResponseWriter, err error) {
+	fillHttpError(w,
+		NewHttpError(http.StatusBadRequest, "",
+			NewError("INVALID_REQUEST", "invalid exchange rate").WithFields(err),
+		),
+	)
+}
+
+// saveExchangeRates receives a list of rates, a rate already stored for the same day is replaced
+func saveExchangeRates(repo ExchangeRateRepository) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		var ratesRest []ExchangeRateRest
+		err := json.NewDecoder(r.Body).Decode(&ratesRest)
+		if err != nil {
+			log.Ctx(ctx).Err(err).Msg("error on decode")
+			fillHttpError(w,
+				NewHttpError(http.StatusBadRequest, "",
+					NewError("INVALID_REQUEST", "invalid json"),
+				),
+			)
+			return
+		}
+		rates := make([]entity.ExchangeRate, len(ratesRest))
+		for i, rateRest := range ratesRest {
+			rates[i], err = rateRest.ToExchangeRate()
+			if err != nil {
+				log.Ctx(ctx).Err(err).Msg("error on validate")
+				invalidExchangeRates(w, err)
+				return
+			}
+		}
+		saveAndCount(w, r, repo, rates)
+	}
+}
+
+// importExchangeRates receives a CSV with the header date,from,to,rate
+func importExchangeRates(repo ExchangeRateRepository) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		rates, err := readExchangeRatesCSV(r.Body)
+		if err != nil {
+			log.Ctx(ctx).Err(err).Msg("error on read csv")
+			var fieldErr entity.FieldError
+			if errors.As(err, &fieldErr) {
+				invalidExchangeRates(w, err)
+				return
+			}
+			fillHttpError(w,
+				NewHttpError(http.StatusBadRequest, "",
+					NewError("INVALID_REQUEST", fmt.Sprintf("invalid csv: %v", err)),
+				),
+			)
+			return
+		}
+		saveAndCount(w, r, repo, rates)
+	}
+}
+
+func saveAndCount(w http.ResponseWriter, r *http.Request, repo ExchangeRateRepository, rates []entity.ExchangeRate) {
+	ctx := r.Context()
+	err := repo.Save(ctx, rates...)
+	if validateExchangeRateError(w, err) {
+		log.Ctx(ctx).Err(err).Msg("error on save")
+		return
+	}
+	err = json.NewEncoder(w).Encode(UpdatedRest{Updated: int64(len(rates))})
+	if validateExchangeRateError(w, err) {
+		return
+	}
+}
+
+// readExchangeRatesCSV reads every line before failing, so all the invalid ones are reported
+func readExchangeRatesCSV(reader io.Reader) ([]entity.ExchangeRate, error) {
+	lines := csv.NewReader(reader)
+	lines.TrimLeadingSpace = true
+	header, err := lines.Read()
+	if err != nil {
+		return nil, fmt.Errorf("missing header: %w", err)
+	}
+	index := make(map[string]int, len(header))
+	for i, column := range header {
+		index[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+	for _, column := range exchangeRateCSVColumns {
+		if _, ok := index[column]; !ok {
+			return nil, fmt.Errorf("header must have the columns %v", exchangeRateCSVColumns)
+		}
+	}
+
+	var rates []entity.ExchangeRate
+	var fieldErr error
+	for line := 2; ; line++ {
+		record, err := lines.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		rate, err := ExchangeRateRest{
+			Date: record[index["date"]],
+			From: record[index["from"]],
+			To:   record[index["to"]],
+			Rate: record[index["rate"]],
+		}.ToExchangeRate()
+		if err != nil {
+			for _, f := range entity.UnwrapFieldErrors(err) {
+				fieldErr = entity.NewFieldError(fieldErr, fmt.Sprintf("line %d %s", line, f.Field()), f.Code(), f.Description())
+			}
+			continue
+		}
+		rates = append(rates, rate)
+	}
+	if fieldErr != nil {
+		return nil, fieldErr
+	}
+	return rates, nil
+}
+
+func listExchangeRates(repo ExchangeRateRepository) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		query := r.URL.Query()
+		rates, err := repo.List(ctx,
+			entity.Currency(strings.ToUpper(query.Get("from"))),
+			entity.Currency(strings.ToUpper(query.Get("to"))),
+		)
+		if validateExchangeRateError(w, err) {
+			log.Ctx(ctx).Err(err).Msg("error on list")
+			return
+		}
+		res := make([]ExchangeRateRest, len(rates))
+		for i, rate := range rates {
+			res[i] = NewExchangeRateRestFromExchangeRate(rate)
+		}
+		err = json.NewEncoder(w).Encode(res)
+		if validateExchangeRateError(w, err) {
+			return
+		}
+	}
+}
+
+// convertExpense returns the expense amount in the base currency with the rate of its day
+func convertExpense(repo ExpenseRepository, converter *entity.Converter) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		expense, err := repo.Get(ctx, chi.URLParam(r, "expenseID"))
+		if validateError(w, err) {
+			log.Ctx(ctx).Err(err).Msg("error on consult")
+			return
+		}
+		money, rate, err := converter.Convert(ctx, expense)
+		if validateExchangeRateError(w, err) {
+			log.Ctx(ctx).Err(err).Msg("error on convert")
+			return
+		}
+		err = json.NewEncoder(w).Encode(ConvertedRest{
+			Amount:   &amountRest{money: money},
+			Currency: string(money.Currency),
+			Rate:     formatRate(rate),
+		})
+		if validateError(w, err) {
+			return
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/axpira/backend/entity"
+	"github.com/axpira/backend/entity/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockExchangeRateRepo struct {
+	mock.Mock
+}
+
+func (m *mockExchangeRateRepo) Save(ctx context.Context, rates ...entity.ExchangeRate) error {
+	args := m.Called(ctx, rates)
+	return args.Error(0)
+}
+func (m *mockExchangeRateRepo) Find(ctx context.Context, from, to entity.Currency, on time.Time) (entity.ExchangeRate, error) {
+	args := m.Called(ctx, from, to, on)
+	return args.Get(0).(entity.ExchangeRate), args.Error(1)
+}
+func (m *mockExchangeRateRepo) List(ctx context.Context, from, to entity.Currency) ([]entity.ExchangeRate, error) {
+	args := m.Called(ctx, from, to)
+	return args.Get(0).([]entity.ExchangeRate), args.Error(1)
+}
+
+// sameRates matches the rates comparing the big.Rat values
+func sameRates(want ...entity.ExchangeRate) interface{} {
+	return mock.MatchedBy(func(got []entity.ExchangeRate) bool {
+		if len(got) != len(want) {
+			return false
+		}
+		for i := range got {
+			if !got[i].Date.Equal(want[i].Date) || got[i].From != want[i].From ||
+				got[i].To != want[i].To || got[i].Rate.Cmp(want[i].Rate) != 0 {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+func TestExchangeRate(t *testing.T) {
+	baseCurrency := config.Config.BaseCurrency
+	config.Config.BaseCurrency = "BRL"
+	defer func() { config.Config.BaseCurrency = baseCurrency }()
+
+	may := time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+	when := time.Date(2021, 5, 3, 10, 0, 0, 0, time.UTC)
+	tests := map[string]struct {
+		method       string
+		path         string
+		sent         []byte
+		setup        func(*mockExchangeRateRepo)
+		setupExpRepo func(*mockExpenseRepo)
+		wantResult   []byte
+		wantStatus   int
+	}{
+		"save": {
+			method: http.MethodPost,
+			path:   "/api/exchange-rate",
+			sent:   []byte(`[{"date": "2021-05-01", "from": "usd", "to": "BRL", "rate": "5.1234"}]`),
+			setup: func(m *mockExchangeRateRepo) {
+				m.On("Save", mock.Anything, sameRates(
+					entity.ExchangeRate{Date: may, From: "USD", To: "BRL", Rate: big.NewRat(51234, 10000)},
+				)).Return(nil)
+			},
+			wantStatus: 200,
+			wantResult: []byte(`{"updated": 1}`),
+		},
+		"save invalid": {
+			method:     http.MethodPost,
+			path:       "/api/exchange-rate",
+			sent:       []byte(`[{"date": "01/05/2021", "from": "USD", "to": "XYZ", "rate": "1/3"}]`),
+			wantStatus: 400,
+			wantResult: []byte(`{
+				"code": "INVALID_REQUEST",
+				"message": "invalid exchange rate",
+				"fields": [
+					{"field": "date", "code": "invalid", "message": "use YYYY-MM-DD"},
+					{"field": "rate", "code": "invalid", "message": "\"1/3\" is not a decimal number"}
+				]
+			}`),
+		},
+		"import csv": {
+			method: http.MethodPost,
+			path:   "/api/exchange-rate/import",
+			sent:   []byte("rate,date,from,to\n5.1234,2021-05-01,USD,BRL\n0.05,2021-05-01,JPY,BRL\n"),
+			setup: func(m *mockExchangeRateRepo) {
+				m.On("Save", mock.Anything, sameRates(
+					entity.ExchangeRate{Date: may, From: "USD", To: "BRL", Rate: big.NewRat(51234, 10000)},
+					entity.ExchangeRate{Date: may, From: "JPY", To: "BRL", Rate: big.NewRat(1, 20)},
+				)).Return(nil)
+			},
+			wantStatus: 200,
+			wantResult: []byte(`{"updated": 2}`),
+		},
+		"import csv invalid lines": {
+			method:     http.MethodPost,
+			path:       "/api/exchange-rate/import",
+			sent:       []byte("date,from,to,rate\n2021-05-01,USD,BRL,5.1\n2021-05-01,USD,USD,1\n2021-05-01,EUR,BRL,-6\n"),
+			wantStatus: 400,
+			wantResult: []byte(`{
+				"code": "INVALID_REQUEST",
+				"message": "invalid exchange rate",
+				"fields": [
+					{"field": "line 3 to", "code": "same_currency", "message": "must be other than from"},
+					{"field": "line 4 rate", "code": "invalid", "message": "must be greater than zero"}
+				]
+			}`),
+		},
+		"import csv rates the column can't hold": {
+			method:     http.MethodPost,
+			path:       "/api/exchange-rate/import",
+			sent:       []byte("date,from,to,rate\n2021-05-01,USD,BRL,5.1234567890123\n2021-05-01,JPY,USD,0.0000000000001\n2021-05-01,VND,BRL,1000000000000\n"),
+			wantStatus: 400,
+			wantResult: []byte(`{
+				"code": "INVALID_REQUEST",
+				"message": "invalid exchange rate",
+				"fields": [
+					{"field": "line 2 rate", "code": "invalid", "message": "\"5.1234567890123\" has more than 12 decimal places"},
+					{"field": "line 3 rate", "code": "invalid", "message": "\"0.0000000000001\" has more than 12 decimal places"},
+					{"field": "line 4 rate", "code": "invalid", "message": "\"1000000000000\" has more than 12 integer digits"}
+				]
+			}`),
+		},
+		"import csv without header": {
+			method:     http.MethodPost,
+			path:       "/api/exchange-rate/import",
+			sent:       []byte("2021-05-01,USD,BRL,5.1\n"),
+			wantStatus: 400,
+			wantResult: []byte(`{
+				"code": "INVALID_REQUEST",
+				"message": "invalid csv: header must have the columns [date from to rate]"
+			}`),
+		},
+		"list": {
+			method: http.MethodGet,
+			path:   "/api/exchange-rate?from=usd",
+			setup: func(m *mockExchangeRateRepo) {
+				m.On("List", mock.Anything, entity.Currency("USD"), entity.Currency("")).Return([]entity.ExchangeRate{
+					{Date: may, From: "USD", To: "BRL", Rate: big.NewRat(51234, 10000)},
+				}, nil)
+			},
+			wantStatus: 200,
+			wantResult: []byte(`[{"date": "2021-05-01", "from": "USD", "to": "BRL", "rate": "5.1234"}]`),
+		},
+		"convert": {
+			method: http.MethodGet,
+			path:   "/api/expense/1/converted",
+			setupExpRepo: func(m *mockExpenseRepo) {
+				m.On("Get", mock.Anything, "1").Return(entity.Expense{Id: "1", Amount: 1500, Currency: "JPY", When: when}, nil)
+			},
+			setup: func(m *mockExchangeRateRepo) {
+				m.On("Find", mock.Anything, entity.Currency("JPY"), entity.Currency("BRL"), when).
+					Return(entity.ExchangeRate{Date: may, From: "JPY", To: "BRL", Rate: big.NewRat(1, 20)}, nil)
+			},
+			wantStatus: 200,
+			wantResult: []byte(`{"amount": "75.00", "currency": "BRL", "rate": "0.05"}`),
+		},
+		"convert without rate": {
+			method: http.MethodGet,
+			path:   "/api/expense/1/converted",
+			setupExpRepo: func(m *mockExpenseRepo) {
+				m.On("Get", mock.Anything, "1").Return(entity.Expense{Id: "1", Amount: 1500, Currency: "JPY", When: when}, nil)
+			},
+			setup: func(m *mockExchangeRateRepo) {
+				m.On("Find", mock.Anything, mock.Anything, mock.Anything, when).
+					Return(entity.ExchangeRate{}, entity.ErrNotFound)
+			},
+			wantStatus: 404,
+			wantResult: []byte(`{"code": "NOT_FOUND", "message": "exchange rate not found"}`),
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockedRepo := new(mockExchangeRateRepo)
+			if tc.setup != nil {
+				tc.setup(mockedRepo)
+			}
+			expenseRepo := new(mockExpenseRepo)
+			if tc.setupExpRepo != nil {
+				tc.setupExpRepo(expenseRepo)
+			}
+			ts := httptest.NewServer(createHandler(context.Background(), expenseRepo, WithExchangeRateRepository(mockedRepo)))
+			defer ts.Close()
+
+			req, err := http.NewRequest(tc.method, ts.URL+tc.path, bytes.NewBuffer(tc.sent))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Accept", "application/json")
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tc.wantStatus, res.StatusCode)
+
+			got, err := io.ReadAll(res.Body)
+			res.Body.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			mockedRepo.AssertExpectations(t)
+			expenseRepo.AssertExpectations(t)
+			assert.JSONEq(t, string(tc.wantResult), string(got))
+		})
+	}
+}
@@ -0,0 +1,326 @@
+package rest
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/axpira/backend/entity"
+	"github.com/axpira/backend/entity/config"
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// ExportRepository calls fn with every expense matching filter while they are read,
+// stopping on the first error of fn
+type ExportRepository interface {
+	Stream(ctx context.Context, filter *entity.ExpenseFilter, fn func(entity.Expense) error) error
+}
+
+// expenseWriter writes one expense at a time in an export format, Close writes what comes after them
+type expenseWriter interface {
+	Write(entity.Expense) error
+	Close() error
+}
+
+type exportFormat struct {
+	contentType string
+	extension   string
+	writer      func(w io.Writer, options exportOptions) expenseWriter
+}
+
+var exportFormats = map[string]exportFormat{
+	"csv":   {contentType: "text/csv; charset=utf-8", extension: "csv", writer: newCSVExpenseWriter},
+	"jsonl": {contentType: "application/x-ndjson", extension: "jsonl", writer: newJSONLinesExpenseWriter},
+	"ofx":   {contentType: "application/x-ofx", extension: "ofx", writer: newOFXExpenseWriter},
+}
+
+type exportOptions struct {
+	// comma and decimalSeparator are the csv ones, like ';' and ',' for a spreadsheet in portuguese
+	comma            rune
+	decimalSeparator string
+	// currency, from and to are the ofx statement ones
+	currency entity.Currency
+	from     time.Time
+	to       time.Time
+	now      time.Time
+}
+
+func exportRoutes(repo ExportRepository) func(r chi.Router) {
+	return func(r chi.Router) {
+		r.Get("/", exportExpenses(repo))
+	}
+}
+
+var csvExportHeader = []string{
+	"id", "kind", "when", "amount", "currency", "what", "where", "who", "tags",
+	"categoryId", "paymentMethodId", "toPaymentMethodId", "externalId",
+}
+
+type csvExpenseWriter struct {
+	w       *csv.Writer
+	options exportOptions
+	header  bool
+}
+
+func newCSVExpenseWriter(w io.Writer, options exportOptions) expenseWriter {
+	writer := csv.NewWriter(w)
+	if options.comma != 0 {
+		writer.Comma = options.comma
+	}
+	return &csvExpenseWriter{w: writer, options: options}
+}
+
+func (c *csvExpenseWriter) writeHeader() error {
+	if c.header {
+		return nil
+	}
+	c.header = true
+	return c.w.Write(csvExportHeader)
+}
+
+func (c *csvExpenseWriter) Write(e entity.Expense) error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	amount := e.Money().String()
+	if c.options.decimalSeparator == "," {
+		amount = strings.Replace(amount, ".", ",", 1)
+	}
+	var tags []string
+	if e.Tags != nil {
+		tags = e.Tags.Value()
+	}
+	err := c.w.Write([]string{
+		e.Id,
+		string(e.KindOrDefault()),
+		e.When.UTC().Format(time.RFC3339),
+		amount,
+		string(e.Currency),
+		e.What,
+		e.Where,
+		e.Who,
+		strings.Join(tags, " "),
+		e.CategoryId,
+		e.PaymentMethodId,
+		e.ToPaymentMethodId,
+		e.ExternalId,
+	})
+	if err != nil {
+		return err
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvExpenseWriter) Close() error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// ExportedExpenseRest has the kind that the API tells by the route
+type ExportedExpenseRest struct {
+	Kind string `json:"kind"`
+	ExpenseRest
+}
+
+// jsonLinesExpenseWriter writes every expense like the API does, one per line
+type jsonLinesExpenseWriter struct {
+	enc *json.Encoder
+}
+
+func newJSONLinesExpenseWriter(w io.Writer, _ exportOptions) expenseWriter {
+	return jsonLinesExpenseWriter{enc: json.NewEncoder(w)}
+}
+
+func (j jsonLinesExpenseWriter) Write(e entity.Expense) error {
+	return j.enc.Encode(ExportedExpenseRest{
+		Kind:        string(e.KindOrDefault()),
+		ExpenseRest: NewExpenseRestFromExpense(e),
+	})
+}
+
+func (j jsonLinesExpenseWriter) Close() error {
+	return nil
+}
+
+// ofxExpenseWriter writes an OFX 2.x bank statement in one currency. The FITID is the id of the
+// expense, incomes are credits and every other kind is a debit
+type ofxExpenseWriter struct {
+	w       io.Writer
+	options exportOptions
+	started bool
+	balance int64
+	err     error
+}
+
+func newOFXExpenseWriter(w io.Writer, options exportOptions) expenseWriter {
+	return &ofxExpenseWriter{w: w, options: options}
+}
+
+const ofxDateLayout = "20060102150405"
+
+func ofxDate(t time.Time) string {
+	return t.UTC().Format(ofxDateLayout) + "[0:GMT]"
+}
+
+func ofxText(s string, max int) string {
+	if utf8.RuneCountInString(s) > max {
+		s = string([]rune(s)[:max])
+	}
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func (o *ofxExpenseWriter) printf(format string, args ...interface{}) {
+	if o.err == nil {
+		_, o.err = fmt.Fprintf(o.w, format, args...)
+	}
+}
+
+func (o *ofxExpenseWriter) start() {
+	if o.started {
+		return
+	}
+	o.started = true
+	o.printf("<?xml version=\"1.0\" encoding=\"UTF-8\" standalone=\"no\"?>\n")
+	o.printf("<?OFX OFXHEADER=\"200\" VERSION=\"220\" SECURITY=\"NONE\" OLDFILEUID=\"NONE\" NEWFILEUID=\"NONE\"?>\n")
+	o.printf("<OFX>\n<SIGNONMSGSRSV1><SONRS><STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>"+
+		"<DTSERVER>%s</DTSERVER><LANGUAGE>ENG</LANGUAGE></SONRS></SIGNONMSGSRSV1>\n", ofxDate(o.options.now))
+	o.printf("<BANKMSGSRSV1><STMTTRNRS><TRNUID>0</TRNUID><STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>\n")
+	o.printf("<STMTRS><CURDEF>%s</CURDEF>\n", o.options.currency)
+	o.printf("<BANKACCTFROM><BANKID>backend</BANKID><ACCTID>export</ACCTID><ACCTTYPE>CHECKING</ACCTTYPE></BANKACCTFROM>\n")
+	o.printf("<BANKTRANLIST><DTSTART>%s</DTSTART><DTEND>%s</DTEND>\n", ofxDate(o.options.from), ofxDate(o.options.to))
+}
+
+func (o *ofxExpenseWriter) Write(e entity.Expense) error {
+	o.start()
+	trnType, amount := "DEBIT", -e.Amount
+	if e.KindOrDefault() == entity.INCOME {
+		trnType, amount = "CREDIT", e.Amount
+	}
+	o.balance += amount
+	o.printf("<STMTTRN><TRNTYPE>%s</TRNTYPE><DTPOSTED>%s</DTPOSTED><TRNAMT>%s</TRNAMT><FITID>%s</FITID><NAME>%s</NAME></STMTTRN>\n",
+		trnType,
+		ofxDate(e.When),
+		entity.Money{Amount: amount, Currency: e.Currency},
+		ofxText(e.Id, 255),
+		ofxText(e.What, 32),
+	)
+	return o.err
+}
+
+func (o *ofxExpenseWriter) Close() error {
+	o.start()
+	o.printf("</BANKTRANLIST>\n<LEDGERBAL><BALAMT>%s</BALAMT><DTASOF>%s</DTASOF></LEDGERBAL>\n",
+		entity.Money{Amount: o.balance, Currency: o.options.currency},
+		ofxDate(o.options.now),
+	)
+	o.printf("</STMTRS></STMTTRNRS></BANKMSGSRSV1>\n</OFX>\n")
+	return o.err
+}
+
+// parseExportQuery reads the format, csv when it's omitted, its options and the search filters from
+// the other params. An ofx statement has one currency, the one of the currency filter or
+// config.Config.DefaultCurrency, and goes from the when filters or from the epoch until now
+func parseExportQuery(query url.Values, now time.Time) (exportFormat, exportOptions, *entity.ExpenseFilter, error) {
+	var err error
+	name := query.Get("format")
+	if name == "" {
+		name = "csv"
+	}
+	format, ok := exportFormats[name]
+	if !ok {
+		err = entity.NewFieldError(err, "format", "invalid", "must be one of [csv jsonl ofx]")
+	}
+	options := exportOptions{
+		decimalSeparator: query.Get("decimalSeparator"),
+		currency:         entity.Currency(config.Config.DefaultCurrency),
+		from:             time.Unix(0, 0).UTC(),
+		to:               now,
+		now:              now,
+	}
+	if currency, ok := searchedCurrency(query); ok {
+		options.currency = currency
+	}
+	if options.decimalSeparator != "" && options.decimalSeparator != "." && options.decimalSeparator != "," {
+		err = entity.NewFieldError(err, "decimalSeparator", "invalid", `must be "." or ","`)
+	}
+	if comma := query.Get("comma"); comma != "" {
+		r, size := utf8.DecodeRuneInString(comma)
+		if size != len(comma) {
+			err = entity.NewFieldError(err, "comma", "invalid", "must be a single character")
+		}
+		options.comma = r
+	}
+	filter, filterErr := parseExpenseFilter(withoutParams(query, "format", "comma", "decimalSeparator"))
+	if filterErr == nil && name == "ofx" {
+		filterErr = entity.FilterCurrency(entity.EQUALS, string(options.currency))(filter)
+	}
+	err = entity.MergeFieldErrors(err, filterErr)
+	if err != nil {
+		return exportFormat{}, exportOptions{}, nil, err
+	}
+	for _, when := range filter.When {
+		switch when.Type {
+		case entity.GE, entity.GT:
+			options.from = when.Value
+		case entity.LE, entity.LT:
+			options.to = when.Value
+		}
+	}
+	return format, options, filter, nil
+}
+
+// exportExpenses streams every transaction matching the filters. Once the first one is written
+// the status can't change anymore, so a later error only ends the file early
+func exportExpenses(repo ExportRepository) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		format, options, filter, err := parseExportQuery(r.URL.Query(), time.Now().UTC())
+		if err != nil {
+			log.Ctx(ctx).Err(err).Msg("error on parse filter")
+			fillHttpError(w,
+				NewHttpError(http.StatusBadRequest, "",
+					NewError("INVALID_FILTER", "invalid filter").WithFields(err),
+				),
+			)
+			return
+		}
+		writer := format.writer(w, options)
+		started := false
+		start := func() {
+			if !started {
+				started = true
+				w.Header().Set("Content-Type", format.contentType)
+				w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "expenses."+format.extension))
+			}
+		}
+		err = repo.Stream(ctx, filter, func(e entity.Expense) error {
+			start()
+			return writer.Write(e)
+		})
+		if err == nil {
+			start()
+			err = writer.Close()
+		}
+		if err != nil {
+			log.Ctx(ctx).Err(err).Msg("error on export")
+			if !started {
+				validateResourceError(w, "export", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/axpira/backend/api/importer"
+	"github.com/axpira/backend/entity"
+	"github.com/axpira/backend/entity/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockExportRepo struct {
+	mock.Mock
+}
+
+func (m *mockExportRepo) Stream(ctx context.Context, filter *entity.ExpenseFilter, fn func(entity.Expense) error) error {
+	args := m.Called(ctx, filter)
+	for _, e := range args.Get(0).([]entity.Expense) {
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+func TestExport(t *testing.T) {
+	defaultCurrency := config.Config.DefaultCurrency
+	config.Config.DefaultCurrency = "BRL"
+	defer func() { config.Config.DefaultCurrency = defaultCurrency }()
+
+	expenses := []entity.Expense{
+		{
+			Id:              "bakery",
+			Kind:            entity.EXPENSE,
+			Amount:          123456,
+			Currency:        "BRL",
+			When:            time.Date(2021, 3, 2, 12, 0, 0, 0, time.UTC),
+			What:            "bread, milk",
+			Tags:            entity.MustNewTags("food", "home"),
+			PaymentMethodId: "checking",
+		},
+		{
+			Id:       "salary",
+			Kind:     entity.INCOME,
+			Amount:   500000,
+			Currency: "BRL",
+			When:     time.Date(2021, 3, 5, 0, 0, 0, 0, time.UTC),
+			What:     "salary",
+		},
+	}
+	march, _ := entity.NewExpenseFilter(
+		entity.FilterWhen(entity.GE, time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)),
+		entity.FilterWhen(entity.LT, time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC)),
+	)
+	tests := map[string]struct {
+		path            string
+		setup           func(*mockExportRepo)
+		wantStatus      int
+		wantContentType string
+		check           func(t *testing.T, got []byte)
+	}{
+		"csv for a spreadsheet in portuguese": {
+			path: "/api/export?comma=%3B&decimalSeparator=,&when[gte]=2021-03-01&when[lt]=2021-04-01",
+			setup: func(m *mockExportRepo) {
+				m.On("Stream", mock.Anything, march).Return(expenses, nil)
+			},
+			wantStatus:      200,
+			wantContentType: "text/csv; charset=utf-8",
+			check: func(t *testing.T, got []byte) {
+				assert.Equal(t, "id;kind;when;amount;currency;what;where;who;tags;categoryId;paymentMethodId;toPaymentMethodId;externalId\n"+
+					"bakery;expense;2021-03-02T12:00:00Z;1234,56;BRL;bread, milk;;;food home;;checking;;\n"+
+					"salary;income;2021-03-05T00:00:00Z;5000,00;BRL;salary;;;;;;;\n", string(got))
+			},
+		},
+		"csv header only": {
+			path: "/api/export",
+			setup: func(m *mockExportRepo) {
+				m.On("Stream", mock.Anything, entity.MustNewExpenseFilter()).Return([]entity.Expense{}, nil)
+			},
+			wantStatus:      200,
+			wantContentType: "text/csv; charset=utf-8",
+			check: func(t *testing.T, got []byte) {
+				assert.Equal(t, "id,kind,when,amount,currency,what,where,who,tags,categoryId,paymentMethodId,toPaymentMethodId,externalId\n", string(got))
+			},
+		},
+		"jsonl": {
+			path: "/api/export?format=jsonl",
+			setup: func(m *mockExportRepo) {
+				m.On("Stream", mock.Anything, entity.MustNewExpenseFilter()).Return(expenses, nil)
+			},
+			wantStatus:      200,
+			wantContentType: "application/x-ndjson",
+			check: func(t *testing.T, got []byte) {
+				lines := bytes.Split(bytes.TrimSpace(got), []byte("\n"))
+				if assert.Len(t, lines, 2) {
+					assert.JSONEq(t, `{
+						"id": "bakery", "kind": "expense", "amount": "1234.56", "currency": "BRL", "when": "2021-03-02T12:00:00Z",
+						"what": "bread, milk", "tags": ["food", "home"], "paymentMethodId": "checking"
+					}`, string(lines[0]))
+					assert.JSONEq(t, `{
+						"id": "salary", "kind": "income", "amount": "5000.00", "currency": "BRL", "when": "2021-03-05T00:00:00Z", "what": "salary"
+					}`, string(lines[1]))
+				}
+			},
+		},
+		"ofx in the default currency": {
+			path: "/api/export?format=ofx&when[gte]=2021-03-01&when[lt]=2021-04-01",
+			setup: func(m *mockExportRepo) {
+				filter := *march
+				filter.Currency = []entity.StrFilter{{Type: entity.EQUALS, Value: "BRL"}}
+				m.On("Stream", mock.Anything, &filter).Return(expenses, nil)
+			},
+			wantStatus:      200,
+			wantContentType: "application/x-ofx",
+			check: func(t *testing.T, got []byte) {
+				assert.Contains(t, string(got), "<DTSTART>20210301000000[0:GMT]</DTSTART><DTEND>20210401000000[0:GMT]</DTEND>")
+				assert.Contains(t, string(got), "<LEDGERBAL><BALAMT>3765.44</BALAMT>")
+				records, err := importer.ParseOFX(bytes.NewReader(got), importer.OFXOptions{})
+				if assert.NoError(t, err, "must be read by the importer") && assert.Len(t, records, 2) {
+					assert.Equal(t, entity.Expense{
+						Kind:       entity.EXPENSE,
+						Amount:     123456,
+						Currency:   "BRL",
+						When:       time.Date(2021, 3, 2, 12, 0, 0, 0, time.UTC),
+						What:       "bread, milk",
+						ExternalId: "ofx:backend:export:bakery",
+					}, records[0].Expense)
+					assert.Equal(t, entity.INCOME, records[1].Expense.Kind)
+				}
+			},
+		},
+		"invalid format": {
+			path:            "/api/export?format=xlsx&decimalSeparator=%3B",
+			wantStatus:      400,
+			wantContentType: "application/json",
+			check: func(t *testing.T, got []byte) {
+				assert.JSONEq(t, `{
+					"code": "INVALID_FILTER",
+					"message": "invalid filter",
+					"fields": [
+						{"field": "format", "code": "invalid", "message": "must be one of [csv jsonl ofx]"},
+						{"field": "decimalSeparator", "code": "invalid", "message": "must be \".\" or \",\""}
+					]
+				}`, string(got))
+			},
+		},
+		"error before the first expense": {
+			path: "/api/export?format=jsonl",
+			setup: func(m *mockExportRepo) {
+				m.On("Stream", mock.Anything, entity.MustNewExpenseFilter()).Return([]entity.Expense{}, errors.New("connection refused"))
+			},
+			wantStatus:      500,
+			wantContentType: "application/json",
+			check: func(t *testing.T, got []byte) {
+				assert.JSONEq(t, `{"code": "INTERNAL_ERROR", "message": "internal error"}`, string(got))
+			},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockedRepo := new(mockExportRepo)
+			if tc.setup != nil {
+				tc.setup(mockedRepo)
+			}
+			ts := httptest.NewServer(createHandler(context.Background(), new(mockExpenseRepo), WithExportRepository(mockedRepo)))
+			defer ts.Close()
+
+			res, err := getJSON(ts.URL + tc.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tc.wantStatus, res.StatusCode)
+			assert.Equal(t, tc.wantContentType, res.Header.Get("Content-Type"))
+
+			got, err := io.ReadAll(res.Body)
+			res.Body.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			mockedRepo.AssertExpectations(t)
+			tc.check(t, got)
+		})
+	}
+}
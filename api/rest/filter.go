@@ -0,0 +1,247 @@
+package rest
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/axpira/backend/entity"
+	"github.com/axpira/backend/entity/config"
+)
+
+var (
+	opFilterParams = map[string]entity.OpFilterType{
+		"":    entity.EQ,
+		"eq":  entity.EQ,
+		"lt":  entity.LT,
+		"gt":  entity.GT,
+		"le":  entity.LE,
+		"lte": entity.LE,
+		"ge":  entity.GE,
+		"gte": entity.GE,
+	}
+	strFilterParams = map[string]entity.StrFilterType{
+		"":      entity.EQUALS,
+		"eq":    entity.EQUALS,
+		"regex": entity.REGEX,
+	}
+	timeFilterLayouts = []string{
+		time.RFC3339Nano,
+		"2006-01-02",
+	}
+)
+
+type filterParser func(op, value string) (func(*entity.ExpenseFilter) error, error)
+
+// newExpenseFilterParsers returns the parser of every field, amounts are scaled with currency
+func newExpenseFilterParsers(currency entity.Currency) map[string]filterParser {
+	return map[string]filterParser{
+		"amount":        amountFilterParser(currency),
+		"currency":      strFilterParser(entity.FilterCurrency),
+		"when":          timeFilterParser(entity.FilterWhen),
+		"where":         urnFilterParser(entity.PLACE, entity.FilterWhere),
+		"who":           urnFilterParser(entity.PERSON, entity.FilterWho),
+		"what":          strFilterParser(entity.FilterWhat),
+		"detail":        strFilterParser(entity.FilterDetail),
+		"tag":           strFilterParser(entity.FilterTag),
+		"category":      strFilterParser(entity.FilterCategory),
+		"paymentMethod": strFilterParser(entity.FilterPaymentMethod),
+		"createdAt":     timeFilterParser(entity.FilterCreatedAt),
+		"updatedAt":     timeFilterParser(entity.FilterUpdatedAt),
+	}
+}
+
+// metadataFilterParser matches the value of key, or just its existence with the exists operator
+func metadataFilterParser(key string) filterParser {
+	return func(op, value string) (func(*entity.ExpenseFilter) error, error) {
+		if op == "exists" {
+			return entity.FilterMetadataKey(key), nil
+		}
+		t, ok := strFilterParams[op]
+		if !ok {
+			return nil, fmt.Errorf("invalid operator %q", op)
+		}
+		return entity.FilterMetadata(t, key, value), nil
+	}
+}
+
+func amountFilterParser(currency entity.Currency) filterParser {
+	return func(op, value string) (func(*entity.ExpenseFilter) error, error) {
+		t, err := parseOpFilter(op)
+		if err != nil {
+			return nil, err
+		}
+		amount, err := entity.ParseMoney(value, currency)
+		if err != nil {
+			return nil, fmt.Errorf("invalid amount %q", value)
+		}
+		return entity.FilterAmountInt(t, int(amount.Amount)), nil
+	}
+}
+
+// searchedCurrency is the currency the query filters by equality, when it's a single valid one
+func searchedCurrency(query url.Values) (entity.Currency, bool) {
+	var values []string
+	for param, v := range query {
+		field, op, _ := splitFilterParam(param)
+		if t, ok := strFilterParams[strings.ToLower(op)]; field == "currency" && ok && t == entity.EQUALS {
+			values = append(values, v...)
+		}
+	}
+	if len(values) != 1 {
+		return "", false
+	}
+	currency, err := entity.ParseCurrency(values[0])
+	if err != nil {
+		return "", false
+	}
+	return currency, true
+}
+
+// filterCurrency is the currency of the amount filters. They compare minor units, so the query
+// must search a single currency. Without any currency filter it's config.Config.DefaultCurrency
+// and implicit says it must be added as one
+func filterCurrency(query url.Values) (currency entity.Currency, implicit bool, err error) {
+	var hasAmount, hasCurrency bool
+	for param := range query {
+		switch field, _, _ := splitFilterParam(param); field {
+		case "amount":
+			hasAmount = true
+		case "currency":
+			hasCurrency = true
+		}
+	}
+	if !hasCurrency {
+		return entity.Currency(config.Config.DefaultCurrency), hasAmount, nil
+	}
+	currency, ok := searchedCurrency(query)
+	if hasAmount && !ok {
+		return "", false, entity.NewFieldError(nil, "currency", "required", "amount filters need a single currency, like currency=BRL")
+	}
+	if !ok {
+		currency = entity.Currency(config.Config.DefaultCurrency)
+	}
+	return currency, false, nil
+}
+
+func parseOpFilter(op string) (entity.OpFilterType, error) {
+	t, ok := opFilterParams[op]
+	if !ok {
+		return 0, fmt.Errorf("invalid operator %q", op)
+	}
+	return t, nil
+}
+
+func parseTime(value string) (time.Time, error) {
+	for _, layout := range timeFilterLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, use RFC3339 or YYYY-MM-DD", value)
+}
+
+func timeFilterParser(f func(entity.OpFilterType, time.Time) func(*entity.ExpenseFilter) error) filterParser {
+	return func(op, value string) (func(*entity.ExpenseFilter) error, error) {
+		t, err := parseOpFilter(op)
+		if err != nil {
+			return nil, err
+		}
+		v, err := parseTime(value)
+		if err != nil {
+			return nil, err
+		}
+		return f(t, v), nil
+	}
+}
+
+// urnFilterParser only has the equals operator, value must be an URN of kind
+func urnFilterParser(kind entity.URNKind, f func(entity.URN) func(*entity.ExpenseFilter) error) filterParser {
+	return func(op, value string) (func(*entity.ExpenseFilter) error, error) {
+		if t, ok := strFilterParams[op]; !ok || t != entity.EQUALS {
+			return nil, fmt.Errorf("invalid operator %q", op)
+		}
+		if _, err := entity.URN(value).Id(kind); err != nil {
+			return nil, fmt.Errorf("invalid urn %q, use urn:%s:<id>", value, kind)
+		}
+		return f(entity.URN(value)), nil
+	}
+}
+
+func strFilterParser(f func(entity.StrFilterType, string) func(*entity.ExpenseFilter) error) filterParser {
+	return func(op, value string) (func(*entity.ExpenseFilter) error, error) {
+		t, ok := strFilterParams[op]
+		if !ok {
+			return nil, fmt.Errorf("invalid operator %q", op)
+		}
+		return f(t, value), nil
+	}
+}
+
+// splitFilterParam splits a query param like "amount[gte]" into field and operator
+func splitFilterParam(param string) (string, string, bool) {
+	i := strings.Index(param, "[")
+	if i == -1 {
+		return param, "", true
+	}
+	if !strings.HasSuffix(param, "]") {
+		return "", "", false
+	}
+	return param[:i], param[i+1 : len(param)-1], true
+}
+
+// withoutParams copies query without the params that aren't filters
+func withoutParams(query url.Values, params ...string) url.Values {
+	res := make(url.Values, len(query))
+	for param, values := range query {
+		res[param] = values
+	}
+	for _, param := range params {
+		delete(res, param)
+	}
+	return res
+}
+
+func parseExpenseFilter(query url.Values) (*entity.ExpenseFilter, error) {
+	params := make([]string, 0, len(query))
+	for param := range query {
+		params = append(params, param)
+	}
+	sort.Strings(params)
+
+	currency, implicit, err := filterCurrency(query)
+	expenseFilterParsers := newExpenseFilterParsers(currency)
+	filters := make([]func(*entity.ExpenseFilter) error, 0, len(params)+1)
+	if implicit {
+		filters = append(filters, entity.FilterCurrency(entity.EQUALS, string(currency)))
+	}
+	for _, param := range params {
+		field, op, ok := splitFilterParam(param)
+		if !ok {
+			err = entity.NewFieldError(err, param, "invalid_syntax", "use field[operator]=value")
+			continue
+		}
+		parser, ok := expenseFilterParsers[field]
+		if key := strings.TrimPrefix(field, "metadata."); key != field {
+			parser, ok = metadataFilterParser(key), true
+		}
+		if !ok {
+			err = entity.NewFieldError(err, param, "unknown_field", fmt.Sprintf("unknown filter field %q", field))
+			continue
+		}
+		for _, value := range query[param] {
+			f, err1 := parser(strings.ToLower(op), value)
+			if err1 != nil {
+				err = entity.NewFieldError(err, param, "invalid_value", err1.Error())
+				continue
+			}
+			filters = append(filters, f)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return entity.NewExpenseFilter(filters...)
+}
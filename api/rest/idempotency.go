@@ -0,0 +1,178 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/axpira/backend/entity"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set on the responses replayed from a previous request
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	// idempotencyStoreTimeout bounds storing or releasing the key once the request is answered
+	idempotencyStoreTimeout = 5 * time.Second
+)
+
+// IdempotencyRepository keeps the responses of the requests sent with an Idempotency-Key.
+// Reserve is a conflict while the key is stored and not expired
+type IdempotencyRepository interface {
+	Reserve(ctx context.Context, key entity.IdempotencyKey) error
+	Get(ctx context.Context, key string) (entity.IdempotencyKey, error)
+	Save(ctx context.Context, key entity.IdempotencyKey) error
+	Delete(ctx context.Context, key string) error
+}
+
+// responseRecorder keeps a copy of the response written through it
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// detached keeps the logger of ctx without its cancellation, so the response is stored
+// even when the client is gone or the request timed out
+func detached(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(log.Ctx(ctx).WithContext(context.Background()), idempotencyStoreTimeout)
+}
+
+// requestHash tells apart requests with the same key, by their method, path and body
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Idempotent answers the retries of a request sent with an Idempotency-Key with its first
+// response during window. The key can't be reused with another request, and it's released
+// when the response is a server error, the handler panics or the response can't be stored,
+// so the request can be retried
+func Idempotent(repo IdempotencyRepository, window time.Duration) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				log.Ctx(ctx).Err(err).Msg("error on read body")
+				fillHttpError(w,
+					NewHttpError(http.StatusBadRequest, "",
+						NewError("INVALID_REQUEST", "invalid body"),
+					),
+				)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			hash := requestHash(r, body)
+			err = repo.Reserve(ctx, entity.IdempotencyKey{
+				Key:         key,
+				RequestHash: hash,
+				ExpiresAt:   time.Now().Add(window),
+			})
+			if errors.Is(err, entity.ErrConflict) {
+				replay(w, r, repo, key, hash)
+				return
+			}
+			if validateResourceError(w, "idempotency key", err) {
+				log.Ctx(ctx).Err(err).Msg("error on reserve idempotency key")
+				return
+			}
+
+			rec := &responseRecorder{ResponseWriter: w}
+			saved := false
+			defer func() {
+				if saved {
+					return
+				}
+				storeCtx, cancel := detached(ctx)
+				defer cancel()
+				if err := repo.Delete(storeCtx, key); err != nil {
+					log.Ctx(ctx).Err(err).Msg("error on release idempotency key")
+				}
+			}()
+			next.ServeHTTP(rec, r)
+			if rec.status == 0 || rec.status >= http.StatusInternalServerError {
+				return
+			}
+			storeCtx, cancel := detached(ctx)
+			defer cancel()
+			err = repo.Save(storeCtx, entity.IdempotencyKey{
+				Key:         key,
+				RequestHash: hash,
+				Status:      rec.status,
+				ContentType: rec.Header().Get("Content-Type"),
+				Response:    rec.body.Bytes(),
+			})
+			if err != nil {
+				log.Ctx(ctx).Err(err).Msg("error on save idempotency key")
+				return
+			}
+			saved = true
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+// replay writes the stored response of key, unless it was sent with another request
+// or its first request wasn't answered yet
+func replay(w http.ResponseWriter, r *http.Request, repo IdempotencyRepository, key, hash string) {
+	ctx := r.Context()
+	stored, err := repo.Get(ctx, key)
+	if validateResourceError(w, "idempotency key", err) {
+		log.Ctx(ctx).Err(err).Msg("error on get idempotency key")
+		return
+	}
+	switch {
+	case stored.RequestHash != hash:
+		fillHttpError(w,
+			NewHttpError(http.StatusUnprocessableEntity, "",
+				NewError("IDEMPOTENCY_KEY_REUSED", "idempotency key was used with another request"),
+			),
+		)
+	case stored.Status == 0:
+		fillHttpError(w,
+			NewHttpError(http.StatusConflict, "",
+				NewError("CONFLICT", "request with this idempotency key is still in progress"),
+			),
+		)
+	default:
+		if stored.ContentType != "" {
+			w.Header().Set("Content-Type", stored.ContentType)
+		}
+		w.Header().Set(IdempotentReplayedHeader, "true")
+		w.WriteHeader(stored.Status)
+		w.Write(stored.Response)
+	}
+}
@@ -0,0 +1,312 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/axpira/backend/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockIdempotencyRepo struct {
+	mock.Mock
+}
+
+func (m *mockIdempotencyRepo) Reserve(ctx context.Context, key entity.IdempotencyKey) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
+func (m *mockIdempotencyRepo) Get(ctx context.Context, key string) (entity.IdempotencyKey, error) {
+	args := m.Called(ctx, key)
+	return args.Get(0).(entity.IdempotencyKey), args.Error(1)
+}
+func (m *mockIdempotencyRepo) Save(ctx context.Context, key entity.IdempotencyKey) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
+func (m *mockIdempotencyRepo) Delete(ctx context.Context, key string) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
+
+func hashOf(method, path, body string) string {
+	sum := sha256.Sum256([]byte(method + " " + path + "\n" + body))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestIdempotency(t *testing.T) {
+	window := time.Hour
+	sent := `{"amount": "1.20", "currency": "BRL"}`
+	hash := hashOf(http.MethodPost, "/api/expense", sent)
+	expense := withKind(entity.Expense{Amount: 120, Currency: "BRL"}, entity.EXPENSE)
+	reserved := mock.MatchedBy(func(k entity.IdempotencyKey) bool {
+		return k.Key == "abc" && k.RequestHash == hash && k.Status == 0 &&
+			k.ExpiresAt.After(time.Now()) && !k.ExpiresAt.After(time.Now().Add(window))
+	})
+	tests := map[string]struct {
+		key             string
+		sent            string
+		accept          string
+		setup           func(*mockIdempotencyRepo)
+		setupExpense    func(*mockExpenseRepo)
+		wantStatus      int
+		wantResult      string
+		wantContentType string
+		wantReplayed    bool
+	}{
+		"without key": {
+			sent: sent,
+			setupExpense: func(m *mockExpenseRepo) {
+				m.On("Create", mock.Anything, expense).Return("1", nil)
+			},
+			wantStatus: 200,
+			wantResult: `{"id": "1"}`,
+		},
+		"first request": {
+			key:  "abc",
+			sent: sent,
+			setup: func(m *mockIdempotencyRepo) {
+				m.On("Reserve", mock.Anything, reserved).Return(nil)
+				m.On("Save", mock.Anything, entity.IdempotencyKey{
+					Key:         "abc",
+					RequestHash: hash,
+					Status:      200,
+					ContentType: "application/json",
+					Response:    []byte(`{"id":"1"}`),
+				}).Return(nil)
+			},
+			setupExpense: func(m *mockExpenseRepo) {
+				m.On("Create", mock.Anything, expense).Return("1", nil)
+			},
+			wantStatus: 200,
+			wantResult: `{"id": "1"}`,
+		},
+		"retry": {
+			key:  "abc",
+			sent: sent,
+			setup: func(m *mockIdempotencyRepo) {
+				m.On("Reserve", mock.Anything, reserved).Return(entity.ErrConflict)
+				m.On("Get", mock.Anything, "abc").Return(entity.IdempotencyKey{
+					Key:         "abc",
+					RequestHash: hash,
+					Status:      200,
+					ContentType: "application/json",
+					Response:    []byte(`{"id":"1"}`),
+				}, nil)
+			},
+			wantStatus:   200,
+			wantResult:   `{"id": "1"}`,
+			wantReplayed: true,
+		},
+		"retry of an invalid request": {
+			key:  "abc",
+			sent: sent,
+			setup: func(m *mockIdempotencyRepo) {
+				m.On("Reserve", mock.Anything, reserved).Return(entity.ErrConflict)
+				m.On("Get", mock.Anything, "abc").Return(entity.IdempotencyKey{
+					Key:         "abc",
+					RequestHash: hash,
+					Status:      400,
+					ContentType: problemContentType,
+					Response:    []byte(`{"type": "urn:problem:invalid-request", "title": "Bad Request", "status": 400}`),
+				}, nil)
+			},
+			wantStatus:      400,
+			wantResult:      `{"type": "urn:problem:invalid-request", "title": "Bad Request", "status": 400}`,
+			wantContentType: problemContentType,
+			wantReplayed:    true,
+		},
+		"key reused with another body": {
+			key:  "abc",
+			sent: sent,
+			setup: func(m *mockIdempotencyRepo) {
+				m.On("Reserve", mock.Anything, reserved).Return(entity.ErrConflict)
+				m.On("Get", mock.Anything, "abc").Return(entity.IdempotencyKey{
+					Key:         "abc",
+					RequestHash: hashOf(http.MethodPost, "/api/expense", `{"amount": "2.00"}`),
+					Status:      200,
+					Response:    []byte(`{"id":"1"}`),
+				}, nil)
+			},
+			wantStatus: 422,
+			wantResult: `{"code": "IDEMPOTENCY_KEY_REUSED", "message": "idempotency key was used with another request"}`,
+		},
+		"retry while in progress": {
+			key:  "abc",
+			sent: sent,
+			setup: func(m *mockIdempotencyRepo) {
+				m.On("Reserve", mock.Anything, reserved).Return(entity.ErrConflict)
+				m.On("Get", mock.Anything, "abc").Return(entity.IdempotencyKey{Key: "abc", RequestHash: hash}, nil)
+			},
+			wantStatus: 409,
+			wantResult: `{"code": "CONFLICT", "message": "request with this idempotency key is still in progress"}`,
+		},
+		"server error releases the key": {
+			key:  "abc",
+			sent: sent,
+			setup: func(m *mockIdempotencyRepo) {
+				m.On("Reserve", mock.Anything, reserved).Return(nil)
+				m.On("Delete", mock.Anything, "abc").Return(nil)
+			},
+			setupExpense: func(m *mockExpenseRepo) {
+				m.On("Create", mock.Anything, expense).Return("", entity.ErrUnknown)
+			},
+			wantStatus: 500,
+			wantResult: `{"code": "INTERNAL_ERROR", "message": "internal error"}`,
+		},
+		"invalid request is kept as a problem": {
+			key:    "abc",
+			sent:   `{"amount": "1.20", "currency": "BRL", "when": "yesterday"}`,
+			accept: problemContentType,
+			setup: func(m *mockIdempotencyRepo) {
+				m.On("Reserve", mock.Anything, mock.AnythingOfType("entity.IdempotencyKey")).Return(nil)
+				m.On("Save", mock.Anything, mock.MatchedBy(func(k entity.IdempotencyKey) bool {
+					return k.Status == 400 && k.ContentType == problemContentType
+				})).Return(nil)
+			},
+			wantStatus:      400,
+			wantContentType: problemContentType,
+		},
+		"invalid key": {
+			key:  strings.Repeat("a", 256),
+			sent: sent,
+			setup: func(m *mockIdempotencyRepo) {
+				m.On("Reserve", mock.Anything, mock.AnythingOfType("entity.IdempotencyKey")).
+					Return(entity.NewFieldError(nil, "idempotencyKey", "too_long", "can't have more than 255 characters"))
+			},
+			wantStatus: 400,
+			wantResult: `{
+				"code": "INVALID_REQUEST",
+				"message": "invalid idempotency key",
+				"fields": [
+					{"field": "idempotencyKey", "code": "too_long", "message": "can't have more than 255 characters"}
+				]
+			}`,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockedRepo := new(mockIdempotencyRepo)
+			if tc.setup != nil {
+				tc.setup(mockedRepo)
+			}
+			mockedExpenseRepo := new(mockExpenseRepo)
+			if tc.setupExpense != nil {
+				tc.setupExpense(mockedExpenseRepo)
+			}
+			ts := httptest.NewServer(createHandler(context.Background(), mockedExpenseRepo, WithIdempotencyRepository(mockedRepo, window)))
+			defer ts.Close()
+
+			req, err := http.NewRequest(http.MethodPost, ts.URL+"/api/expense", bytes.NewBufferString(tc.sent))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.key != "" {
+				req.Header.Set(IdempotencyKeyHeader, tc.key)
+			}
+			accept := tc.accept
+			if accept == "" {
+				accept = "application/json"
+			}
+			req.Header.Set("Accept", accept)
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tc.wantStatus, res.StatusCode)
+			if tc.wantContentType != "" {
+				assert.Equal(t, tc.wantContentType, res.Header.Get("Content-Type"))
+			}
+			if tc.wantReplayed {
+				assert.Equal(t, "true", res.Header.Get(IdempotentReplayedHeader))
+			} else {
+				assert.Empty(t, res.Header.Get(IdempotentReplayedHeader))
+			}
+
+			got, err := io.ReadAll(res.Body)
+			res.Body.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.wantResult != "" {
+				assert.JSONEq(t, tc.wantResult, string(got))
+			}
+			mockedRepo.AssertExpectations(t)
+			mockedExpenseRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestIdempotencyStoresAfterCancel(t *testing.T) {
+	sent := `{"amount": "1.20"}`
+	alive := mock.MatchedBy(func(ctx context.Context) bool { return ctx.Err() == nil })
+	tests := map[string]struct {
+		handler http.HandlerFunc
+		setup   func(*mockIdempotencyRepo)
+	}{
+		"client gone before save": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"id":"1"}`))
+			},
+			setup: func(m *mockIdempotencyRepo) {
+				m.On("Save", alive, mock.MatchedBy(func(k entity.IdempotencyKey) bool {
+					return k.Status == 200 && string(k.Response) == `{"id":"1"}`
+				})).Return(nil)
+			},
+		},
+		"save fails": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"id":"1"}`))
+			},
+			setup: func(m *mockIdempotencyRepo) {
+				m.On("Save", alive, mock.AnythingOfType("entity.IdempotencyKey")).Return(entity.ErrUnknown)
+				m.On("Delete", alive, "abc").Return(nil)
+			},
+		},
+		"server error": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+			setup: func(m *mockIdempotencyRepo) {
+				m.On("Delete", alive, "abc").Return(nil)
+			},
+		},
+		"handler panics": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				panic("boom")
+			},
+			setup: func(m *mockIdempotencyRepo) {
+				m.On("Delete", alive, "abc").Return(nil)
+			},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockedRepo := new(mockIdempotencyRepo)
+			mockedRepo.On("Reserve", mock.Anything, mock.AnythingOfType("entity.IdempotencyKey")).Return(nil)
+			tc.setup(mockedRepo)
+			ctx, cancel := context.WithCancel(context.Background())
+			handler := Idempotent(mockedRepo, time.Hour)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				cancel()
+				tc.handler(w, r)
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/api/expense", strings.NewReader(sent)).WithContext(ctx)
+			req.Header.Set(IdempotencyKeyHeader, "abc")
+			func() {
+				defer func() { recover() }()
+				handler.ServeHTTP(httptest.NewRecorder(), req)
+			}()
+			mockedRepo.AssertExpectations(t)
+		})
+	}
+}
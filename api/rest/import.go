@@ -0,0 +1,231 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/axpira/backend/api/importer"
+	"github.com/axpira/backend/entity"
+	"github.com/axpira/backend/entity/config"
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+type ImportRowRest struct {
+	Row    int    `json:"row"`
+	Status string `json:"status"`
+	Id     string `json:"id,omitempty"`
+	Error  *Error `json:"error,omitempty"`
+}
+
+type ImportReportRest struct {
+	Created int             `json:"created"`
+	Skipped int             `json:"skipped"`
+	Failed  int             `json:"failed"`
+	Rows    []ImportRowRest `json:"rows"`
+}
+
+func NewImportReportRestFromReport(report importer.Report) ImportReportRest {
+	res := ImportReportRest{
+		Created: report.Created,
+		Skipped: report.Skipped,
+		Failed:  report.Failed,
+		Rows:    make([]ImportRowRest, len(report.Rows)),
+	}
+	for i, row := range report.Rows {
+		res.Rows[i] = ImportRowRest{
+			Row:    row.Row,
+			Status: string(row.Status),
+			Id:     row.Id,
+		}
+		if row.Err != nil {
+			e := newResourceError("expense", row.Err).Detail
+			res.Rows[i].Error = &e
+		}
+	}
+	return res
+}
+
+func importRoutes(repo ExpenseRepository) func(r chi.Router) {
+	return func(r chi.Router) {
+		r.Post("/csv", importCSV(repo))
+		r.Post("/ofx", importOFX(repo))
+	}
+}
+
+// uploadedFile is the part named file of a multipart/form-data upload, or the whole body otherwise
+func uploadedFile(r *http.Request) (io.ReadCloser, error) {
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "multipart/form-data" {
+		return r.Body, nil
+	}
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+func importRecords(w http.ResponseWriter, r *http.Request, repo ExpenseRepository, records []importer.Record) {
+	ctx := r.Context()
+	report, err := importer.Import(ctx, repo, records)
+	if validateResourceError(w, "import", err) {
+		log.Ctx(ctx).Err(err).Msg("error on import")
+		return
+	}
+	err = json.NewEncoder(w).Encode(NewImportReportRestFromReport(report))
+	if validateResourceError(w, "import", err) {
+		return
+	}
+}
+
+func invalidUpload(w http.ResponseWriter, message string) {
+	fillHttpError(w,
+		NewHttpError(http.StatusBadRequest, "",
+			NewError("INVALID_REQUEST", message),
+		),
+	)
+}
+
+// parseCSVMapping reads the mapping from the query params, the columns count from 0 and
+// currency is config.Config.DefaultCurrency when it's omitted
+func parseCSVMapping(query url.Values) (importer.CSVMapping, error) {
+	var err error
+	mapping := importer.CSVMapping{
+		DateFormat:       query.Get("dateFormat"),
+		DecimalSeparator: query.Get("decimalSeparator"),
+		PaymentMethodId:  query.Get("paymentMethodId"),
+		Currency:         entity.Currency(config.Config.DefaultCurrency),
+	}
+	columns := []struct {
+		param  string
+		column *int
+	}{
+		{"dateColumn", &mapping.DateColumn},
+		{"amountColumn", &mapping.AmountColumn},
+		{"descriptionColumn", &mapping.DescriptionColumn},
+	}
+	for _, c := range columns {
+		param, column := c.param, c.column
+		value := query.Get(param)
+		if value == "" {
+			err = entity.NewFieldError(err, param, "no_empty", "can't be empty")
+			continue
+		}
+		i, convErr := strconv.Atoi(value)
+		if convErr != nil {
+			err = entity.NewFieldError(err, param, "invalid", fmt.Sprintf("%q is not a column number", value))
+			continue
+		}
+		*column = i
+	}
+	flags := []struct {
+		param string
+		flag  *bool
+	}{
+		{"header", &mapping.Header},
+		{"signed", &mapping.Signed},
+	}
+	for _, f := range flags {
+		param, flag := f.param, f.flag
+		if value := query.Get(param); value != "" {
+			b, convErr := strconv.ParseBool(value)
+			if convErr != nil {
+				err = entity.NewFieldError(err, param, "invalid", fmt.Sprintf("%q is not a boolean", value))
+				continue
+			}
+			*flag = b
+		}
+	}
+	if comma := query.Get("comma"); comma != "" {
+		r, size := utf8.DecodeRuneInString(comma)
+		if size != len(comma) {
+			err = entity.NewFieldError(err, "comma", "invalid", "must be a single character")
+		}
+		mapping.Comma = r
+	}
+	if currency := query.Get("currency"); currency != "" {
+		c, currencyErr := entity.ParseCurrency(currency)
+		err = entity.MergeFieldErrors(err, currencyErr)
+		mapping.Currency = c
+	}
+	if err != nil {
+		return importer.CSVMapping{}, err
+	}
+	return mapping, mapping.Validate()
+}
+
+// importCSV creates the expenses of the uploaded CSV, replying what happened to each row
+func importCSV(repo ExpenseRepository) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		mapping, err := parseCSVMapping(r.URL.Query())
+		if err != nil {
+			log.Ctx(ctx).Err(err).Msg("error on parse mapping")
+			fillHttpError(w,
+				NewHttpError(http.StatusBadRequest, "",
+					NewError("INVALID_REQUEST", "invalid mapping").WithFields(err),
+				),
+			)
+			return
+		}
+		file, err := uploadedFile(r)
+		if err != nil {
+			log.Ctx(ctx).Err(err).Msg("error on upload")
+			invalidUpload(w, "invalid upload")
+			return
+		}
+		defer file.Close()
+		records, err := importer.ParseCSV(file, mapping)
+		if err != nil {
+			log.Ctx(ctx).Err(err).Msg("error on read")
+			invalidUpload(w, "invalid csv")
+			return
+		}
+		importRecords(w, r, repo, records)
+	}
+}
+
+// importOFX creates the expenses of the uploaded OFX, the ones already imported are skipped.
+// currency is used when the statement has none, config.Config.DefaultCurrency when it's omitted
+func importOFX(repo ExpenseRepository) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		options := importer.OFXOptions{
+			Currency:        entity.Currency(config.Config.DefaultCurrency),
+			PaymentMethodId: r.URL.Query().Get("paymentMethodId"),
+		}
+		if currency := r.URL.Query().Get("currency"); currency != "" {
+			c, err := entity.ParseCurrency(currency)
+			if err != nil {
+				log.Ctx(ctx).Err(err).Msg("error on parse currency")
+				fillHttpError(w,
+					NewHttpError(http.StatusBadRequest, "",
+						NewError("INVALID_REQUEST", "invalid options").WithFields(err),
+					),
+				)
+				return
+			}
+			options.Currency = c
+		}
+		file, err := uploadedFile(r)
+		if err != nil {
+			log.Ctx(ctx).Err(err).Msg("error on upload")
+			invalidUpload(w, "invalid upload")
+			return
+		}
+		defer file.Close()
+		records, err := importer.ParseOFX(file, options)
+		if err != nil {
+			log.Ctx(ctx).Err(err).Msg("error on read")
+			invalidUpload(w, "invalid ofx")
+			return
+		}
+		importRecords(w, r, repo, records)
+	}
+}
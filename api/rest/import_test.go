@@ -0,0 +1,207 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/axpira/backend/entity"
+	"github.com/axpira/backend/entity/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestImportCSV(t *testing.T) {
+	defaultCurrency := config.Config.DefaultCurrency
+	config.Config.DefaultCurrency = "BRL"
+	defer func() { config.Config.DefaultCurrency = defaultCurrency }()
+
+	statement := []byte("Data;Histórico;Valor\n02/03/2021;PADARIA REAL;-1.234,56\n03/03/2021;SALARIO;5.000,00\n04/03/2021;MERCADO;abc\n")
+	tests := map[string]struct {
+		query      string
+		sent       []byte
+		setup      func(*mockExpenseRepo)
+		wantResult []byte
+		wantStatus int
+	}{
+		"import": {
+			query: "?comma=%3B&header=true&dateColumn=0&dateFormat=dd/mm/yyyy&descriptionColumn=1&amountColumn=2&decimalSeparator=,&signed=true&paymentMethodId=checking",
+			sent:  statement,
+			setup: func(m *mockExpenseRepo) {
+				m.On("Create", mock.Anything, entity.Expense{
+					Kind:            entity.EXPENSE,
+					Amount:          123456,
+					Currency:        "BRL",
+					When:            time.Date(2021, 3, 2, 0, 0, 0, 0, time.UTC),
+					What:            "PADARIA REAL",
+					PaymentMethodId: "checking",
+				}).Return("bakery", nil)
+				m.On("Create", mock.Anything, entity.Expense{
+					Kind:            entity.INCOME,
+					Amount:          500000,
+					Currency:        "BRL",
+					When:            time.Date(2021, 3, 3, 0, 0, 0, 0, time.UTC),
+					What:            "SALARIO",
+					PaymentMethodId: "checking",
+				}).Return("", entity.ErrConflict)
+			},
+			wantStatus: 200,
+			wantResult: []byte(`{
+				"created": 1,
+				"skipped": 1,
+				"failed": 1,
+				"rows": [
+					{"row": 2, "status": "created", "id": "bakery"},
+					{"row": 3, "status": "skipped"},
+					{"row": 4, "status": "failed", "error": {
+						"code": "INVALID_REQUEST",
+						"message": "invalid expense",
+						"fields": [{"field": "amount", "code": "invalid", "message": "\"abc\" is not a decimal number"}]
+					}}
+				]
+			}`),
+		},
+		"invalid mapping": {
+			query:      "?dateColumn=first&amountColumn=1&decimalSeparator=%3B",
+			sent:       statement,
+			wantStatus: 400,
+			wantResult: []byte(`{
+				"code": "INVALID_REQUEST",
+				"message": "invalid mapping",
+				"fields": [
+					{"field": "dateColumn", "code": "invalid", "message": "\"first\" is not a column number"},
+					{"field": "descriptionColumn", "code": "no_empty", "message": "can't be empty"}
+				]
+			}`),
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockedRepo := new(mockExpenseRepo)
+			if tc.setup != nil {
+				tc.setup(mockedRepo)
+			}
+			ts := httptest.NewServer(createHandler(context.Background(), mockedRepo))
+			defer ts.Close()
+
+			res, err := postJSON(ts.URL+"/api/import/csv"+tc.query, "text/csv", bytes.NewBuffer(tc.sent))
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tc.wantStatus, res.StatusCode)
+
+			got, err := io.ReadAll(res.Body)
+			res.Body.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			mockedRepo.AssertExpectations(t)
+			assert.JSONEq(t, string(tc.wantResult), string(got))
+		})
+	}
+}
+
+func TestImportOFX(t *testing.T) {
+	defaultCurrency := config.Config.DefaultCurrency
+	config.Config.DefaultCurrency = "BRL"
+	defer func() { config.Config.DefaultCurrency = defaultCurrency }()
+
+	statement := `OFXHEADER:100
+DATA:OFXSGML
+
+<OFX><BANKMSGSRSV1><STMTTRNRS><STMTRS>
+<BANKACCTFROM><BANKID>0341<ACCTID>12345-6</BANKACCTFROM>
+<BANKTRANLIST>
+<STMTTRN><TRNTYPE>DEBIT<DTPOSTED>20210302<TRNAMT>-12.50<FITID>1<NAME>PADARIA</STMTTRN>
+<STMTTRN><TRNTYPE>DEBIT<DTPOSTED>20210303<TRNAMT>-30.00<FITID>2<NAME>MERCADO</STMTTRN>
+</BANKTRANLIST>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1></OFX>
+`
+	upload := func(content string) (*bytes.Buffer, string) {
+		body := new(bytes.Buffer)
+		form := multipart.NewWriter(body)
+		part, err := form.CreateFormFile("file", "statement.ofx")
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write([]byte(content))
+		form.Close()
+		return body, form.FormDataContentType()
+	}
+	tests := map[string]struct {
+		content    string
+		setup      func(*mockExpenseRepo)
+		wantResult []byte
+		wantStatus int
+	}{
+		"import skipping the imported ones": {
+			content: statement,
+			setup: func(m *mockExpenseRepo) {
+				m.On("Create", mock.Anything, entity.Expense{
+					Kind:            entity.EXPENSE,
+					Amount:          1250,
+					Currency:        "BRL",
+					When:            time.Date(2021, 3, 2, 0, 0, 0, 0, time.UTC),
+					What:            "PADARIA",
+					PaymentMethodId: "checking",
+					ExternalId:      "ofx:0341:12345-6:1",
+				}).Return("", entity.ErrConflict)
+				m.On("Create", mock.Anything, entity.Expense{
+					Kind:            entity.EXPENSE,
+					Amount:          3000,
+					Currency:        "BRL",
+					When:            time.Date(2021, 3, 3, 0, 0, 0, 0, time.UTC),
+					What:            "MERCADO",
+					PaymentMethodId: "checking",
+					ExternalId:      "ofx:0341:12345-6:2",
+				}).Return("market", nil)
+			},
+			wantStatus: 200,
+			wantResult: []byte(`{
+				"created": 1,
+				"skipped": 1,
+				"failed": 0,
+				"rows": [
+					{"row": 1, "status": "skipped"},
+					{"row": 2, "status": "created", "id": "market"}
+				]
+			}`),
+		},
+		"not ofx": {
+			content:    "date,amount\n",
+			wantStatus: 400,
+			wantResult: []byte(`{"code": "INVALID_REQUEST", "message": "invalid ofx"}`),
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockedRepo := new(mockExpenseRepo)
+			if tc.setup != nil {
+				tc.setup(mockedRepo)
+			}
+			ts := httptest.NewServer(createHandler(context.Background(), mockedRepo))
+			defer ts.Close()
+
+			body, contentType := upload(tc.content)
+			res, err := postJSON(ts.URL+"/api/import/ofx?paymentMethodId=checking", contentType, body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tc.wantStatus, res.StatusCode)
+
+			got, err := io.ReadAll(res.Body)
+			res.Body.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			mockedRepo.AssertExpectations(t)
+			assert.JSONEq(t, string(tc.wantResult), string(got))
+		})
+	}
+}
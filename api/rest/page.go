@@ -0,0 +1,58 @@
+package rest
+
+import (
+	"net/url"
+	"strconv"
+
+	"github.com/axpira/backend/entity"
+)
+
+// PageRest has the cursors to send back as the cursor param, empty when there's no page after or before it
+type PageRest struct {
+	Items []ExpenseRest `json:"items"`
+	Next  string        `json:"next,omitempty"`
+	Prev  string        `json:"prev,omitempty"`
+	// Total is only sent when asked with count=true
+	Total *int64 `json:"total,omitempty"`
+}
+
+func NewPageRestFromPage(page entity.Page) PageRest {
+	res := PageRest{
+		Items: make([]ExpenseRest, len(page.Expenses)),
+		Next:  page.Next,
+		Prev:  page.Prev,
+		Total: page.Total,
+	}
+	for i, expense := range page.Expenses {
+		res.Items[i] = NewExpenseRestFromExpense(expense)
+	}
+	return res
+}
+
+// parsePageQuery reads limit, cursor, sort and count, the other params are the filter
+func parsePageQuery(query url.Values) (entity.PageRequest, *entity.ExpenseFilter, error) {
+	var err error
+	page := entity.PageRequest{Limit: entity.DefaultPageLimit, Cursor: query.Get("cursor")}
+	if limit := query.Get("limit"); limit != "" {
+		if value, parseErr := strconv.Atoi(limit); parseErr != nil {
+			err = entity.NewFieldError(err, "limit", "invalid", "must be a number")
+		} else {
+			page.Limit = value
+		}
+	}
+	if count := query.Get("count"); count != "" {
+		value, parseErr := strconv.ParseBool(count)
+		if parseErr != nil {
+			err = entity.NewFieldError(err, "count", "invalid", "must be true or false")
+		}
+		page.Count = value
+	}
+	page.Sort, _ = entity.ParseSort(query.Get("sort"))
+	filter, filterErr := parseExpenseFilter(withoutParams(query, "limit", "cursor", "sort", "count"))
+	err = entity.MergeFieldErrors(err, page.Validate())
+	err = entity.MergeFieldErrors(err, filterErr)
+	if err != nil {
+		return entity.PageRequest{}, nil, err
+	}
+	return page, filter, nil
+}
@@ -0,0 +1,151 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/axpira/backend/entity"
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+type PaymentMethodRepository interface {
+	Create(ctx context.Context, paymentMethod entity.PaymentMethod) (string, error)
+	Update(ctx context.Context, paymentMethod entity.PaymentMethod) error
+	Delete(ctx context.Context, id string) error
+	Get(ctx context.Context, id string) (entity.PaymentMethod, error)
+	List(ctx context.Context) ([]entity.PaymentMethod, error)
+}
+
+type PaymentMethodRest struct {
+	Id   string `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+	Type string `json:"type,omitempty"`
+}
+
+func (p PaymentMethodRest) ToPaymentMethod() entity.PaymentMethod {
+	return entity.PaymentMethod{
+		Id:   p.Id,
+		Name: p.Name,
+		Type: entity.PaymentMethodType(p.Type),
+	}
+}
+
+func NewPaymentMethodRestFromPaymentMethod(p entity.PaymentMethod) PaymentMethodRest {
+	return PaymentMethodRest{
+		Id:   p.Id,
+		Name: p.Name,
+		Type: string(p.Type),
+	}
+}
+
+func paymentMethodRoutes(repo PaymentMethodRepository) func(r chi.Router) {
+	return func(r chi.Router) {
+		r.Post("/", createPaymentMethod(repo))
+		r.Get("/", listPaymentMethod(repo))
+		r.Route("/{paymentMethodID}", func(r chi.Router) {
+			r.Get("/", getPaymentMethod(repo))
+			r.Put("/", updatePaymentMethod(repo))
+			r.Delete("/", deletePaymentMethod(repo))
+		})
+	}
+}
+
+func validatePaymentMethodError(w http.ResponseWriter, err error) bool {
+	return validateResourceError(w, "payment method", err)
+}
+
+func decodePaymentMethod(w http.ResponseWriter, r *http.Request) (entity.PaymentMethod, bool) {
+	paymentMethod := new(PaymentMethodRest)
+	err := json.NewDecoder(r.Body).Decode(paymentMethod)
+	if err != nil {
+		log.Ctx(r.Context()).Err(err).Msg("error on decode")
+		fillHttpError(w,
+			NewHttpError(http.StatusBadRequest, "",
+				NewError("INVALID_REQUEST", "invalid json"),
+			),
+		)
+		return entity.PaymentMethod{}, false
+	}
+	return paymentMethod.ToPaymentMethod(), true
+}
+
+func createPaymentMethod(repo PaymentMethodRepository) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		paymentMethod, ok := decodePaymentMethod(w, r)
+		if !ok {
+			return
+		}
+		id, err := repo.Create(ctx, paymentMethod)
+		if validatePaymentMethodError(w, err) {
+			log.Ctx(ctx).Err(err).Msg("error on create")
+			return
+		}
+		w.Write([]byte(`{"id":"` + id + `"}`))
+	}
+}
+
+func listPaymentMethod(repo PaymentMethodRepository) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		paymentMethods, err := repo.List(ctx)
+		if validatePaymentMethodError(w, err) {
+			log.Ctx(ctx).Err(err).Msg("error on list")
+			return
+		}
+		res := make([]PaymentMethodRest, len(paymentMethods))
+		for i, paymentMethod := range paymentMethods {
+			res[i] = NewPaymentMethodRestFromPaymentMethod(paymentMethod)
+		}
+		err = json.NewEncoder(w).Encode(res)
+		if validatePaymentMethodError(w, err) {
+			return
+		}
+	}
+}
+
+func getPaymentMethod(repo PaymentMethodRepository) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		paymentMethod, err := repo.Get(ctx, chi.URLParam(r, "paymentMethodID"))
+		if validatePaymentMethodError(w, err) {
+			log.Ctx(ctx).Err(err).Msg("error on consult")
+			return
+		}
+		err = json.NewEncoder(w).Encode(NewPaymentMethodRestFromPaymentMethod(paymentMethod))
+		if validatePaymentMethodError(w, err) {
+			return
+		}
+	}
+}
+
+func updatePaymentMethod(repo PaymentMethodRepository) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		paymentMethod, ok := decodePaymentMethod(w, r)
+		if !ok {
+			return
+		}
+		paymentMethod.Id = chi.URLParam(r, "paymentMethodID")
+		err := repo.Update(ctx, paymentMethod)
+		if validatePaymentMethodError(w, err) {
+			log.Ctx(ctx).Err(err).Msg("error on update")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func deletePaymentMethod(repo PaymentMethodRepository) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		err := repo.Delete(ctx, chi.URLParam(r, "paymentMethodID"))
+		if validatePaymentMethodError(w, err) {
+			log.Ctx(ctx).Err(err).Msg("error on delete")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,129 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/axpira/backend/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockPaymentMethodRepo struct {
+	mock.Mock
+}
+
+func (m *mockPaymentMethodRepo) Create(ctx context.Context, paymentMethod entity.PaymentMethod) (string, error) {
+	args := m.Called(ctx, paymentMethod)
+	return args.String(0), args.Error(1)
+}
+func (m *mockPaymentMethodRepo) Update(ctx context.Context, paymentMethod entity.PaymentMethod) error {
+	args := m.Called(ctx, paymentMethod)
+	return args.Error(0)
+}
+func (m *mockPaymentMethodRepo) Delete(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+func (m *mockPaymentMethodRepo) Get(ctx context.Context, id string) (entity.PaymentMethod, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(entity.PaymentMethod), args.Error(1)
+}
+func (m *mockPaymentMethodRepo) List(ctx context.Context) ([]entity.PaymentMethod, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]entity.PaymentMethod), args.Error(1)
+}
+
+func TestPaymentMethod(t *testing.T) {
+	tests := map[string]struct {
+		method     string
+		path       string
+		sent       []byte
+		setup      func(*mockPaymentMethodRepo)
+		wantResult []byte
+		wantStatus int
+	}{
+		"create": {
+			method: http.MethodPost,
+			path:   "/api/payment-method",
+			sent:   []byte(`{"name": "Visa", "type": "credit_card"}`),
+			setup: func(m *mockPaymentMethodRepo) {
+				m.On("Create", mock.Anything, entity.PaymentMethod{Name: "Visa", Type: entity.CREDIT_CARD}).Return("card", nil)
+			},
+			wantStatus: 200,
+			wantResult: []byte(`{"id": "card"}`),
+		},
+		"list": {
+			method: http.MethodGet,
+			path:   "/api/payment-method",
+			setup: func(m *mockPaymentMethodRepo) {
+				m.On("List", mock.Anything).Return([]entity.PaymentMethod{
+					{Id: "pix", Name: "Nubank", Type: entity.PIX},
+				}, nil)
+			},
+			wantStatus: 200,
+			wantResult: []byte(`[{"id": "pix", "name": "Nubank", "type": "pix"}]`),
+		},
+		"get": {
+			method: http.MethodGet,
+			path:   "/api/payment-method/card",
+			setup: func(m *mockPaymentMethodRepo) {
+				m.On("Get", mock.Anything, "card").Return(entity.PaymentMethod{Id: "card", Name: "Visa", Type: entity.CREDIT_CARD}, nil)
+			},
+			wantStatus: 200,
+			wantResult: []byte(`{"id": "card", "name": "Visa", "type": "credit_card"}`),
+		},
+		"update not found": {
+			method: http.MethodPut,
+			path:   "/api/payment-method/unknown",
+			sent:   []byte(`{"name": "Cash", "type": "cash"}`),
+			setup: func(m *mockPaymentMethodRepo) {
+				m.On("Update", mock.Anything, entity.PaymentMethod{Id: "unknown", Name: "Cash", Type: entity.CASH}).Return(entity.ErrNotFound)
+			},
+			wantStatus: 404,
+			wantResult: []byte(`{"code": "NOT_FOUND", "message": "payment method not found"}`),
+		},
+		"delete in use": {
+			method: http.MethodDelete,
+			path:   "/api/payment-method/card",
+			setup: func(m *mockPaymentMethodRepo) {
+				m.On("Delete", mock.Anything, "card").Return(fmt.Errorf("payment method card has expenses: %w", entity.ErrConflict))
+			},
+			wantStatus: 409,
+			wantResult: []byte(`{"code": "CONFLICT", "message": "payment method is in use or already exists"}`),
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockedRepo := new(mockPaymentMethodRepo)
+			tc.setup(mockedRepo)
+			ts := httptest.NewServer(createHandler(context.Background(), new(mockExpenseRepo), WithPaymentMethodRepository(mockedRepo)))
+			defer ts.Close()
+
+			req, err := http.NewRequest(tc.method, ts.URL+tc.path, bytes.NewBuffer(tc.sent))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Accept", "application/json")
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tc.wantStatus, res.StatusCode)
+
+			got, err := io.ReadAll(res.Body)
+			res.Body.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			mockedRepo.AssertExpectations(t)
+			assert.JSONEq(t, string(tc.wantResult), string(got))
+		})
+	}
+}
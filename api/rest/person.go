@@ -0,0 +1,155 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/axpira/backend/entity"
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+type PersonRepository interface {
+	Create(ctx context.Context, person entity.Person) (string, error)
+	Update(ctx context.Context, person entity.Person) error
+	Delete(ctx context.Context, id string) error
+	Get(ctx context.Context, id string) (entity.Person, error)
+	List(ctx context.Context) ([]entity.Person, error)
+	Resolve(ctx context.Context, create bool) (int64, error)
+}
+
+type PersonRest struct {
+	Id      string   `json:"id,omitempty"`
+	Urn     string   `json:"urn,omitempty"`
+	Name    string   `json:"name,omitempty"`
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+func (p PersonRest) ToPerson() entity.Person {
+	return entity.Person{
+		Id:      p.Id,
+		Name:    p.Name,
+		Aliases: p.Aliases,
+	}
+}
+
+func NewPersonRestFromPerson(p entity.Person) PersonRest {
+	return PersonRest{
+		Id:      p.Id,
+		Urn:     string(p.URN()),
+		Name:    p.Name,
+		Aliases: p.Aliases,
+	}
+}
+
+func personRoutes(repo PersonRepository) func(r chi.Router) {
+	return func(r chi.Router) {
+		r.Post("/", createPerson(repo))
+		r.Get("/", listPerson(repo))
+		r.Post("/resolve", resolveReferences(repo.Resolve, "person"))
+		r.Route("/{personID}", func(r chi.Router) {
+			r.Get("/", getPerson(repo))
+			r.Put("/", updatePerson(repo))
+			r.Delete("/", deletePerson(repo))
+		})
+	}
+}
+
+func validatePersonError(w http.ResponseWriter, err error) bool {
+	return validateResourceError(w, "person", err)
+}
+
+func decodePerson(w http.ResponseWriter, r *http.Request) (entity.Person, bool) {
+	person := new(PersonRest)
+	err := json.NewDecoder(r.Body).Decode(person)
+	if err != nil {
+		log.Ctx(r.Context()).Err(err).Msg("error on decode")
+		fillHttpError(w,
+			NewHttpError(http.StatusBadRequest, "",
+				NewError("INVALID_REQUEST", "invalid json"),
+			),
+		)
+		return entity.Person{}, false
+	}
+	return person.ToPerson(), true
+}
+
+func createPerson(repo PersonRepository) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		person, ok := decodePerson(w, r)
+		if !ok {
+			return
+		}
+		id, err := repo.Create(ctx, person)
+		if validatePersonError(w, err) {
+			log.Ctx(ctx).Err(err).Msg("error on create")
+			return
+		}
+		w.Write([]byte(`{"id":"` + id + `"}`))
+	}
+}
+
+func listPerson(repo PersonRepository) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		persons, err := repo.List(ctx)
+		if validatePersonError(w, err) {
+			log.Ctx(ctx).Err(err).Msg("error on list")
+			return
+		}
+		res := make([]PersonRest, len(persons))
+		for i, person := range persons {
+			res[i] = NewPersonRestFromPerson(person)
+		}
+		err = json.NewEncoder(w).Encode(res)
+		if validatePersonError(w, err) {
+			return
+		}
+	}
+}
+
+func getPerson(repo PersonRepository) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		person, err := repo.Get(ctx, chi.URLParam(r, "personID"))
+		if validatePersonError(w, err) {
+			log.Ctx(ctx).Err(err).Msg("error on consult")
+			return
+		}
+		err = json.NewEncoder(w).Encode(NewPersonRestFromPerson(person))
+		if validatePersonError(w, err) {
+			return
+		}
+	}
+}
+
+func updatePerson(repo PersonRepository) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		person, ok := decodePerson(w, r)
+		if !ok {
+			return
+		}
+		person.Id = chi.URLParam(r, "personID")
+		err := repo.Update(ctx, person)
+		if validatePersonError(w, err) {
+			log.Ctx(ctx).Err(err).Msg("error on update")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func deletePerson(repo PersonRepository) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		err := repo.Delete(ctx, chi.URLParam(r, "personID"))
+		if validatePersonError(w, err) {
+			log.Ctx(ctx).Err(err).Msg("error on delete")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,205 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/axpira/backend/entity"
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+type PlaceRepository interface {
+	Create(ctx context.Context, place entity.Place) (string, error)
+	Update(ctx context.Context, place entity.Place) error
+	Delete(ctx context.Context, id string) error
+	Get(ctx context.Context, id string) (entity.Place, error)
+	List(ctx context.Context) ([]entity.Place, error)
+	Resolve(ctx context.Context, create bool) (int64, error)
+}
+
+type LocationRest struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+type PlaceRest struct {
+	Id       string        `json:"id,omitempty"`
+	Urn      string        `json:"urn,omitempty"`
+	Name     string        `json:"name,omitempty"`
+	Aliases  []string      `json:"aliases,omitempty"`
+	Location *LocationRest `json:"location,omitempty"`
+}
+
+func (p PlaceRest) ToPlace() entity.Place {
+	place := entity.Place{
+		Id:      p.Id,
+		Name:    p.Name,
+		Aliases: p.Aliases,
+	}
+	if p.Location != nil {
+		place.Location = &entity.GeoPoint{Latitude: p.Location.Latitude, Longitude: p.Location.Longitude}
+	}
+	return place
+}
+
+func NewPlaceRestFromPlace(p entity.Place) PlaceRest {
+	res := PlaceRest{
+		Id:      p.Id,
+		Urn:     string(p.URN()),
+		Name:    p.Name,
+		Aliases: p.Aliases,
+	}
+	if p.Location != nil {
+		res.Location = &LocationRest{Latitude: p.Location.Latitude, Longitude: p.Location.Longitude}
+	}
+	return res
+}
+
+func placeRoutes(repo PlaceRepository) func(r chi.Router) {
+	return func(r chi.Router) {
+		r.Post("/", createPlace(repo))
+		r.Get("/", listPlace(repo))
+		r.Post("/resolve", resolveReferences(repo.Resolve, "place"))
+		r.Route("/{placeID}", func(r chi.Router) {
+			r.Get("/", getPlace(repo))
+			r.Put("/", updatePlace(repo))
+			r.Delete("/", deletePlace(repo))
+		})
+	}
+}
+
+func validatePlaceError(w http.ResponseWriter, err error) bool {
+	return validateResourceError(w, "place", err)
+}
+
+func decodePlace(w http.ResponseWriter, r *http.Request) (entity.Place, bool) {
+	place := new(PlaceRest)
+	err := json.NewDecoder(r.Body).Decode(place)
+	if err != nil {
+		log.Ctx(r.Context()).Err(err).Msg("error on decode")
+		fillHttpError(w,
+			NewHttpError(http.StatusBadRequest, "",
+				NewError("INVALID_REQUEST", "invalid json"),
+			),
+		)
+		return entity.Place{}, false
+	}
+	return place.ToPlace(), true
+}
+
+func createPlace(repo PlaceRepository) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		place, ok := decodePlace(w, r)
+		if !ok {
+			return
+		}
+		id, err := repo.Create(ctx, place)
+		if validatePlaceError(w, err) {
+			log.Ctx(ctx).Err(err).Msg("error on create")
+			return
+		}
+		w.Write([]byte(`{"id":"` + id + `"}`))
+	}
+}
+
+func listPlace(repo PlaceRepository) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		places, err := repo.List(ctx)
+		if validatePlaceError(w, err) {
+			log.Ctx(ctx).Err(err).Msg("error on list")
+			return
+		}
+		res := make([]PlaceRest, len(places))
+		for i, place := range places {
+			res[i] = NewPlaceRestFromPlace(place)
+		}
+		err = json.NewEncoder(w).Encode(res)
+		if validatePlaceError(w, err) {
+			return
+		}
+	}
+}
+
+func getPlace(repo PlaceRepository) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		place, err := repo.Get(ctx, chi.URLParam(r, "placeID"))
+		if validatePlaceError(w, err) {
+			log.Ctx(ctx).Err(err).Msg("error on consult")
+			return
+		}
+		err = json.NewEncoder(w).Encode(NewPlaceRestFromPlace(place))
+		if validatePlaceError(w, err) {
+			return
+		}
+	}
+}
+
+func updatePlace(repo PlaceRepository) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		place, ok := decodePlace(w, r)
+		if !ok {
+			return
+		}
+		place.Id = chi.URLParam(r, "placeID")
+		err := repo.Update(ctx, place)
+		if validatePlaceError(w, err) {
+			log.Ctx(ctx).Err(err).Msg("error on update")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func deletePlace(repo PlaceRepository) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		err := repo.Delete(ctx, chi.URLParam(r, "placeID"))
+		if validatePlaceError(w, err) {
+			log.Ctx(ctx).Err(err).Msg("error on delete")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// resolveReferences links the expenses to the entities their free text names, creating
+// the missing ones with create=true, and answers how many expenses were linked
+func resolveReferences(resolve func(context.Context, bool) (int64, error), resource string) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		create := false
+		if v := r.URL.Query().Get("create"); v != "" {
+			var err error
+			create, err = strconv.ParseBool(v)
+			if err != nil {
+				fillHttpError(w,
+					NewHttpError(http.StatusBadRequest, "",
+						NewError("INVALID_REQUEST", fmt.Sprintf("invalid %s", resource)).WithFields(
+							entity.NewFieldError(nil, "create", "invalid", "must be a boolean"),
+						),
+					),
+				)
+				return
+			}
+		}
+		resolved, err := resolve(ctx, create)
+		if validateResourceError(w, resource, err) {
+			log.Ctx(ctx).Err(err).Msg("error on resolve")
+			return
+		}
+		err = json.NewEncoder(w).Encode(struct {
+			Resolved int64 `json:"resolved"`
+		}{resolved})
+		if validateResourceError(w, resource, err) {
+			return
+		}
+	}
+}
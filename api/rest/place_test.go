@@ -0,0 +1,246 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/axpira/backend/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockPlaceRepo struct {
+	mock.Mock
+}
+
+func (m *mockPlaceRepo) Create(ctx context.Context, place entity.Place) (string, error) {
+	args := m.Called(ctx, place)
+	return args.String(0), args.Error(1)
+}
+func (m *mockPlaceRepo) Update(ctx context.Context, place entity.Place) error {
+	args := m.Called(ctx, place)
+	return args.Error(0)
+}
+func (m *mockPlaceRepo) Delete(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+func (m *mockPlaceRepo) Get(ctx context.Context, id string) (entity.Place, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(entity.Place), args.Error(1)
+}
+func (m *mockPlaceRepo) List(ctx context.Context) ([]entity.Place, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]entity.Place), args.Error(1)
+}
+func (m *mockPlaceRepo) Resolve(ctx context.Context, create bool) (int64, error) {
+	args := m.Called(ctx, create)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+type mockPersonRepo struct {
+	mock.Mock
+}
+
+func (m *mockPersonRepo) Create(ctx context.Context, person entity.Person) (string, error) {
+	args := m.Called(ctx, person)
+	return args.String(0), args.Error(1)
+}
+func (m *mockPersonRepo) Update(ctx context.Context, person entity.Person) error {
+	args := m.Called(ctx, person)
+	return args.Error(0)
+}
+func (m *mockPersonRepo) Delete(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+func (m *mockPersonRepo) Get(ctx context.Context, id string) (entity.Person, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(entity.Person), args.Error(1)
+}
+func (m *mockPersonRepo) List(ctx context.Context) ([]entity.Person, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]entity.Person), args.Error(1)
+}
+func (m *mockPersonRepo) Resolve(ctx context.Context, create bool) (int64, error) {
+	args := m.Called(ctx, create)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func TestPlaceAndPerson(t *testing.T) {
+	tests := map[string]struct {
+		method     string
+		path       string
+		sent       []byte
+		setup      func(*mockPlaceRepo, *mockPersonRepo, *mockExpenseRepo)
+		wantResult []byte
+		wantStatus int
+	}{
+		"create place": {
+			method: http.MethodPost,
+			path:   "/api/place",
+			sent:   []byte(`{"name": "Starbucks", "aliases": ["STARBUCKS #12"], "location": {"latitude": -23.56, "longitude": -46.65}}`),
+			setup: func(m *mockPlaceRepo, _ *mockPersonRepo, _ *mockExpenseRepo) {
+				m.On("Create", mock.Anything, entity.Place{
+					Name:     "Starbucks",
+					Aliases:  []string{"STARBUCKS #12"},
+					Location: &entity.GeoPoint{Latitude: -23.56, Longitude: -46.65},
+				}).Return("starbucks", nil)
+			},
+			wantStatus: 200,
+			wantResult: []byte(`{"id": "starbucks"}`),
+		},
+		"get place": {
+			method: http.MethodGet,
+			path:   "/api/place/starbucks",
+			setup: func(m *mockPlaceRepo, _ *mockPersonRepo, _ *mockExpenseRepo) {
+				m.On("Get", mock.Anything, "starbucks").Return(entity.Place{Id: "starbucks", Name: "Starbucks", Aliases: []string{"STARBUCKS #12"}}, nil)
+			},
+			wantStatus: 200,
+			wantResult: []byte(`{"id": "starbucks", "urn": "urn:place:starbucks", "name": "Starbucks", "aliases": ["STARBUCKS #12"]}`),
+		},
+		"delete place in use": {
+			method: http.MethodDelete,
+			path:   "/api/place/starbucks",
+			setup: func(m *mockPlaceRepo, _ *mockPersonRepo, _ *mockExpenseRepo) {
+				m.On("Delete", mock.Anything, "starbucks").Return(fmt.Errorf("place starbucks has expenses: %w", entity.ErrConflict))
+			},
+			wantStatus: 409,
+			wantResult: []byte(`{"code": "CONFLICT", "message": "place is in use or already exists"}`),
+		},
+		"resolve places creating the missing ones": {
+			method: http.MethodPost,
+			path:   "/api/place/resolve?create=true",
+			setup: func(m *mockPlaceRepo, _ *mockPersonRepo, _ *mockExpenseRepo) {
+				m.On("Resolve", mock.Anything, true).Return(int64(12), nil)
+			},
+			wantStatus: 200,
+			wantResult: []byte(`{"resolved": 12}`),
+		},
+		"resolve with invalid create": {
+			method:     http.MethodPost,
+			path:       "/api/place/resolve?create=maybe",
+			wantStatus: 400,
+			wantResult: []byte(`{
+				"code": "INVALID_REQUEST",
+				"message": "invalid place",
+				"fields": [{"field": "create", "code": "invalid", "message": "must be a boolean"}]
+			}`),
+		},
+		"list persons": {
+			method: http.MethodGet,
+			path:   "/api/person",
+			setup: func(_ *mockPlaceRepo, m *mockPersonRepo, _ *mockExpenseRepo) {
+				m.On("List", mock.Anything).Return([]entity.Person{{Id: "ana", Name: "Ana", Aliases: []string{"aninha"}}}, nil)
+			},
+			wantStatus: 200,
+			wantResult: []byte(`[{"id": "ana", "urn": "urn:person:ana", "name": "Ana", "aliases": ["aninha"]}]`),
+		},
+		"resolve persons": {
+			method: http.MethodPost,
+			path:   "/api/person/resolve",
+			setup: func(_ *mockPlaceRepo, m *mockPersonRepo, _ *mockExpenseRepo) {
+				m.On("Resolve", mock.Anything, false).Return(int64(3), nil)
+			},
+			wantStatus: 200,
+			wantResult: []byte(`{"resolved": 3}`),
+		},
+		"create expense referencing a place and a person": {
+			method: http.MethodPost,
+			path:   "/api/expense",
+			sent:   []byte(`{"amount": "12.00", "where": "starbucks ", "whereUrn": "urn:place:starbucks", "whoUrn": "urn:person:ana"}`),
+			setup: func(_ *mockPlaceRepo, _ *mockPersonRepo, m *mockExpenseRepo) {
+				m.On("Create", mock.Anything, entity.Expense{
+					Kind:     entity.EXPENSE,
+					Amount:   1200,
+					Where:    "starbucks ",
+					PlaceId:  "starbucks",
+					PersonId: "ana",
+				}).Return("1", nil)
+			},
+			wantStatus: 200,
+			wantResult: []byte(`{"id": "1"}`),
+		},
+		"create expense with an urn of other kind": {
+			method:     http.MethodPost,
+			path:       "/api/expense",
+			sent:       []byte(`{"amount": "12.00", "whereUrn": "urn:person:ana"}`),
+			wantStatus: 400,
+			wantResult: []byte(`{
+				"code": "INVALID_REQUEST",
+				"message": "invalid expense",
+				"fields": [{"field": "whereUrn", "code": "invalid", "message": "\"urn:person:ana\" is not like urn:place:<id>"}]
+			}`),
+		},
+		"get expense with references": {
+			method: http.MethodGet,
+			path:   "/api/expense/1",
+			setup: func(_ *mockPlaceRepo, _ *mockPersonRepo, m *mockExpenseRepo) {
+				m.On("Get", mock.Anything, "1").Return(entity.Expense{Id: "1", Amount: 1200, PlaceId: "starbucks", PersonId: "ana"}, nil)
+			},
+			wantStatus: 200,
+			wantResult: []byte(`{"id": "1", "amount": "12.00", "whereUrn": "urn:place:starbucks", "whoUrn": "urn:person:ana"}`),
+		},
+		"search expenses by place": {
+			method: http.MethodGet,
+			path:   "/api/expense?where=urn:place:starbucks",
+			setup: func(_ *mockPlaceRepo, _ *mockPersonRepo, m *mockExpenseRepo) {
+				m.On("SearchPage", mock.Anything, entity.MustNewExpenseFilter(
+					entity.FilterWhere("urn:place:starbucks"),
+					entity.FilterKind(entity.EXPENSE),
+				), defaultPage).Return(entity.Page{}, nil)
+			},
+			wantStatus: 200,
+			wantResult: []byte(`{"items": []}`),
+		},
+		"search expenses by an invalid person": {
+			method:     http.MethodGet,
+			path:       "/api/expense?who=ana",
+			wantStatus: 400,
+			wantResult: []byte(`{
+				"code": "INVALID_FILTER",
+				"message": "invalid filter",
+				"fields": [{"field": "who", "code": "invalid_value", "message": "invalid urn \"ana\", use urn:person:<id>"}]
+			}`),
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			placeRepo := new(mockPlaceRepo)
+			personRepo := new(mockPersonRepo)
+			expenseRepo := new(mockExpenseRepo)
+			if tc.setup != nil {
+				tc.setup(placeRepo, personRepo, expenseRepo)
+			}
+			ts := httptest.NewServer(createHandler(context.Background(), expenseRepo, WithPlaceRepository(placeRepo), WithPersonRepository(personRepo)))
+			defer ts.Close()
+
+			req, err := http.NewRequest(tc.method, ts.URL+tc.path, bytes.NewBuffer(tc.sent))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Accept", "application/json")
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tc.wantStatus, res.StatusCode)
+
+			got, err := io.ReadAll(res.Body)
+			res.Body.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			placeRepo.AssertExpectations(t)
+			personRepo.AssertExpectations(t)
+			expenseRepo.AssertExpectations(t)
+			assert.JSONEq(t, string(tc.wantResult), string(got))
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package rest
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+)
+
+const problemContentType = "application/problem+json"
+
+// ProblemRest is an error as RFC 7807 describes it, Code, TraceId and Errors are extensions
+type ProblemRest struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// Code is the one of the application/json errors
+	Code    string           `json:"code,omitempty"`
+	TraceId string           `json:"traceId,omitempty"`
+	Errors  []FieldErrorRest `json:"errors,omitempty"`
+}
+
+// problemWriter writes the errors of the request it answers as problems
+type problemWriter struct {
+	http.ResponseWriter
+	instance string
+	traceID  string
+}
+
+// asProblemWriter finds the problemWriter under the writers wrapping w
+func asProblemWriter(w http.ResponseWriter) (problemWriter, bool) {
+	for {
+		switch v := w.(type) {
+		case problemWriter:
+			return v, true
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = v.Unwrap()
+		default:
+			return problemWriter{}, false
+		}
+	}
+}
+
+// problemType names the kind of the error, like urn:problem:invalid-request for INVALID_REQUEST
+func problemType(code string) string {
+	if code == "" {
+		return "about:blank"
+	}
+	return "urn:problem:" + strings.ReplaceAll(strings.ToLower(code), "_", "-")
+}
+
+func (p problemWriter) problem(err HttpError) ProblemRest {
+	return ProblemRest{
+		Type:     problemType(err.Detail.Code),
+		Title:    http.StatusText(err.StatusCode),
+		Status:   err.StatusCode,
+		Detail:   err.Detail.Message,
+		Instance: p.instance,
+		Code:     err.Detail.Code,
+		TraceId:  p.traceID,
+		Errors:   err.Detail.Fields,
+	}
+}
+
+// acceptsProblem is false only for the clients that accept application/json and not problems,
+// the ones written before the errors were problems
+func acceptsProblem(accept string) bool {
+	legacy := false
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType, params, _ := mime.ParseMediaType(mediaRange)
+		if params["q"] == "0" {
+			continue
+		}
+		switch mediaType {
+		case problemContentType:
+			return true
+		case "application/json":
+			legacy = true
+		}
+	}
+	return !legacy
+}
+
+// ProblemDetails makes the handlers write their errors as application/problem+json,
+// unless the client asked for the application/json errors with the Accept header
+func ProblemDetails(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		if acceptsProblem(r.Header.Get("Accept")) {
+			w = problemWriter{ResponseWriter: w, instance: r.URL.Path, traceID: GetTraceID(r.Context())}
+		}
+		next.ServeHTTP(w, r)
+	}
+	return http.HandlerFunc(fn)
+}
@@ -0,0 +1,154 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/axpira/backend/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestProblemDetails(t *testing.T) {
+	tests := map[string]struct {
+		method          string
+		path            string
+		accept          string
+		sent            []byte
+		setup           func(*mockExpenseRepo)
+		wantStatus      int
+		wantContentType string
+		wantResult      []byte
+	}{
+		"not found without accept": {
+			method: http.MethodGet,
+			path:   "/api/expense/1",
+			setup: func(m *mockExpenseRepo) {
+				m.On("Get", mock.Anything, "1").Return(entity.Expense{}, entity.ErrNotFound)
+			},
+			wantStatus:      404,
+			wantContentType: "application/problem+json",
+			wantResult: []byte(`{
+				"type": "urn:problem:not-found",
+				"title": "Not Found",
+				"status": 404,
+				"detail": "expense not found",
+				"instance": "/api/expense/1",
+				"code": "NOT_FOUND",
+				"traceId": "trace"
+			}`),
+		},
+		"field errors accepting problems": {
+			method:          http.MethodPost,
+			path:            "/api/expense",
+			accept:          "application/problem+json, application/json",
+			sent:            []byte(`{"amount": "1.234", "currency": "BRL"}`),
+			wantStatus:      400,
+			wantContentType: "application/problem+json",
+			wantResult: []byte(`{
+				"type": "urn:problem:invalid-request",
+				"title": "Bad Request",
+				"status": 400,
+				"detail": "invalid expense",
+				"instance": "/api/expense",
+				"code": "INVALID_REQUEST",
+				"traceId": "trace",
+				"errors": [{"field": "amount", "code": "invalid_precision", "message": "BRL has 2 decimal places"}]
+			}`),
+		},
+		"technical error accepting anything": {
+			method: http.MethodDelete,
+			path:   "/api/expense/1",
+			accept: "*/*",
+			setup: func(m *mockExpenseRepo) {
+				m.On("Get", mock.Anything, "1").Return(entity.Expense{}, errors.New("connection refused"))
+			},
+			wantStatus:      500,
+			wantContentType: "application/problem+json",
+			wantResult: []byte(`{
+				"type": "urn:problem:internal-error",
+				"title": "Internal Server Error",
+				"status": 500,
+				"detail": "internal error",
+				"instance": "/api/expense/1",
+				"code": "INTERNAL_ERROR",
+				"traceId": "trace"
+			}`),
+		},
+		"unknown url": {
+			method:          http.MethodGet,
+			path:            "/api/nothing",
+			wantStatus:      404,
+			wantContentType: "application/problem+json",
+			wantResult: []byte(`{
+				"type": "urn:problem:url-not-found",
+				"title": "Not Found",
+				"status": 404,
+				"detail": "URL '/api/nothing' not found",
+				"instance": "/api/nothing",
+				"code": "URL_NOT_FOUND",
+				"traceId": "trace"
+			}`),
+		},
+		"old clients accepting json": {
+			method: http.MethodGet,
+			path:   "/api/expense/1",
+			accept: "application/json, */*;q=0.5",
+			setup: func(m *mockExpenseRepo) {
+				m.On("Get", mock.Anything, "1").Return(entity.Expense{}, entity.ErrNotFound)
+			},
+			wantStatus:      404,
+			wantContentType: "application/json",
+			wantResult:      []byte(`{"code": "NOT_FOUND", "message": "expense not found"}`),
+		},
+		"old clients refusing problems": {
+			method: http.MethodGet,
+			path:   "/api/expense/1",
+			accept: "application/json, application/problem+json;q=0",
+			setup: func(m *mockExpenseRepo) {
+				m.On("Get", mock.Anything, "1").Return(entity.Expense{}, entity.ErrNotFound)
+			},
+			wantStatus:      404,
+			wantContentType: "application/json",
+			wantResult:      []byte(`{"code": "NOT_FOUND", "message": "expense not found"}`),
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockedRepo := new(mockExpenseRepo)
+			if tc.setup != nil {
+				tc.setup(mockedRepo)
+			}
+			ts := httptest.NewServer(createHandler(context.Background(), mockedRepo))
+			defer ts.Close()
+
+			req, err := http.NewRequest(tc.method, ts.URL+tc.path, bytes.NewBuffer(tc.sent))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set(TraceIDHeader, "trace")
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tc.wantStatus, res.StatusCode)
+			assert.Equal(t, tc.wantContentType, res.Header.Get("Content-Type"))
+
+			got, err := io.ReadAll(res.Body)
+			res.Body.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			mockedRepo.AssertExpectations(t)
+			assert.JSONEq(t, string(tc.wantResult), string(got))
+		})
+	}
+}
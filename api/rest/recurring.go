@@ -0,0 +1,211 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/axpira/backend/entity"
+	"github.com/axpira/backend/entity/config"
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+type RecurringExpenseRepository interface {
+	Create(ctx context.Context, recurring entity.RecurringExpense) (string, error)
+	Update(ctx context.Context, recurring entity.RecurringExpense) error
+	Delete(ctx context.Context, id string) error
+	Get(ctx context.Context, id string) (entity.RecurringExpense, error)
+	List(ctx context.Context) ([]entity.RecurringExpense, error)
+}
+
+type ScheduleRest struct {
+	Frequency  string     `json:"frequency"`
+	Interval   int        `json:"interval,omitempty"`
+	DayOfMonth int        `json:"dayOfMonth,omitempty"`
+	Start      *time.Time `json:"start,omitempty"`
+	Until      *time.Time `json:"until,omitempty"`
+	Count      int        `json:"count,omitempty"`
+}
+
+type RecurringExpenseRest struct {
+	Id string `json:"id,omitempty"`
+	// Kind is expense when empty
+	Kind           string       `json:"kind,omitempty"`
+	Template       ExpenseRest  `json:"template"`
+	Schedule       ScheduleRest `json:"schedule"`
+	NextOccurrence int          `json:"nextOccurrence"`
+}
+
+func (r RecurringExpenseRest) ToRecurringExpense() (entity.RecurringExpense, error) {
+	if r.Template.Currency == "" {
+		r.Template.Currency = config.Config.DefaultCurrency
+	}
+	template, err := r.Template.ToExpense()
+	if err != nil {
+		return entity.RecurringExpense{}, err
+	}
+	template.Kind = entity.TransactionKind(r.Kind)
+	if template.Kind == "" {
+		template.Kind = entity.EXPENSE
+	}
+	if err := template.AllocateSplit(); err != nil {
+		return entity.RecurringExpense{}, err
+	}
+	schedule := entity.Schedule{
+		Frequency:  entity.Frequency(r.Schedule.Frequency),
+		Interval:   r.Schedule.Interval,
+		DayOfMonth: r.Schedule.DayOfMonth,
+		Count:      r.Schedule.Count,
+	}
+	if r.Schedule.Start != nil {
+		schedule.Start = r.Schedule.Start.UTC()
+	}
+	if r.Schedule.Until != nil {
+		schedule.Until = r.Schedule.Until.UTC()
+	}
+	return entity.RecurringExpense{
+		Id:       r.Id,
+		Template: template,
+		Schedule: schedule,
+	}, nil
+}
+
+func NewRecurringExpenseRestFromRecurringExpense(r entity.RecurringExpense) RecurringExpenseRest {
+	return RecurringExpenseRest{
+		Id:       r.Id,
+		Kind:     string(r.Template.KindOrDefault()),
+		Template: NewExpenseRestFromExpense(r.Template),
+		Schedule: ScheduleRest{
+			Frequency:  string(r.Schedule.Frequency),
+			Interval:   r.Schedule.Interval,
+			DayOfMonth: r.Schedule.DayOfMonth,
+			Start:      NewRestTime(r.Schedule.Start),
+			Until:      NewRestTime(r.Schedule.Until),
+			Count:      r.Schedule.Count,
+		},
+		NextOccurrence: r.NextOccurrence,
+	}
+}
+
+func recurringRoutes(repo RecurringExpenseRepository) func(r chi.Router) {
+	return func(r chi.Router) {
+		r.Post("/", createRecurring(repo))
+		r.Get("/", listRecurring(repo))
+		r.Route("/{recurringID}", func(r chi.Router) {
+			r.Get("/", getRecurring(repo))
+			r.Put("/", updateRecurring(repo))
+			r.Delete("/", deleteRecurring(repo))
+		})
+	}
+}
+
+func validateRecurringError(w http.ResponseWriter, err error) bool {
+	return validateResourceError(w, "recurring expense", err)
+}
+
+func decodeRecurring(w http.ResponseWriter, r *http.Request) (entity.RecurringExpense, bool) {
+	ctx := r.Context()
+	recurringRest := new(RecurringExpenseRest)
+	err := json.NewDecoder(r.Body).Decode(recurringRest)
+	if err != nil {
+		log.Ctx(ctx).Err(err).Msg("error on decode")
+		fillHttpError(w,
+			NewHttpError(http.StatusBadRequest, "",
+				NewError("INVALID_REQUEST", "invalid json"),
+			),
+		)
+		return entity.RecurringExpense{}, false
+	}
+	recurring, err := recurringRest.ToRecurringExpense()
+	if err != nil {
+		log.Ctx(ctx).Err(err).Msg("error on validate")
+		fillHttpError(w,
+			NewHttpError(http.StatusBadRequest, "",
+				NewError("INVALID_REQUEST", "invalid recurring expense").WithFields(err),
+			),
+		)
+		return entity.RecurringExpense{}, false
+	}
+	return recurring, true
+}
+
+func createRecurring(repo RecurringExpenseRepository) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		recurring, ok := decodeRecurring(w, r)
+		if !ok {
+			return
+		}
+		id, err := repo.Create(ctx, recurring)
+		if validateRecurringError(w, err) {
+			log.Ctx(ctx).Err(err).Msg("error on create")
+			return
+		}
+		w.Write([]byte(`{"id":"` + id + `"}`))
+	}
+}
+
+func listRecurring(repo RecurringExpenseRepository) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		recurrings, err := repo.List(ctx)
+		if validateRecurringError(w, err) {
+			log.Ctx(ctx).Err(err).Msg("error on list")
+			return
+		}
+		res := make([]RecurringExpenseRest, len(recurrings))
+		for i, recurring := range recurrings {
+			res[i] = NewRecurringExpenseRestFromRecurringExpense(recurring)
+		}
+		err = json.NewEncoder(w).Encode(res)
+		if validateRecurringError(w, err) {
+			return
+		}
+	}
+}
+
+func getRecurring(repo RecurringExpenseRepository) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		recurring, err := repo.Get(ctx, chi.URLParam(r, "recurringID"))
+		if validateRecurringError(w, err) {
+			log.Ctx(ctx).Err(err).Msg("error on consult")
+			return
+		}
+		err = json.NewEncoder(w).Encode(NewRecurringExpenseRestFromRecurringExpense(recurring))
+		if validateRecurringError(w, err) {
+			return
+		}
+	}
+}
+
+func updateRecurring(repo RecurringExpenseRepository) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		recurring, ok := decodeRecurring(w, r)
+		if !ok {
+			return
+		}
+		recurring.Id = chi.URLParam(r, "recurringID")
+		err := repo.Update(ctx, recurring)
+		if validateRecurringError(w, err) {
+			log.Ctx(ctx).Err(err).Msg("error on update")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func deleteRecurring(repo RecurringExpenseRepository) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		err := repo.Delete(ctx, chi.URLParam(r, "recurringID"))
+		if validateRecurringError(w, err) {
+			log.Ctx(ctx).Err(err).Msg("error on delete")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
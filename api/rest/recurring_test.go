@@ -0,0 +1,169 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/axpira/backend/entity"
+	"github.com/axpira/backend/entity/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockRecurringRepo struct {
+	mock.Mock
+}
+
+func (m *mockRecurringRepo) Create(ctx context.Context, recurring entity.RecurringExpense) (string, error) {
+	args := m.Called(ctx, recurring)
+	return args.String(0), args.Error(1)
+}
+func (m *mockRecurringRepo) Update(ctx context.Context, recurring entity.RecurringExpense) error {
+	args := m.Called(ctx, recurring)
+	return args.Error(0)
+}
+func (m *mockRecurringRepo) Delete(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+func (m *mockRecurringRepo) Get(ctx context.Context, id string) (entity.RecurringExpense, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(entity.RecurringExpense), args.Error(1)
+}
+func (m *mockRecurringRepo) List(ctx context.Context) ([]entity.RecurringExpense, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]entity.RecurringExpense), args.Error(1)
+}
+
+func TestRecurring(t *testing.T) {
+	defaultCurrency := config.Config.DefaultCurrency
+	config.Config.DefaultCurrency = "BRL"
+	defer func() { config.Config.DefaultCurrency = defaultCurrency }()
+
+	start := time.Date(2021, 1, 5, 0, 0, 0, 0, time.UTC)
+	rent := entity.RecurringExpense{
+		Template: entity.Expense{Kind: entity.EXPENSE, Amount: 150000, Currency: "BRL", What: "rent"},
+		Schedule: entity.Schedule{Frequency: entity.MONTHLY, DayOfMonth: 5, Start: start},
+	}
+	tests := map[string]struct {
+		method     string
+		path       string
+		sent       []byte
+		setup      func(*mockRecurringRepo)
+		wantResult []byte
+		wantStatus int
+	}{
+		"create": {
+			method: http.MethodPost,
+			path:   "/api/recurring",
+			sent:   []byte(`{"template": {"amount": "1500.00", "what": "rent"}, "schedule": {"frequency": "monthly", "dayOfMonth": 5, "start": "2021-01-05T00:00:00Z"}}`),
+			setup: func(m *mockRecurringRepo) {
+				m.On("Create", mock.Anything, rent).Return("rent", nil)
+			},
+			wantStatus: 200,
+			wantResult: []byte(`{"id": "rent"}`),
+		},
+		"create invalid": {
+			method: http.MethodPost,
+			path:   "/api/recurring",
+			sent:   []byte(`{"template": {"amount": "1500.00"}, "schedule": {"frequency": "hourly"}}`),
+			setup: func(m *mockRecurringRepo) {
+				m.On("Create", mock.Anything, mock.Anything).
+					Return("", entity.NewFieldError(nil, "frequency", "invalid", "must be one of [daily weekly monthly yearly]"))
+			},
+			wantStatus: 400,
+			wantResult: []byte(`{
+				"code": "INVALID_REQUEST",
+				"message": "invalid recurring expense",
+				"fields": [
+					{"field": "frequency", "code": "invalid", "message": "must be one of [daily weekly monthly yearly]"}
+				]
+			}`),
+		},
+		"get": {
+			method: http.MethodGet,
+			path:   "/api/recurring/rent",
+			setup: func(m *mockRecurringRepo) {
+				r := rent
+				r.Id = "rent"
+				r.NextOccurrence = 3
+				m.On("Get", mock.Anything, "rent").Return(r, nil)
+			},
+			wantStatus: 200,
+			wantResult: []byte(`{
+				"id": "rent",
+				"kind": "expense",
+				"template": {"amount": "1500.00", "currency": "BRL", "what": "rent"},
+				"schedule": {"frequency": "monthly", "dayOfMonth": 5, "start": "2021-01-05T00:00:00Z"},
+				"nextOccurrence": 3
+			}`),
+		},
+		"get not found": {
+			method: http.MethodGet,
+			path:   "/api/recurring/unknown",
+			setup: func(m *mockRecurringRepo) {
+				m.On("Get", mock.Anything, "unknown").Return(entity.RecurringExpense{}, entity.ErrNotFound)
+			},
+			wantStatus: 404,
+			wantResult: []byte(`{"code": "NOT_FOUND", "message": "recurring expense not found"}`),
+		},
+		"update": {
+			method: http.MethodPut,
+			path:   "/api/recurring/rent",
+			sent:   []byte(`{"template": {"amount": "1500.00", "what": "rent"}, "schedule": {"frequency": "monthly", "dayOfMonth": 5, "start": "2021-01-05T00:00:00Z"}}`),
+			setup: func(m *mockRecurringRepo) {
+				r := rent
+				r.Id = "rent"
+				m.On("Update", mock.Anything, r).Return(nil)
+			},
+			wantStatus: 204,
+		},
+		"delete": {
+			method: http.MethodDelete,
+			path:   "/api/recurring/rent",
+			setup: func(m *mockRecurringRepo) {
+				m.On("Delete", mock.Anything, "rent").Return(nil)
+			},
+			wantStatus: 204,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockedRepo := new(mockRecurringRepo)
+			if tc.setup != nil {
+				tc.setup(mockedRepo)
+			}
+			ts := httptest.NewServer(createHandler(context.Background(), new(mockExpenseRepo), WithRecurringExpenseRepository(mockedRepo)))
+			defer ts.Close()
+
+			req, err := http.NewRequest(tc.method, ts.URL+tc.path, bytes.NewBuffer(tc.sent))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Accept", "application/json")
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tc.wantStatus, res.StatusCode)
+
+			got, err := io.ReadAll(res.Body)
+			res.Body.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			mockedRepo.AssertExpectations(t)
+			if tc.wantResult != nil {
+				assert.JSONEq(t, string(tc.wantResult), string(got))
+			} else {
+				assert.Empty(t, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/axpira/backend/entity"
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+type ReportRepository interface {
+	Summary(ctx context.Context, filter *entity.ExpenseFilter, group entity.SummaryGroup) ([]entity.Summary, error)
+}
+
+type SummaryRest struct {
+	Key      string      `json:"key"`
+	Total    *amountRest `json:"total,omitempty"`
+	Currency string      `json:"currency"`
+	Count    int64       `json:"count"`
+}
+
+func NewSummaryRestFromSummary(s entity.Summary) SummaryRest {
+	return SummaryRest{
+		Key:      s.Key,
+		Total:    NewAmountRest(entity.Money{Amount: s.Total, Currency: s.Currency}),
+		Currency: string(s.Currency),
+		Count:    s.Count,
+	}
+}
+
+func reportRoutes(repo ReportRepository) func(r chi.Router) {
+	return func(r chi.Router) {
+		r.Get("/summary", getSummary(repo))
+	}
+}
+
+// parseSummaryQuery reads groupBy, month when it's omitted, and the search filters from the other params.
+// Only expenses are totaled, transfers and settlements aren't spending
+func parseSummaryQuery(query url.Values) (*entity.ExpenseFilter, entity.SummaryGroup, error) {
+	group := entity.BY_MONTH
+	if groupBy := query.Get("groupBy"); groupBy != "" {
+		group = entity.SummaryGroup(groupBy)
+	}
+	err := group.Validate()
+	filter, filterErr := parseExpenseFilter(withoutParams(query, "groupBy"))
+	if filterErr == nil {
+		filterErr = entity.FilterKind(entity.EXPENSE)(filter)
+	}
+	err = entity.MergeFieldErrors(err, filterErr)
+	if err != nil {
+		return nil, "", err
+	}
+	return filter, group, nil
+}
+
+func getSummary(repo ReportRepository) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		filter, group, err := parseSummaryQuery(r.URL.Query())
+		if err != nil {
+			log.Ctx(ctx).Err(err).Msg("error on parse filter")
+			fillHttpError(w,
+				NewHttpError(http.StatusBadRequest, "",
+					NewError("INVALID_FILTER", "invalid filter").WithFields(err),
+				),
+			)
+			return
+		}
+		summaries, err := repo.Summary(ctx, filter, group)
+		if validateResourceError(w, "report", err) {
+			log.Ctx(ctx).Err(err).Msg("error on summary")
+			return
+		}
+		res := make([]SummaryRest, len(summaries))
+		for i, summary := range summaries {
+			res[i] = NewSummaryRestFromSummary(summary)
+		}
+		err = json.NewEncoder(w).Encode(res)
+		if validateResourceError(w, "report", err) {
+			return
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package rest
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/axpira/backend/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockReportRepo struct {
+	mock.Mock
+}
+
+func (m *mockReportRepo) Summary(ctx context.Context, filter *entity.ExpenseFilter, group entity.SummaryGroup) ([]entity.Summary, error) {
+	args := m.Called(ctx, filter, group)
+	return args.Get(0).([]entity.Summary), args.Error(1)
+}
+
+func TestReportSummary(t *testing.T) {
+	from := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := map[string]struct {
+		path       string
+		setup      func(*mockReportRepo)
+		wantResult []byte
+		wantStatus int
+	}{
+		"by month when not grouped": {
+			path: "/api/report/summary?when[gte]=2021-01-01",
+			setup: func(m *mockReportRepo) {
+				filter, _ := entity.NewExpenseFilter(entity.FilterWhen(entity.GE, from), entity.FilterKind(entity.EXPENSE))
+				m.On("Summary", mock.Anything, filter, entity.BY_MONTH).Return([]entity.Summary{
+					{Key: "2021-01", Currency: "BRL", Total: 150000, Count: 12},
+					{Key: "2021-02", Currency: "BRL", Total: 90050, Count: 7},
+				}, nil)
+			},
+			wantStatus: 200,
+			wantResult: []byte(`[
+				{"key": "2021-01", "total": "1500.00", "currency": "BRL", "count": 12},
+				{"key": "2021-02", "total": "900.50", "currency": "BRL", "count": 7}
+			]`),
+		},
+		"by category": {
+			path: "/api/report/summary?groupBy=category&tag=trip",
+			setup: func(m *mockReportRepo) {
+				filter, _ := entity.NewExpenseFilter(entity.FilterTag(entity.EQUALS, "trip"), entity.FilterKind(entity.EXPENSE))
+				m.On("Summary", mock.Anything, filter, entity.BY_CATEGORY).Return([]entity.Summary{
+					{Key: "", Currency: "USD", Total: 1000, Count: 1},
+				}, nil)
+			},
+			wantStatus: 200,
+			wantResult: []byte(`[{"key": "", "total": "10.00", "currency": "USD", "count": 1}]`),
+		},
+		"invalid group and filter": {
+			path:       "/api/report/summary?groupBy=day&color=blue",
+			wantStatus: 400,
+			wantResult: []byte(`{
+				"code": "INVALID_FILTER",
+				"message": "invalid filter",
+				"fields": [
+					{"field": "groupBy", "code": "invalid", "message": "must be one of [month week category tag who where]"},
+					{"field": "color", "code": "unknown_field", "message": "unknown filter field \"color\""}
+				]
+			}`),
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockedRepo := new(mockReportRepo)
+			if tc.setup != nil {
+				tc.setup(mockedRepo)
+			}
+			ts := httptest.NewServer(createHandler(context.Background(), new(mockExpenseRepo), WithReportRepository(mockedRepo)))
+			defer ts.Close()
+
+			res, err := getJSON(ts.URL + tc.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tc.wantStatus, res.StatusCode)
+
+			got, err := io.ReadAll(res.Body)
+			res.Body.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			mockedRepo.AssertExpectations(t)
+			assert.JSONEq(t, string(tc.wantResult), string(got))
+		})
+	}
+}
@@ -5,9 +5,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"math"
 	"net/http"
-	"strconv"
+	"regexp"
 	"sync"
 	"time"
 
@@ -25,65 +24,174 @@ func NewRestTime(t time.Time) *time.Time {
 	return &t
 }
 
+// amountRest keeps the decimal as sent, it is scaled only when the currency is known
 type amountRest struct {
-	value int64
+	money entity.Money
+	raw   string
 }
 
-func NewAmountRest(a int64) *amountRest {
-	if a == 0 {
+var decimalPattern = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+func NewAmountRest(m entity.Money) *amountRest {
+	if m.Amount == 0 {
 		return nil
 	}
-	return &amountRest{a}
-
+	return &amountRest{money: m}
 }
 
 func (a amountRest) MarshalJSON() ([]byte, error) {
-	return []byte(fmt.Sprintf(`"%.02f"`, float64(a.value)*math.Pow10(-2))), nil
+	return json.Marshal(a.money.String())
 }
 
-func (a *amountRest) UnmarshalJSON(data []byte) (err error) {
-	v, err := strconv.ParseFloat(string(data[1:len(data)-1]), 64)
-	if err != nil {
+func (a *amountRest) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err != nil {
 		return err
 	}
-	a.value = int64(math.Round(v * 100))
+	if !decimalPattern.MatchString(raw) {
+		return fmt.Errorf("invalid amount %q", raw)
+	}
+	a.raw = raw
 	return nil
 }
 
 type ExpenseRest struct {
-	Id     string      `json:"id,omitempty"`
-	Amount *amountRest `json:"amount,omitempty"`
-	When   *time.Time  `json:"when,omitempty"`
-	Where  string      `json:"where,omitempty"`
-	Who    string      `json:"who,omitempty"`
-	What   string      `json:"what,omitempty"`
+	Id              string      `json:"id,omitempty"`
+	Amount          *amountRest `json:"amount,omitempty"`
+	Currency        string      `json:"currency,omitempty"`
+	When            *time.Time  `json:"when,omitempty"`
+	Where           string      `json:"where,omitempty"`
+	Who             string      `json:"who,omitempty"`
+	What            string      `json:"what,omitempty"`
+	Tags            []string    `json:"tags,omitempty"`
+	CategoryId      string      `json:"categoryId,omitempty"`
+	PaymentMethodId string      `json:"paymentMethodId,omitempty"`
+	// ToPaymentMethodId is only sent on transfers
+	ToPaymentMethodId string `json:"toPaymentMethodId,omitempty"`
+	// WhereUrn and WhoUrn reference the place and the person, like urn:place:<id>
+	WhereUrn string `json:"whereUrn,omitempty"`
+	WhoUrn   string `json:"whoUrn,omitempty"`
+	// Split divides the amount between people, Who is who paid it
+	Split *SplitRest `json:"split,omitempty"`
+	// Details are the line items, their totals must sum Amount
+	Details []DetailRest `json:"details,omitempty"`
+	// Metadata is free-form, the keys can't be empty nor have spaces
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// RecurringId is read only, set on the expenses created by a recurring expense
+	RecurringId string `json:"recurringId,omitempty"`
+	// ExternalId is read only, set on the expenses imported from a statement
+	ExternalId string `json:"externalId,omitempty"`
 }
 
-func (e ExpenseRest) ToExpense() entity.Expense {
+func (e ExpenseRest) ToExpense() (entity.Expense, error) {
 	exp := entity.Expense{
-		Id:    e.Id,
-		Where: e.Where,
-		Who:   e.Who,
-		What:  e.What,
+		Id:                e.Id,
+		Where:             e.Where,
+		Who:               e.Who,
+		What:              e.What,
+		CategoryId:        e.CategoryId,
+		PaymentMethodId:   e.PaymentMethodId,
+		ToPaymentMethodId: e.ToPaymentMethodId,
+	}
+	if e.WhereUrn != "" {
+		id, err := urnId(e.WhereUrn, entity.PLACE, "whereUrn")
+		if err != nil {
+			return entity.Expense{}, err
+		}
+		exp.PlaceId = id
+	}
+	if e.WhoUrn != "" {
+		id, err := urnId(e.WhoUrn, entity.PERSON, "whoUrn")
+		if err != nil {
+			return entity.Expense{}, err
+		}
+		exp.PersonId = id
+	}
+	if e.Currency != "" {
+		currency, err := entity.ParseCurrency(e.Currency)
+		if err != nil {
+			return entity.Expense{}, err
+		}
+		exp.Currency = currency
 	}
 	if e.Amount != nil {
-		exp.Amount = e.Amount.value
+		money, err := entity.ParseMoney(e.Amount.raw, exp.Currency)
+		if err != nil {
+			return entity.Expense{}, err
+		}
+		exp.Amount = money.Amount
+	}
+	if e.Split != nil {
+		split, err := e.Split.ToSplit(exp.Currency)
+		if err != nil {
+			return entity.Expense{}, err
+		}
+		exp.Split = &split
+	}
+	if e.Details != nil {
+		details, err := toDetails(e.Details, exp.Currency)
+		if err != nil {
+			return entity.Expense{}, err
+		}
+		exp.Details = details
 	}
 	if e.When != nil {
 		exp.When = e.When.UTC()
 	}
-	return exp
+	if e.Tags != nil {
+		tags, err := entity.NewTags(e.Tags...)
+		if err != nil {
+			return entity.Expense{}, err
+		}
+		exp.Tags = tags
+	}
+	if e.Metadata != nil {
+		metadata, err := entity.NewMetadata(e.Metadata)
+		if err != nil {
+			return entity.Expense{}, err
+		}
+		exp.Metadata = metadata
+	}
+	return exp, nil
+}
+
+// urnId returns the id referenced by urn, the error is on field
+func urnId(urn string, kind entity.URNKind, field string) (string, error) {
+	id, err := entity.URN(urn).Id(kind)
+	if err != nil {
+		return "", entity.RenameFieldError(err, field)
+	}
+	return id, nil
 }
 
 func NewExpenseRestFromExpense(e entity.Expense) ExpenseRest {
-	return ExpenseRest{
-		Id:     e.Id,
-		Amount: NewAmountRest(e.Amount),
-		When:   NewRestTime(e.When),
-		Where:  e.Where,
-		Who:    e.Who,
-		What:   e.What,
+	res := ExpenseRest{
+		Id:                e.Id,
+		Amount:            NewAmountRest(e.Money()),
+		Currency:          string(e.Currency),
+		When:              NewRestTime(e.When),
+		Where:             e.Where,
+		Who:               e.Who,
+		What:              e.What,
+		CategoryId:        e.CategoryId,
+		PaymentMethodId:   e.PaymentMethodId,
+		ToPaymentMethodId: e.ToPaymentMethodId,
+		WhereUrn:          string(entity.NewURN(entity.PLACE, e.PlaceId)),
+		WhoUrn:            string(entity.NewURN(entity.PERSON, e.PersonId)),
+		RecurringId:       e.RecurringId,
+		ExternalId:        e.ExternalId,
+		Metadata:          e.Metadata,
+	}
+	if e.Tags != nil {
+		res.Tags = e.Tags.Value()
+	}
+	if e.Split != nil {
+		res.Split = NewSplitRestFromSplit(*e.Split, e.Currency)
 	}
+	if len(e.Details) > 0 {
+		res.Details = newDetailRestsFromDetails(e.Details, e.Currency)
+	}
+	return res
 }
 
 type Service interface {
@@ -97,7 +205,92 @@ type ExpenseRepository interface {
 	Update(ctx context.Context, expense entity.Expense) error
 	Delete(ctx context.Context, id string) error
 	Get(ctx context.Context, id string) (entity.Expense, error)
+	Replace(ctx context.Context, expense entity.Expense) error
 	Search(context.Context, *entity.ExpenseFilter) ([]entity.Expense, error)
+	SearchPage(context.Context, *entity.ExpenseFilter, entity.PageRequest) (entity.Page, error)
+	UpdateTags(context.Context, *entity.ExpenseFilter, entity.TagAction, *entity.Tags) (int64, error)
+}
+
+type options struct {
+	categoryRepo      CategoryRepository
+	paymentMethodRepo PaymentMethodRepository
+	exchangeRateRepo  ExchangeRateRepository
+	recurringRepo     RecurringExpenseRepository
+	placeRepo         PlaceRepository
+	personRepo        PersonRepository
+	budgetRepo        BudgetRepository
+	reportRepo        ReportRepository
+	exportRepo        ExportRepository
+	idempotencyRepo   IdempotencyRepository
+	idempotencyWindow time.Duration
+}
+
+type Option func(*options)
+
+func WithCategoryRepository(repo CategoryRepository) Option {
+	return func(o *options) {
+		o.categoryRepo = repo
+	}
+}
+
+func WithPaymentMethodRepository(repo PaymentMethodRepository) Option {
+	return func(o *options) {
+		o.paymentMethodRepo = repo
+	}
+}
+
+// WithExchangeRateRepository also converts expenses to config.Config.BaseCurrency
+func WithExchangeRateRepository(repo ExchangeRateRepository) Option {
+	return func(o *options) {
+		o.exchangeRateRepo = repo
+	}
+}
+
+func WithRecurringExpenseRepository(repo RecurringExpenseRepository) Option {
+	return func(o *options) {
+		o.recurringRepo = repo
+	}
+}
+
+func WithPlaceRepository(repo PlaceRepository) Option {
+	return func(o *options) {
+		o.placeRepo = repo
+	}
+}
+
+func WithPersonRepository(repo PersonRepository) Option {
+	return func(o *options) {
+		o.personRepo = repo
+	}
+}
+
+// WithBudgetRepository mounts /budget, the status reads the spending from the expenses
+func WithBudgetRepository(repo BudgetRepository) Option {
+	return func(o *options) {
+		o.budgetRepo = repo
+	}
+}
+
+// WithReportRepository mounts /report, it totals only the expenses
+func WithReportRepository(repo ReportRepository) Option {
+	return func(o *options) {
+		o.reportRepo = repo
+	}
+}
+
+func WithExportRepository(repo ExportRepository) Option {
+	return func(o *options) {
+		o.exportRepo = repo
+	}
+}
+
+// WithIdempotencyRepository replays the response of the transaction creates sent with an
+// Idempotency-Key to their retries during window
+func WithIdempotencyRepository(repo IdempotencyRepository, window time.Duration) Option {
+	return func(o *options) {
+		o.idempotencyRepo = repo
+		o.idempotencyWindow = window
+	}
 }
 
 type service struct {
@@ -105,11 +298,11 @@ type service struct {
 	wg  *sync.WaitGroup
 }
 
-func New(ctx context.Context, repo ExpenseRepository) (Service, error) {
+func New(ctx context.Context, repo ExpenseRepository, opts ...Option) (Service, error) {
 	addr := fmt.Sprintf(":%v", config.Config.Port)
 	srv := &http.Server{
 		Addr:    addr,
-		Handler: createHandler(ctx, repo),
+		Handler: createHandler(ctx, repo, opts...),
 	}
 	return &service{
 		srv: srv,
@@ -126,30 +319,69 @@ func (s *service) Start(ctx context.Context) {
 	}()
 }
 
-func createHandler(ctx context.Context, repo ExpenseRepository) http.Handler {
+func createHandler(ctx context.Context, repo ExpenseRepository, opts ...Option) http.Handler {
 	l := log.Ctx(ctx)
 	r := chi.NewRouter()
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
 
 	r.Route("/api", func(r chi.Router) {
 		r.Use(middleware.SetHeader("Content-Type", "application/json"))
 		r.Use(TraceID)
+		r.Use(ProblemDetails)
 		r.Use(LogHandler(l))
 		r.Use(middleware.Timeout(60 * time.Second))
 		r.NotFound(http.HandlerFunc(notFoundHandler))
-		r.Route("/expense", func(r chi.Router) {
-			r.Post("/", createExpense(repo))
-			r.Route("/{expenseID}", func(r chi.Router) {
-				r.Get("/", getExpense(repo))
-				r.Delete("/", deleteExpense(repo))
-			})
-		})
+		r.Route("/expense", transactionRoutes(newKindRepository(repo, entity.EXPENSE), o))
+		r.Route("/income", transactionRoutes(newKindRepository(repo, entity.INCOME), o))
+		r.Route("/transfer", transactionRoutes(newKindRepository(repo, entity.TRANSFER), o))
+		settlements := newKindRepository(repo, entity.SETTLEMENT)
+		r.Route("/settlement", transactionRoutes(settlements, o))
+		r.Route("/balance", balanceRoutes(repo, settlements))
+		if o.categoryRepo != nil {
+			r.Route("/category", categoryRoutes(o.categoryRepo))
+		}
+		if o.paymentMethodRepo != nil {
+			r.Route("/payment-method", paymentMethodRoutes(o.paymentMethodRepo))
+		}
+		if o.exchangeRateRepo != nil {
+			r.Route("/exchange-rate", exchangeRateRoutes(o.exchangeRateRepo))
+		}
+		if o.recurringRepo != nil {
+			r.Route("/recurring", recurringRoutes(o.recurringRepo))
+		}
+		if o.placeRepo != nil {
+			r.Route("/place", placeRoutes(o.placeRepo))
+		}
+		if o.personRepo != nil {
+			r.Route("/person", personRoutes(o.personRepo))
+		}
+		if o.budgetRepo != nil {
+			r.Route("/budget", budgetRoutes(o.budgetRepo, newKindRepository(repo, entity.EXPENSE)))
+		}
+		r.Route("/import", importRoutes(repo))
+		if o.reportRepo != nil {
+			r.Route("/report", reportRoutes(o.reportRepo))
+		}
+		if o.exportRepo != nil {
+			r.Route("/export", exportRoutes(o.exportRepo))
+		}
 	})
 	return r
 }
 
+type FieldErrorRest struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
 type Error struct {
-	Code    string `json:"code,omitempty"`
-	Message string `json:"message,omitempty"`
+	Code    string           `json:"code,omitempty"`
+	Message string           `json:"message,omitempty"`
+	Fields  []FieldErrorRest `json:"fields,omitempty"`
 }
 
 func NewError(code, message string) Error {
@@ -159,6 +391,20 @@ func NewError(code, message string) Error {
 	}
 }
 
+// WithFields adds every entity.FieldError chained in err, in the order they happened
+func (h Error) WithFields(err error) Error {
+	fieldErrors := entity.UnwrapFieldErrors(err)
+	h.Fields = make([]FieldErrorRest, len(fieldErrors))
+	for i, f := range fieldErrors {
+		h.Fields[len(fieldErrors)-1-i] = FieldErrorRest{
+			Field:   f.Field(),
+			Code:    f.Code(),
+			Message: f.Description(),
+		}
+	}
+	return h
+}
+
 func (h Error) Error() string {
 	return fmt.Sprintf("%s: %s", h.Code, h.Message)
 }
@@ -196,8 +442,13 @@ func fillHttpError(w http.ResponseWriter, err error) bool {
 	if err != nil {
 		var httpError HttpError
 		if errors.As(err, &httpError) {
+			var body interface{} = httpError.Detail
+			if p, ok := asProblemWriter(w); ok {
+				w.Header().Set("Content-Type", problemContentType)
+				body = p.problem(httpError)
+			}
 			w.WriteHeader(httpError.StatusCode)
-			err := json.NewEncoder(w).Encode(httpError.Detail)
+			err := json.NewEncoder(w).Encode(body)
 			if err != nil {
 				panic(err)
 			}
@@ -217,21 +468,40 @@ func notFoundHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func validateError(w http.ResponseWriter, err error) bool {
+	return validateResourceError(w, "expense", err)
+}
+
+func validateResourceError(w http.ResponseWriter, resource string, err error) bool {
 	if err != nil {
-		if errors.Is(err, entity.ErrNotFound) {
-			fillHttpError(w, NewHttpError(http.StatusNotFound, "",
-				NewError("NOT_FOUND", fmt.Sprintf("expense not found"))),
-			)
-		} else {
-			fillHttpError(w, NewHttpError(0, "",
-				NewError("", ""),
-			))
-		}
+		fillHttpError(w, newResourceError(resource, err))
 		return true
 	}
 	return false
 }
 
+// newResourceError sends the field errors as an invalid request and the other business errors as
+// refused by a rule. Conflicts and technical errors only say what failed, their details like the
+// constraint names are only logged
+func newResourceError(resource string, err error) HttpError {
+	var fieldErr entity.FieldError
+	switch {
+	case errors.Is(err, entity.ErrNotFound):
+		return NewHttpError(http.StatusNotFound, "",
+			NewError("NOT_FOUND", fmt.Sprintf("%s not found", resource)))
+	case errors.Is(err, entity.ErrConflict):
+		return NewHttpError(http.StatusConflict, "",
+			NewError("CONFLICT", fmt.Sprintf("%s is in use or already exists", resource)))
+	case errors.As(err, &fieldErr):
+		return NewHttpError(http.StatusBadRequest, "",
+			NewError("INVALID_REQUEST", fmt.Sprintf("invalid %s", resource)).WithFields(err))
+	case errors.Is(err, entity.ErrBusiness):
+		return NewHttpError(http.StatusUnprocessableEntity, "",
+			NewError("UNPROCESSABLE", err.Error()))
+	}
+	return NewHttpError(http.StatusInternalServerError, "",
+		NewError("INTERNAL_ERROR", "internal error"))
+}
+
 func createExpense(repo ExpenseRepository) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
@@ -246,7 +516,20 @@ func createExpense(repo ExpenseRepository) func(w http.ResponseWriter, r *http.R
 			)
 			return
 		}
-		id, err := repo.Create(ctx, expense.ToExpense())
+		if expense.Currency == "" {
+			expense.Currency = config.Config.DefaultCurrency
+		}
+		exp, err := expense.ToExpense()
+		if err != nil {
+			log.Ctx(ctx).Err(err).Msg("error on validate")
+			fillHttpError(w,
+				NewHttpError(http.StatusBadRequest, "",
+					NewError("INVALID_REQUEST", "invalid expense").WithFields(err),
+				),
+			)
+			return
+		}
+		id, err := repo.Create(ctx, exp)
 		if validateError(w, err) {
 			log.Ctx(ctx).Err(err).Msg("error on create")
 			return
@@ -254,6 +537,32 @@ func createExpense(repo ExpenseRepository) func(w http.ResponseWriter, r *http.R
 		w.Write([]byte(`{"id":"` + id + `"}`))
 	}
 }
+
+func searchExpense(repo ExpenseRepository) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		page, filter, err := parsePageQuery(r.URL.Query())
+		if err != nil {
+			log.Ctx(ctx).Err(err).Msg("error on parse filter")
+			fillHttpError(w,
+				NewHttpError(http.StatusBadRequest, "",
+					NewError("INVALID_FILTER", "invalid filter").WithFields(err),
+				),
+			)
+			return
+		}
+		res, err := repo.SearchPage(ctx, filter, page)
+		if validateError(w, err) {
+			log.Ctx(ctx).Err(err).Msg("error on search")
+			return
+		}
+		err = json.NewEncoder(w).Encode(NewPageRestFromPage(res))
+		if validateError(w, err) {
+			return
+		}
+	}
+}
+
 func getExpense(repo ExpenseRepository) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
@@ -270,12 +579,84 @@ func getExpense(repo ExpenseRepository) func(w http.ResponseWriter, r *http.Requ
 	}
 }
 
+// currencyFunc gives the currency of an expense sent without one
+type currencyFunc func(ctx context.Context, id string, expense *ExpenseRest) (string, error)
+
+func defaultCurrency(context.Context, string, *ExpenseRest) (string, error) {
+	return config.Config.DefaultCurrency, nil
+}
+
+// storedCurrency keeps the currency of a partial update, new amounts are scaled with it
+func storedCurrency(repo ExpenseRepository) currencyFunc {
+	return func(ctx context.Context, id string, expense *ExpenseRest) (string, error) {
+		if expense.Amount == nil && expense.Split == nil && expense.Details == nil {
+			return "", nil
+		}
+		stored, err := repo.Get(ctx, id)
+		if err != nil {
+			return "", err
+		}
+		return string(stored.Currency), nil
+	}
+}
+
+func replaceExpense(repo ExpenseRepository) func(w http.ResponseWriter, r *http.Request) {
+	return saveExpense(repo.Replace, defaultCurrency)
+}
+
+func updateExpense(repo ExpenseRepository) func(w http.ResponseWriter, r *http.Request) {
+	return saveExpense(repo.Update, storedCurrency(repo))
+}
+
+func saveExpense(save func(context.Context, entity.Expense) error, currencyOf currencyFunc) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		expenseRest := new(ExpenseRest)
+		err := json.NewDecoder(r.Body).Decode(expenseRest)
+		if err != nil {
+			log.Ctx(ctx).Err(err).Msg("error on decode")
+			fillHttpError(w,
+				NewHttpError(http.StatusBadRequest, "",
+					NewError("INVALID_REQUEST", "invalid json"),
+				),
+			)
+			return
+		}
+		expenseID := chi.URLParam(r, "expenseID")
+		if expenseRest.Currency == "" {
+			expenseRest.Currency, err = currencyOf(ctx, expenseID, expenseRest)
+			if validateError(w, err) {
+				log.Ctx(ctx).Err(err).Msg("error on consult")
+				return
+			}
+		}
+		expense, err := expenseRest.ToExpense()
+		if err != nil {
+			log.Ctx(ctx).Err(err).Msg("error on validate")
+			fillHttpError(w,
+				NewHttpError(http.StatusBadRequest, "",
+					NewError("INVALID_REQUEST", "invalid expense").WithFields(err),
+				),
+			)
+			return
+		}
+		expense.Id = expenseID
+		err = save(ctx, expense)
+		if validateError(w, err) {
+			log.Ctx(ctx).Err(err).Msg("error on update")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
 func deleteExpense(repo ExpenseRepository) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 		expenseID := chi.URLParam(r, "expenseID")
 		err := repo.Delete(ctx, expenseID)
 		if validateError(w, err) {
+			log.Ctx(ctx).Err(err).Msg("error on delete")
 			return
 		}
 		w.WriteHeader(http.StatusNoContent)
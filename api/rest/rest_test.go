@@ -4,13 +4,16 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/axpira/backend/entity"
+	"github.com/axpira/backend/entity/config"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -63,6 +66,10 @@ func (m *mockExpenseRepo) Update(ctx context.Context, expense entity.Expense) er
 	args := m.Called(ctx, expense)
 	return args.Error(0)
 }
+func (m *mockExpenseRepo) Replace(ctx context.Context, expense entity.Expense) error {
+	args := m.Called(ctx, expense)
+	return args.Error(0)
+}
 func (m *mockExpenseRepo) Delete(ctx context.Context, id string) error {
 	args := m.Called(ctx, id)
 	return args.Error(0)
@@ -72,8 +79,39 @@ func (m *mockExpenseRepo) Get(ctx context.Context, id string) (entity.Expense, e
 	return args.Get(0).(entity.Expense), args.Error(1)
 }
 
-func (m *mockExpenseRepo) Search(context.Context, *entity.ExpenseFilter) ([]entity.Expense, error) {
-	return nil, nil
+func (m *mockExpenseRepo) Search(ctx context.Context, filter *entity.ExpenseFilter) ([]entity.Expense, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]entity.Expense), args.Error(1)
+}
+
+func (m *mockExpenseRepo) SearchPage(ctx context.Context, filter *entity.ExpenseFilter, page entity.PageRequest) (entity.Page, error) {
+	args := m.Called(ctx, filter, page)
+	return args.Get(0).(entity.Page), args.Error(1)
+}
+
+func (m *mockExpenseRepo) UpdateTags(ctx context.Context, filter *entity.ExpenseFilter, action entity.TagAction, tags *entity.Tags) (int64, error) {
+	args := m.Called(ctx, filter, action, tags)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// getJSON and postJSON are http.Get and http.Post of a client accepting the application/json errors
+func getJSON(url string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	return http.DefaultClient.Do(req)
+}
+
+func postJSON(url, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodPost, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Accept", "application/json")
+	return http.DefaultClient.Do(req)
 }
 
 func TestGetExpense(t *testing.T) {
@@ -107,6 +145,20 @@ func TestGetExpense(t *testing.T) {
 				"who": "my who"
 			}`),
 		},
+		"success zero decimal currency": {
+			id: "9",
+			mockExpense: entity.Expense{
+				Id:       "9",
+				Amount:   1500,
+				Currency: "JPY",
+			},
+			wantStatus: 200,
+			wantResult: []byte(`{
+				"id":       "9",
+				"amount":   "1500",
+				"currency": "JPY"
+			}`),
+		},
 		"success amount": {
 			id: "2",
 			mockExpense: entity.Expense{
@@ -119,6 +171,18 @@ func TestGetExpense(t *testing.T) {
 				"amount": "2.30"
 			}`),
 		},
+		"success tags": {
+			id: "6",
+			mockExpense: entity.Expense{
+				Id:   "6",
+				Tags: entity.MustNewTags("trip", "work"),
+			},
+			wantStatus: 200,
+			wantResult: []byte(`{
+				"id":   "6",
+				"tags": ["trip", "work"]
+			}`),
+		},
 		"success id": {
 			id: "3",
 			mockExpense: entity.Expense{
@@ -141,7 +205,7 @@ func TestGetExpense(t *testing.T) {
 		"unknown error": {
 			id:         "5",
 			wantStatus: 500,
-			wantResult: []byte("{}"),
+			wantResult: []byte(`{"code": "INTERNAL_ERROR", "message": "internal error"}`),
 			mockErr:    errors.New("unknown error"),
 		},
 	}
@@ -156,7 +220,7 @@ func TestGetExpense(t *testing.T) {
 			ts := httptest.NewServer(createHandler(ctx, mockedExpense))
 			defer ts.Close()
 
-			res, err := http.Get(ts.URL + "/api/expense/" + tc.id)
+			res, err := getJSON(ts.URL + "/api/expense/" + tc.id)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -180,6 +244,11 @@ func TestGetExpense(t *testing.T) {
 	}
 }
 
+func withKind(e entity.Expense, kind entity.TransactionKind) entity.Expense {
+	e.Kind = kind
+	return e
+}
+
 func TestCreateExpense(t *testing.T) {
 	now := time.Now()
 	tests := map[string]struct {
@@ -228,6 +297,84 @@ func TestCreateExpense(t *testing.T) {
 				"id":     "2"
 			}`),
 		},
+		"success currency": {
+			id:       "8",
+			callMock: true,
+			sent: []byte(`{
+				"amount": "1500",
+				"currency": "jpy"
+			}`),
+			mockExpense: entity.Expense{
+				Amount:   1500,
+				Currency: "JPY",
+			},
+			wantStatus: 200,
+			wantResult: []byte(`{
+				"id":     "8"
+			}`),
+		},
+		"success tags": {
+			id:       "6",
+			callMock: true,
+			sent: []byte(`{
+				"amount": "2.3",
+				"tags": ["trip", " work "]
+			}`),
+			mockExpense: entity.Expense{
+				Amount: 230,
+				Tags:   entity.MustNewTags("trip", "work"),
+			},
+			wantStatus: 200,
+			wantResult: []byte(`{
+				"id":     "6"
+			}`),
+		},
+		"bad request invalid tags": {
+			id: "7",
+			sent: []byte(`{
+				"tags": ["", "my trip"]
+			}`),
+			wantStatus: 400,
+			wantResult: []byte(`{
+				"code": "INVALID_REQUEST",
+				"message": "invalid expense",
+				"fields": [
+					{"field": "tag", "code": "no_empty", "message": "can't be empty"},
+					{"field": "tag", "code": "no_space", "message": "can't have spaces"}
+				]
+			}`),
+		},
+		"success metadata": {
+			id:       "9",
+			callMock: true,
+			sent: []byte(`{
+				"amount": "2.3",
+				"metadata": {" invoice ": "2021-042", "project": "home"}
+			}`),
+			mockExpense: entity.Expense{
+				Amount:   230,
+				Metadata: map[string]string{"invoice": "2021-042", "project": "home"},
+			},
+			wantStatus: 200,
+			wantResult: []byte(`{
+				"id":     "9"
+			}`),
+		},
+		"bad request invalid metadata": {
+			id: "10",
+			sent: []byte(`{
+				"metadata": {"": "a", "my key": "b"}
+			}`),
+			wantStatus: 400,
+			wantResult: []byte(`{
+				"code": "INVALID_REQUEST",
+				"message": "invalid expense",
+				"fields": [
+					{"field": "metadata", "code": "no_empty", "message": "key can't be empty"},
+					{"field": "metadata.my key", "code": "no_space", "message": "key can't have spaces"}
+				]
+			}`),
+		},
 		"bad request": {
 			id:         "3",
 			sent:       []byte(`{"invalid message"}`),
@@ -258,9 +405,71 @@ func TestCreateExpense(t *testing.T) {
 				Amount: 230,
 			},
 			wantStatus: 500,
-			wantResult: []byte("{}"),
+			wantResult: []byte(`{"code": "INTERNAL_ERROR", "message": "internal error"}`),
 			mockErr:    errors.New("unknown error"),
 		},
+		"bad request empty body": {
+			sent:       []byte(`{}`),
+			wantStatus: 400,
+			wantResult: []byte(`{
+				"code": "INVALID_REQUEST",
+				"message": "invalid expense",
+				"fields": [{"field": "amount", "code": "no_empty", "message": "can't be empty"}]
+			}`),
+		},
+		"bad request out of the columns and the dates": {
+			sent:       []byte(`{"amount": "-2.30", "when": "3000-01-01T00:00:00Z", "what": "` + strings.Repeat("a", 256) + `"}`),
+			wantStatus: 400,
+			wantResult: []byte(`{
+				"code": "INVALID_REQUEST",
+				"message": "invalid expense",
+				"fields": [
+					{"field": "amount", "code": "invalid", "message": "must be greater than zero"},
+					{"field": "when", "code": "out_of_range", "message": "must be between 1900-01-01 and 1 year from now"},
+					{"field": "what", "code": "too_long", "message": "can't have more than 255 characters"}
+				]
+			}`),
+		},
+		"technical error without details": {
+			id:          "6",
+			callMock:    true,
+			sent:        []byte(`{"amount": "2.3"}`),
+			mockExpense: entity.Expense{Amount: 230},
+			wantStatus:  500,
+			wantResult:  []byte(`{"code": "INTERNAL_ERROR", "message": "internal error"}`),
+			mockErr:     fmt.Errorf("%w: connection refused", entity.ErrUnknown),
+		},
+		"field errors of the repository": {
+			id:          "7",
+			callMock:    true,
+			sent:        []byte(`{"amount": "2.3", "categoryId": "food"}`),
+			mockExpense: entity.Expense{Amount: 230, CategoryId: "food"},
+			wantStatus:  400,
+			wantResult: []byte(`{
+				"code": "INVALID_REQUEST",
+				"message": "invalid expense",
+				"fields": [{"field": "categoryId", "code": "not_found", "message": "reference not found"}]
+			}`),
+			mockErr: entity.NewFieldError(nil, "categoryId", "not_found", "reference not found"),
+		},
+		"business error": {
+			id:          "8",
+			callMock:    true,
+			sent:        []byte(`{"amount": "2.3"}`),
+			mockExpense: entity.Expense{Amount: 230},
+			wantStatus:  422,
+			wantResult:  []byte(`{"code": "UNPROCESSABLE", "message": "period closed"}`),
+			mockErr:     fmt.Errorf("%wperiod closed", entity.ErrBusiness),
+		},
+		"conflict without its constraint": {
+			id:          "9",
+			callMock:    true,
+			sent:        []byte(`{"amount": "2.3"}`),
+			mockExpense: entity.Expense{Amount: 230},
+			wantStatus:  409,
+			wantResult:  []byte(`{"code": "CONFLICT", "message": "expense is in use or already exists"}`),
+			mockErr:     fmt.Errorf("uq_expense_external_id: %w", entity.ErrConflict),
+		},
 	}
 
 	for name, tc := range tests {
@@ -268,14 +477,14 @@ func TestCreateExpense(t *testing.T) {
 			mockedRepo := new(mockExpenseRepo)
 			if tc.callMock {
 				mockedRepo.
-					On("Create", mock.Anything, tc.mockExpense).
+					On("Create", mock.Anything, withKind(tc.mockExpense, entity.EXPENSE)).
 					Return(tc.id, tc.mockErr)
 			}
 			ctx := context.Background()
 			ts := httptest.NewServer(createHandler(ctx, mockedRepo))
 			defer ts.Close()
 
-			res, err := http.Post(ts.URL+"/api/expense", "application/json", bytes.NewBuffer(tc.sent))
+			res, err := postJSON(ts.URL+"/api/expense", "application/json", bytes.NewBuffer(tc.sent))
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -322,7 +531,7 @@ func TestDeleteExpense(t *testing.T) {
 			id:         "2",
 			callMock:   true,
 			wantStatus: 500,
-			wantResult: []byte("{}"),
+			wantResult: []byte(`{"code": "INTERNAL_ERROR", "message": "internal error"}`),
 			mockErr:    errors.New("unknown error"),
 		},
 		"not found error": {
@@ -341,6 +550,9 @@ func TestDeleteExpense(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			mockedRepo := new(mockExpenseRepo)
 			if tc.callMock {
+				mockedRepo.
+					On("Get", mock.Anything, tc.id).
+					Return(entity.Expense{Id: tc.id}, nil)
 				mockedRepo.
 					On("Delete", mock.Anything, tc.id).
 					Return(tc.mockErr)
@@ -353,6 +565,7 @@ func TestDeleteExpense(t *testing.T) {
 			if err != nil {
 				t.Fatal(err)
 			}
+			req.Header.Set("Accept", "application/json")
 			res, err := http.DefaultClient.Do(req)
 			if err != nil {
 				t.Fatal(err)
@@ -377,3 +590,427 @@ func TestDeleteExpense(t *testing.T) {
 		})
 	}
 }
+
+// defaultPage is the page searched when no page param is sent
+var defaultPage = entity.PageRequest{Limit: entity.DefaultPageLimit, Sort: entity.Sort{Field: entity.SORT_WHEN}}
+
+func TestSearchExpense(t *testing.T) {
+	defaultCurrency := config.Config.DefaultCurrency
+	config.Config.DefaultCurrency = "BRL"
+	defer func() { config.Config.DefaultCurrency = defaultCurrency }()
+	when := time.Date(2021, 4, 22, 7, 20, 54, 0, time.UTC)
+	tests := map[string]struct {
+		query        string
+		wantFilter   *entity.ExpenseFilter
+		wantPage     *entity.PageRequest
+		mockExpenses []entity.Expense
+		mockPage     entity.Page
+		mockErr      error
+		wantResult   []byte
+		wantStatus   int
+		callMock     bool
+	}{
+		"success without filter": {
+			callMock:   true,
+			wantFilter: entity.MustNewExpenseFilter(entity.FilterKind(entity.EXPENSE)),
+			mockExpenses: []entity.Expense{
+				{Id: "1", Amount: 120, When: when, What: "coffee"},
+				{Id: "2", Amount: 230},
+			},
+			wantStatus: 200,
+			wantResult: []byte(`{"items": [
+				{"id": "1", "amount": "1.20", "when": "2021-04-22T07:20:54Z", "what": "coffee"},
+				{"id": "2", "amount": "2.30"}
+			]}`),
+		},
+		"success empty result": {
+			callMock:     true,
+			wantFilter:   entity.MustNewExpenseFilter(entity.FilterKind(entity.EXPENSE)),
+			mockExpenses: []entity.Expense{},
+			wantStatus:   200,
+			wantResult:   []byte(`{"items": []}`),
+		},
+		"success with filters": {
+			query:    "?amount[gte]=10.00&amount[lt]=20&currency=BRL&when[lt]=2021-05-01&what[regex]=coffee&tag=trip&createdAt[eq]=2021-04-22T07:20:54Z",
+			callMock: true,
+			wantFilter: entity.MustNewExpenseFilter(
+				entity.FilterKind(entity.EXPENSE),
+				entity.FilterAmountInt(entity.GE, 1000),
+				entity.FilterAmountInt(entity.LT, 2000),
+				entity.FilterCreatedAt(entity.EQ, when),
+				entity.FilterCurrency(entity.EQUALS, "BRL"),
+				entity.FilterTag(entity.EQUALS, "trip"),
+				entity.FilterWhat(entity.REGEX, "coffee"),
+				entity.FilterWhen(entity.LT, time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)),
+			),
+			mockExpenses: []entity.Expense{},
+			wantStatus:   200,
+			wantResult:   []byte(`{"items": []}`),
+		},
+		"success with amount in the currency filtered": {
+			query:    "?amount[gte]=1000&currency[eq]=JPY",
+			callMock: true,
+			wantFilter: entity.MustNewExpenseFilter(
+				entity.FilterKind(entity.EXPENSE),
+				entity.FilterAmountInt(entity.GE, 1000),
+				entity.FilterCurrency(entity.EQUALS, "JPY"),
+			),
+			mockExpenses: []entity.Expense{},
+			wantStatus:   200,
+			wantResult:   []byte(`{"items": []}`),
+		},
+		"success with amount in the default currency": {
+			query:    "?amount[gte]=10.00",
+			callMock: true,
+			wantFilter: entity.MustNewExpenseFilter(
+				entity.FilterKind(entity.EXPENSE),
+				entity.FilterCurrency(entity.EQUALS, "BRL"),
+				entity.FilterAmountInt(entity.GE, 1000),
+			),
+			mockExpenses: []entity.Expense{},
+			wantStatus:   200,
+			wantResult:   []byte(`{"items": []}`),
+		},
+		"bad request amount without a single currency": {
+			query:      "?amount[gte]=10.00&currency=BRL&currency[eq]=USD",
+			wantStatus: 400,
+			wantResult: []byte(`{
+				"code": "INVALID_FILTER",
+				"message": "invalid filter",
+				"fields": [
+					{"field": "currency", "code": "required", "message": "amount filters need a single currency, like currency=BRL"}
+				]
+			}`),
+		},
+		"success with metadata filters": {
+			query:    "?metadata.invoice[exists]=&metadata.project=home&metadata.client[regex]=^acme",
+			callMock: true,
+			wantFilter: entity.MustNewExpenseFilter(
+				entity.FilterKind(entity.EXPENSE),
+				entity.FilterMetadata(entity.REGEX, "client", "^acme"),
+				entity.FilterMetadataKey("invoice"),
+				entity.FilterMetadata(entity.EQUALS, "project", "home"),
+			),
+			mockExpenses: []entity.Expense{
+				{Id: "1", Amount: 120, Metadata: map[string]string{"invoice": "2021-042", "project": "home", "client": "acme"}},
+			},
+			wantStatus: 200,
+			wantResult: []byte(`{"items": [
+				{"id": "1", "amount": "1.20", "metadata": {"invoice": "2021-042", "project": "home", "client": "acme"}}
+			]}`),
+		},
+		"success with page": {
+			query:      "?what=coffee&limit=2&cursor=abc&sort=-amount&count=true",
+			callMock:   true,
+			wantFilter: entity.MustNewExpenseFilter(entity.FilterKind(entity.EXPENSE), entity.FilterWhat(entity.EQUALS, "coffee")),
+			wantPage: &entity.PageRequest{
+				Limit:  2,
+				Cursor: "abc",
+				Sort:   entity.Sort{Field: entity.SORT_AMOUNT, Desc: true},
+				Count:  true,
+			},
+			mockPage: entity.Page{
+				Expenses: []entity.Expense{{Id: "3", Amount: 500}, {Id: "1", Amount: 120}},
+				Next:     "next",
+				Prev:     "prev",
+				Total:    func(i int64) *int64 { return &i }(5),
+			},
+			wantStatus: 200,
+			wantResult: []byte(`{
+				"items": [{"id": "3", "amount": "5.00"}, {"id": "1", "amount": "1.20"}],
+				"next": "next",
+				"prev": "prev",
+				"total": 5
+			}`),
+		},
+		"bad request invalid page": {
+			query:      "?limit=ten&sort=color&count=maybe",
+			wantStatus: 400,
+			wantResult: []byte(`{
+				"code": "INVALID_FILTER",
+				"message": "invalid filter",
+				"fields": [
+					{"field": "limit", "code": "invalid", "message": "must be a number"},
+					{"field": "count", "code": "invalid", "message": "must be true or false"},
+					{"field": "sort", "code": "invalid", "message": "must be one of [when amount createdAt], with a minus to sort descending"}
+				]
+			}`),
+		},
+		"bad request limit too big": {
+			query:      "?limit=1001",
+			wantStatus: 400,
+			wantResult: []byte(`{
+				"code": "INVALID_FILTER",
+				"message": "invalid filter",
+				"fields": [{"field": "limit", "code": "invalid", "message": "must be between 1 and 1000"}]
+			}`),
+		},
+		"bad request invalid cursor": {
+			query:      "?cursor=abc",
+			callMock:   true,
+			wantFilter: entity.MustNewExpenseFilter(entity.FilterKind(entity.EXPENSE)),
+			wantPage:   &entity.PageRequest{Limit: entity.DefaultPageLimit, Cursor: "abc", Sort: entity.Sort{Field: entity.SORT_WHEN}},
+			mockErr:    entity.NewFieldError(nil, "cursor", "invalid", "must be the next or prev of a page with the same sort"),
+			wantStatus: 400,
+			wantResult: []byte(`{
+				"code": "INVALID_REQUEST",
+				"message": "invalid expense",
+				"fields": [{"field": "cursor", "code": "invalid", "message": "must be the next or prev of a page with the same sort"}]
+			}`),
+		},
+		"bad request invalid filters": {
+			query:      "?amount[gte]=ten&currency=BRL&color=red&what[gt]=a&when[lt]=yesterday",
+			wantStatus: 400,
+			wantResult: []byte(`{
+				"code": "INVALID_FILTER",
+				"message": "invalid filter",
+				"fields": [
+					{"field": "amount[gte]", "code": "invalid_value", "message": "invalid amount \"ten\""},
+					{"field": "color", "code": "unknown_field", "message": "unknown filter field \"color\""},
+					{"field": "what[gt]", "code": "invalid_value", "message": "invalid operator \"gt\""},
+					{"field": "when[lt]", "code": "invalid_value", "message": "invalid time \"yesterday\", use RFC3339 or YYYY-MM-DD"}
+				]
+			}`),
+		},
+		"unknown error": {
+			callMock:     true,
+			wantFilter:   entity.MustNewExpenseFilter(entity.FilterKind(entity.EXPENSE)),
+			mockExpenses: []entity.Expense{},
+			mockErr:      errors.New("unknown error"),
+			wantStatus:   500,
+			wantResult:   []byte(`{"code": "INTERNAL_ERROR", "message": "internal error"}`),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockedRepo := new(mockExpenseRepo)
+			if tc.callMock {
+				page, mockPage := defaultPage, tc.mockPage
+				if tc.wantPage != nil {
+					page = *tc.wantPage
+				}
+				if tc.mockExpenses != nil {
+					mockPage.Expenses = tc.mockExpenses
+				}
+				mockedRepo.
+					On("SearchPage", mock.Anything, tc.wantFilter, page).
+					Return(mockPage, tc.mockErr)
+			}
+			ctx := context.Background()
+			ts := httptest.NewServer(createHandler(ctx, mockedRepo))
+			defer ts.Close()
+
+			res, err := getJSON(ts.URL + "/api/expense" + tc.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tc.wantStatus, res.StatusCode)
+
+			got, err := io.ReadAll(res.Body)
+			res.Body.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, res.Header.Get("content-type"), "application/json")
+			mockedRepo.AssertExpectations(t)
+			assert.JSONEq(t, string(tc.wantResult), string(got))
+		})
+	}
+}
+
+func TestSaveExpense(t *testing.T) {
+	tests := map[string]struct {
+		method      string
+		id          string
+		sent        []byte
+		mockMethod  string
+		mockExpense entity.Expense
+		mockErr     error
+		stored      *entity.Expense
+		wantResult  []byte
+		wantStatus  int
+	}{
+		"success replace": {
+			method:     http.MethodPut,
+			id:         "1",
+			sent:       []byte(`{"amount": "1.20", "what": "my what"}`),
+			mockMethod: "Replace",
+			mockExpense: entity.Expense{
+				Id:     "1",
+				Kind:   entity.EXPENSE,
+				Amount: 120,
+				What:   "my what",
+			},
+			wantStatus: 204,
+		},
+		"success update ignoring body id": {
+			method:     http.MethodPatch,
+			id:         "2",
+			sent:       []byte(`{"id": "3", "who": "my who"}`),
+			mockMethod: "Update",
+			mockExpense: entity.Expense{
+				Id:  "2",
+				Who: "my who",
+			},
+			wantStatus: 204,
+		},
+		"success update amount with the stored currency": {
+			method:     http.MethodPatch,
+			id:         "6",
+			sent:       []byte(`{"amount": "1500"}`),
+			stored:     &entity.Expense{Id: "6", Amount: 900, Currency: "JPY"},
+			mockMethod: "Update",
+			mockExpense: entity.Expense{
+				Id:       "6",
+				Amount:   1500,
+				Currency: "JPY",
+			},
+			wantStatus: 204,
+		},
+		"success update currency with the amount": {
+			method:     http.MethodPatch,
+			id:         "9",
+			sent:       []byte(`{"amount": "1500", "currency": "JPY"}`),
+			stored:     &entity.Expense{Id: "9", Amount: 1234, Currency: "BRL"},
+			mockMethod: "Update",
+			mockExpense: entity.Expense{
+				Id:       "9",
+				Amount:   1500,
+				Currency: "JPY",
+			},
+			wantStatus: 204,
+		},
+		"bad request update currency without the amounts": {
+			method: http.MethodPatch,
+			id:     "10",
+			sent:   []byte(`{"currency": "JPY"}`),
+			stored: &entity.Expense{
+				Id:       "10",
+				Amount:   1234,
+				Currency: "BRL",
+				Split:    &entity.Split{Kind: entity.EXACT, Shares: []entity.Share{{Who: "ana", Amount: 1234}}},
+				Details:  []entity.Detail{{Description: "coffee", Quantity: entity.QuantityUnit, UnitPrice: 1234}},
+			},
+			wantStatus: 400,
+			wantResult: []byte(`{
+				"code": "INVALID_REQUEST",
+				"message": "invalid expense",
+				"fields": [
+					{"field": "amount", "code": "no_empty", "message": "must be sent to change the currency"},
+					{"field": "split", "code": "no_empty", "message": "must be sent to change the currency"},
+					{"field": "details", "code": "no_empty", "message": "must be sent to change the currency"}
+				]
+			}`),
+		},
+		"bad request amount precision": {
+			method:     http.MethodPatch,
+			id:         "7",
+			sent:       []byte(`{"amount": "15.5", "currency": "JPY"}`),
+			wantStatus: 400,
+			wantResult: []byte(`{
+				"code": "INVALID_REQUEST",
+				"message": "invalid expense",
+				"fields": [
+					{"field": "amount", "code": "invalid_precision", "message": "JPY has 0 decimal places"}
+				]
+			}`),
+		},
+		"bad request unknown currency": {
+			method:     http.MethodPut,
+			id:         "8",
+			sent:       []byte(`{"amount": "15.50", "currency": "XYZ"}`),
+			wantStatus: 400,
+			wantResult: []byte(`{
+				"code": "INVALID_REQUEST",
+				"message": "invalid expense",
+				"fields": [
+					{"field": "currency", "code": "invalid", "message": "unknown ISO 4217 currency \"XYZ\""}
+				]
+			}`),
+		},
+		"bad request": {
+			method:     http.MethodPatch,
+			id:         "3",
+			sent:       []byte(`{"invalid message"}`),
+			wantStatus: 400,
+			wantResult: []byte(`{
+				"code": "INVALID_REQUEST",
+				"message": "invalid json"
+			}`),
+		},
+		"replace not found": {
+			method:      http.MethodPut,
+			id:          "4",
+			sent:        []byte(`{"amount": "1.00"}`),
+			mockMethod:  "Replace",
+			mockExpense: entity.Expense{Id: "4", Kind: entity.EXPENSE, Amount: 100},
+			mockErr:     entity.ErrNotFound,
+			wantStatus:  404,
+			wantResult: []byte(`{
+				"code":     "NOT_FOUND",
+				"message": "expense not found"
+			}`),
+		},
+		"update not found": {
+			method:      http.MethodPatch,
+			id:          "5",
+			sent:        []byte(`{}`),
+			mockMethod:  "Update",
+			mockExpense: entity.Expense{Id: "5"},
+			mockErr:     entity.ErrNotFound,
+			wantStatus:  404,
+			wantResult: []byte(`{
+				"code":     "NOT_FOUND",
+				"message": "expense not found"
+			}`),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockedRepo := new(mockExpenseRepo)
+			if tc.stored == nil && tc.mockMethod != "" {
+				tc.stored = &entity.Expense{Id: tc.id, Amount: 100}
+			}
+			if tc.stored != nil {
+				mockedRepo.
+					On("Get", mock.Anything, tc.id).
+					Return(*tc.stored, nil)
+			}
+			if tc.mockMethod != "" {
+				mockedRepo.
+					On(tc.mockMethod, mock.Anything, tc.mockExpense).
+					Return(tc.mockErr)
+			}
+			ctx := context.Background()
+			ts := httptest.NewServer(createHandler(ctx, mockedRepo))
+			defer ts.Close()
+
+			req, err := http.NewRequest(tc.method, ts.URL+"/api/expense/"+tc.id, bytes.NewBuffer(tc.sent))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Accept", "application/json")
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tc.wantStatus, res.StatusCode)
+
+			got, err := io.ReadAll(res.Body)
+			res.Body.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			mockedRepo.AssertExpectations(t)
+			if len(tc.wantResult) == 0 {
+				assert.Empty(t, got)
+			} else {
+				assert.JSONEq(t, string(tc.wantResult), string(got))
+			}
+		})
+	}
+}
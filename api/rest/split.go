@@ -0,0 +1,224 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/axpira/backend/entity"
+	"github.com/axpira/backend/entity/config"
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+type ShareRest struct {
+	Who string `json:"who"`
+	// Percent is a decimal like "33.33", only on percent splits
+	Percent string      `json:"percent,omitempty"`
+	Amount  *amountRest `json:"amount,omitempty"`
+}
+
+type SplitRest struct {
+	Kind   string      `json:"kind"`
+	Shares []ShareRest `json:"shares"`
+}
+
+func (s SplitRest) ToSplit(currency entity.Currency) (entity.Split, error) {
+	var err error
+	split := entity.Split{
+		Kind:   entity.SplitKind(s.Kind),
+		Shares: make([]entity.Share, len(s.Shares)),
+	}
+	for i, share := range s.Shares {
+		field := fmt.Sprintf("split.shares[%d]", i)
+		split.Shares[i].Who = share.Who
+		if share.Percent != "" {
+			percent, percentErr := entity.ParsePercent(share.Percent)
+			err = entity.MergeFieldErrors(err, entity.RenameFieldError(percentErr, field+".percent"))
+			split.Shares[i].Percent = percent
+		}
+		if share.Amount != nil {
+			money, amountErr := entity.ParseMoney(share.Amount.raw, currency)
+			err = entity.MergeFieldErrors(err, entity.RenameFieldError(amountErr, field+".amount"))
+			split.Shares[i].Amount = money.Amount
+		}
+	}
+	if err != nil {
+		return entity.Split{}, err
+	}
+	return split, nil
+}
+
+func NewSplitRestFromSplit(s entity.Split, currency entity.Currency) *SplitRest {
+	res := &SplitRest{
+		Kind:   string(s.Kind),
+		Shares: make([]ShareRest, len(s.Shares)),
+	}
+	for i, share := range s.Shares {
+		res.Shares[i] = ShareRest{
+			Who:    share.Who,
+			Amount: NewAmountRest(entity.Money{Amount: share.Amount, Currency: currency}),
+		}
+		if share.Percent != 0 {
+			res.Shares[i].Percent = entity.FormatPercent(share.Percent)
+		}
+	}
+	return res
+}
+
+type BalanceRest struct {
+	Who      string      `json:"who"`
+	Amount   *amountRest `json:"amount"`
+	Currency string      `json:"currency"`
+}
+
+type DebtRest struct {
+	From     string      `json:"from"`
+	To       string      `json:"to"`
+	Amount   *amountRest `json:"amount"`
+	Currency string      `json:"currency"`
+}
+
+type BalancesRest struct {
+	Balances []BalanceRest `json:"balances"`
+	Debts    []DebtRest    `json:"debts"`
+}
+
+type SettleUpRest struct {
+	From     string      `json:"from"`
+	To       string      `json:"to"`
+	Amount   *amountRest `json:"amount"`
+	Currency string      `json:"currency,omitempty"`
+	When     *time.Time  `json:"when,omitempty"`
+}
+
+// ToSettlement returns the transaction that records the payment, Currency is the default one when empty
+func (s SettleUpRest) ToSettlement() (entity.Transaction, error) {
+	var err error
+	if s.From == "" {
+		err = entity.NewFieldError(err, "from", "no_empty", "can't be empty")
+	}
+	if s.To == "" {
+		err = entity.NewFieldError(err, "to", "no_empty", "can't be empty")
+	}
+	if s.From != "" && s.From == s.To {
+		err = entity.NewFieldError(err, "to", "same_person", "must be other than from")
+	}
+	if s.Amount == nil {
+		err = entity.NewFieldError(err, "amount", "no_empty", "can't be empty")
+	}
+	if err != nil {
+		return entity.Transaction{}, err
+	}
+	if s.Currency == "" {
+		s.Currency = config.Config.DefaultCurrency
+	}
+	currency, err := entity.ParseCurrency(s.Currency)
+	if err != nil {
+		return entity.Transaction{}, err
+	}
+	money, err := entity.ParseMoney(s.Amount.raw, currency)
+	if err != nil {
+		return entity.Transaction{}, err
+	}
+	if money.Amount <= 0 {
+		return entity.Transaction{}, entity.NewFieldError(nil, "amount", "invalid", "must be greater than zero")
+	}
+	settlement := entity.NewSettlement(s.From, s.To, money)
+	settlement.When = time.Now().UTC()
+	if s.When != nil {
+		settlement.When = s.When.UTC()
+	}
+	return settlement, nil
+}
+
+// balanceRoutes sums every split transaction, settlements are created on the settlement view of repo
+func balanceRoutes(repo ExpenseRepository, settlements ExpenseRepository) func(r chi.Router) {
+	return func(r chi.Router) {
+		r.Get("/", getBalances(repo))
+		r.Post("/settle", settleUp(settlements))
+	}
+}
+
+func getBalances(repo ExpenseRepository) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		filter, err := parseExpenseFilter(r.URL.Query())
+		if err == nil {
+			err = entity.FilterSplit()(filter)
+		}
+		if err != nil {
+			log.Ctx(ctx).Err(err).Msg("error on parse filter")
+			fillHttpError(w,
+				NewHttpError(http.StatusBadRequest, "",
+					NewError("INVALID_FILTER", "invalid filter").WithFields(err),
+				),
+			)
+			return
+		}
+		transactions, err := repo.Search(ctx, filter)
+		if validateResourceError(w, "balance", err) {
+			log.Ctx(ctx).Err(err).Msg("error on search")
+			return
+		}
+		balances := entity.Balances(transactions)
+		debts := entity.Debts(balances)
+		res := BalancesRest{
+			Balances: make([]BalanceRest, len(balances)),
+			Debts:    make([]DebtRest, len(debts)),
+		}
+		for i, b := range balances {
+			res.Balances[i] = BalanceRest{
+				Who:      b.Who,
+				Amount:   &amountRest{money: entity.Money{Amount: b.Amount, Currency: b.Currency}},
+				Currency: string(b.Currency),
+			}
+		}
+		for i, d := range debts {
+			res.Debts[i] = DebtRest{
+				From:     d.From,
+				To:       d.To,
+				Amount:   &amountRest{money: entity.Money{Amount: d.Amount, Currency: d.Currency}},
+				Currency: string(d.Currency),
+			}
+		}
+		err = json.NewEncoder(w).Encode(res)
+		if validateResourceError(w, "balance", err) {
+			return
+		}
+	}
+}
+
+func settleUp(repo ExpenseRepository) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		settleUp := new(SettleUpRest)
+		err := json.NewDecoder(r.Body).Decode(settleUp)
+		if err != nil {
+			log.Ctx(ctx).Err(err).Msg("error on decode")
+			fillHttpError(w,
+				NewHttpError(http.StatusBadRequest, "",
+					NewError("INVALID_REQUEST", "invalid json"),
+				),
+			)
+			return
+		}
+		settlement, err := settleUp.ToSettlement()
+		if err != nil {
+			log.Ctx(ctx).Err(err).Msg("error on validate")
+			fillHttpError(w,
+				NewHttpError(http.StatusBadRequest, "",
+					NewError("INVALID_REQUEST", "invalid settlement").WithFields(err),
+				),
+			)
+			return
+		}
+		id, err := repo.Create(ctx, settlement)
+		if validateResourceError(w, "settlement", err) {
+			log.Ctx(ctx).Err(err).Msg("error on settle up")
+			return
+		}
+		w.Write([]byte(`{"id":"` + id + `"}`))
+	}
+}
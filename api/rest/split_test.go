@@ -0,0 +1,216 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/axpira/backend/entity"
+	"github.com/axpira/backend/entity/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSplit(t *testing.T) {
+	defaultCurrency := config.Config.DefaultCurrency
+	config.Config.DefaultCurrency = "BRL"
+	defer func() { config.Config.DefaultCurrency = defaultCurrency }()
+
+	when := time.Date(2021, 5, 3, 10, 0, 0, 0, time.UTC)
+	tests := map[string]struct {
+		method     string
+		path       string
+		sent       []byte
+		setup      func(*mockExpenseRepo)
+		wantResult []byte
+		wantStatus int
+	}{
+		"create split": {
+			method: http.MethodPost,
+			path:   "/api/expense",
+			sent:   []byte(`{"amount": "100.00", "who": "ana", "split": {"kind": "equal", "shares": [{"who": "ana"}, {"who": "bob"}, {"who": "carl"}]}}`),
+			setup: func(m *mockExpenseRepo) {
+				m.On("Create", mock.Anything, entity.Expense{
+					Kind:     entity.EXPENSE,
+					Amount:   10000,
+					Currency: "BRL",
+					Who:      "ana",
+					Split: &entity.Split{Kind: entity.EQUAL, Shares: []entity.Share{
+						{Who: "ana", Amount: 3334}, {Who: "bob", Amount: 3333}, {Who: "carl", Amount: 3333},
+					}},
+				}).Return("1", nil)
+			},
+			wantStatus: 200,
+			wantResult: []byte(`{"id": "1"}`),
+		},
+		"create split without who paid": {
+			method:     http.MethodPost,
+			path:       "/api/expense",
+			sent:       []byte(`{"amount": "100.00", "split": {"kind": "equal", "shares": [{"who": "bob"}]}}`),
+			wantStatus: 400,
+			wantResult: []byte(`{
+				"code": "INVALID_REQUEST",
+				"message": "invalid expense",
+				"fields": [
+					{"field": "who", "code": "no_empty", "message": "can't be empty on a split, it's who paid"}
+				]
+			}`),
+		},
+		"create invalid split": {
+			method:     http.MethodPost,
+			path:       "/api/expense",
+			sent:       []byte(`{"amount": "100.00", "who": "ana", "split": {"kind": "percent", "shares": [{"who": "ana", "percent": "33.333"}, {"who": "bob", "amount": "1.001"}]}}`),
+			wantStatus: 400,
+			wantResult: []byte(`{
+				"code": "INVALID_REQUEST",
+				"message": "invalid expense",
+				"fields": [
+					{"field": "split.shares[0].percent", "code": "invalid", "message": "\"33.333\" is not a percent with up to 2 decimal places"},
+					{"field": "split.shares[1].amount", "code": "invalid_precision", "message": "BRL has 2 decimal places"}
+				]
+			}`),
+		},
+		"get split": {
+			method: http.MethodGet,
+			path:   "/api/expense/1",
+			setup: func(m *mockExpenseRepo) {
+				m.On("Get", mock.Anything, "1").Return(entity.Expense{
+					Id:       "1",
+					Kind:     entity.EXPENSE,
+					Amount:   10000,
+					Currency: "BRL",
+					Who:      "ana",
+					Split: &entity.Split{Kind: entity.PERCENT, Shares: []entity.Share{
+						{Who: "ana", Percent: 7000, Amount: 7000}, {Who: "bob", Percent: 3000, Amount: 3000},
+					}},
+				}, nil)
+			},
+			wantStatus: 200,
+			wantResult: []byte(`{
+				"id": "1",
+				"amount": "100.00",
+				"currency": "BRL",
+				"who": "ana",
+				"split": {"kind": "percent", "shares": [
+					{"who": "ana", "percent": "70.00", "amount": "70.00"},
+					{"who": "bob", "percent": "30.00", "amount": "30.00"}
+				]}
+			}`),
+		},
+		"update amount allocates the stored split again": {
+			method: http.MethodPatch,
+			path:   "/api/expense/1",
+			sent:   []byte(`{"amount": "10.00"}`),
+			setup: func(m *mockExpenseRepo) {
+				m.On("Get", mock.Anything, "1").Return(entity.Expense{
+					Id:       "1",
+					Kind:     entity.EXPENSE,
+					Amount:   10000,
+					Currency: "BRL",
+					Who:      "ana",
+					Split: &entity.Split{Kind: entity.EQUAL, Shares: []entity.Share{
+						{Who: "ana", Amount: 3334}, {Who: "bob", Amount: 3333}, {Who: "carl", Amount: 3333},
+					}},
+				}, nil)
+				m.On("Update", mock.Anything, entity.Expense{
+					Id:       "1",
+					Amount:   1000,
+					Currency: "BRL",
+					Split: &entity.Split{Kind: entity.EQUAL, Shares: []entity.Share{
+						{Who: "ana", Amount: 334}, {Who: "bob", Amount: 333}, {Who: "carl", Amount: 333},
+					}},
+				}).Return(nil)
+			},
+			wantStatus: 204,
+		},
+		"balances": {
+			method: http.MethodGet,
+			path:   "/api/balance?currency=BRL",
+			setup: func(m *mockExpenseRepo) {
+				m.On("Search", mock.Anything, entity.MustNewExpenseFilter(
+					entity.FilterCurrency(entity.EQUALS, "BRL"),
+					entity.FilterSplit(),
+				)).Return([]entity.Expense{
+					{Who: "ana", Amount: 900, Currency: "BRL", Split: &entity.Split{Kind: entity.EQUAL, Shares: []entity.Share{
+						{Who: "ana", Amount: 300}, {Who: "bob", Amount: 300}, {Who: "carl", Amount: 300},
+					}}},
+				}, nil)
+			},
+			wantStatus: 200,
+			wantResult: []byte(`{
+				"balances": [
+					{"who": "ana", "amount": "6.00", "currency": "BRL"},
+					{"who": "bob", "amount": "-3.00", "currency": "BRL"},
+					{"who": "carl", "amount": "-3.00", "currency": "BRL"}
+				],
+				"debts": [
+					{"from": "bob", "to": "ana", "amount": "3.00", "currency": "BRL"},
+					{"from": "carl", "to": "ana", "amount": "3.00", "currency": "BRL"}
+				]
+			}`),
+		},
+		"settle up": {
+			method: http.MethodPost,
+			path:   "/api/balance/settle",
+			sent:   []byte(`{"from": "bob", "to": "ana", "amount": "3.00", "when": "2021-05-03T10:00:00Z"}`),
+			setup: func(m *mockExpenseRepo) {
+				settlement := entity.NewSettlement("bob", "ana", entity.Money{Amount: 300, Currency: "BRL"})
+				settlement.When = when
+				m.On("Create", mock.Anything, settlement).Return("2", nil)
+			},
+			wantStatus: 200,
+			wantResult: []byte(`{"id": "2"}`),
+		},
+		"settle up with yourself": {
+			method:     http.MethodPost,
+			path:       "/api/balance/settle",
+			sent:       []byte(`{"from": "bob", "to": "bob", "amount": "3.00"}`),
+			wantStatus: 400,
+			wantResult: []byte(`{
+				"code": "INVALID_REQUEST",
+				"message": "invalid settlement",
+				"fields": [
+					{"field": "to", "code": "same_person", "message": "must be other than from"}
+				]
+			}`),
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockedRepo := new(mockExpenseRepo)
+			if tc.setup != nil {
+				tc.setup(mockedRepo)
+			}
+			ts := httptest.NewServer(createHandler(context.Background(), mockedRepo))
+			defer ts.Close()
+
+			req, err := http.NewRequest(tc.method, ts.URL+tc.path, bytes.NewBuffer(tc.sent))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Accept", "application/json")
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tc.wantStatus, res.StatusCode)
+
+			got, err := io.ReadAll(res.Body)
+			res.Body.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			mockedRepo.AssertExpectations(t)
+			if tc.wantResult != nil {
+				assert.JSONEq(t, string(tc.wantResult), string(got))
+			} else {
+				assert.Empty(t, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/axpira/backend/entity"
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+type TagsRest struct {
+	Tags []string `json:"tags"`
+}
+
+type UpdatedRest struct {
+	Updated int64 `json:"updated"`
+}
+
+func decodeTags(w http.ResponseWriter, r *http.Request) (*TagsRest, bool) {
+	ctx := r.Context()
+	tags := new(TagsRest)
+	err := json.NewDecoder(r.Body).Decode(tags)
+	if err != nil {
+		log.Ctx(ctx).Err(err).Msg("error on decode")
+		fillHttpError(w,
+			NewHttpError(http.StatusBadRequest, "",
+				NewError("INVALID_REQUEST", "invalid json"),
+			),
+		)
+		return nil, false
+	}
+	return tags, true
+}
+
+func invalidTags(w http.ResponseWriter, err error) {
+	fillHttpError(w,
+		NewHttpError(http.StatusBadRequest, "",
+			NewError("INVALID_REQUEST", "invalid tags").WithFields(err),
+		),
+	)
+}
+
+// updateExpenseTags changes the tags of one expense with UpdateTags, so concurrent changes
+// are applied on the stored tags instead of overwriting each other
+func updateExpenseTags(repo ExpenseRepository, action entity.TagAction) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		tagsRest, ok := decodeTags(w, r)
+		if !ok {
+			return
+		}
+		tags, err := entity.NewTags(tagsRest.Tags...)
+		if err != nil {
+			log.Ctx(ctx).Err(err).Msg("error on validate")
+			invalidTags(w, err)
+			return
+		}
+		expenseID := chi.URLParam(r, "expenseID")
+		filter, err := entity.NewExpenseFilter(entity.FilterId(entity.EQUALS, expenseID))
+		if validateError(w, err) {
+			return
+		}
+		_, err = repo.UpdateTags(ctx, filter, action, tags)
+		if validateError(w, err) {
+			log.Ctx(ctx).Err(err).Msg("error on update tags")
+			return
+		}
+		expense, err := repo.Get(ctx, expenseID)
+		if validateError(w, err) {
+			log.Ctx(ctx).Err(err).Msg("error on consult")
+			return
+		}
+		res := TagsRest{Tags: []string{}}
+		if expense.Tags != nil {
+			res.Tags = expense.Tags.Value()
+		}
+		err = json.NewEncoder(w).Encode(res)
+		if validateError(w, err) {
+			return
+		}
+	}
+}
+
+func bulkUpdateExpenseTags(repo ExpenseRepository, action entity.TagAction) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if len(r.URL.Query()) == 0 {
+			fillHttpError(w,
+				NewHttpError(http.StatusBadRequest, "",
+					NewError("FILTER_REQUIRED", "at least one filter is required"),
+				),
+			)
+			return
+		}
+		filter, err := parseExpenseFilter(r.URL.Query())
+		if err != nil {
+			log.Ctx(ctx).Err(err).Msg("error on parse filter")
+			fillHttpError(w,
+				NewHttpError(http.StatusBadRequest, "",
+					NewError("INVALID_FILTER", "invalid filter").WithFields(err),
+				),
+			)
+			return
+		}
+		tagsRest, ok := decodeTags(w, r)
+		if !ok {
+			return
+		}
+		tags, err := entity.NewTags(tagsRest.Tags...)
+		if err != nil {
+			log.Ctx(ctx).Err(err).Msg("error on validate")
+			invalidTags(w, err)
+			return
+		}
+		updated, err := repo.UpdateTags(ctx, filter, action, tags)
+		if validateError(w, err) {
+			log.Ctx(ctx).Err(err).Msg("error on update tags")
+			return
+		}
+		err = json.NewEncoder(w).Encode(UpdatedRest{Updated: updated})
+		if validateError(w, err) {
+			return
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/axpira/backend/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestUpdateExpenseTags(t *testing.T) {
+	byId := entity.MustNewExpenseFilter(entity.FilterId(entity.EQUALS, "1"), entity.FilterKind(entity.EXPENSE))
+	tests := map[string]struct {
+		method      string
+		sent        []byte
+		wantAction  entity.TagAction
+		wantTags    *entity.Tags
+		mockErr     error
+		mockExpense entity.Expense
+		mockGetErr  error
+		wantResult  []byte
+		wantStatus  int
+	}{
+		"add tags": {
+			method:      http.MethodPost,
+			sent:        []byte(`{"tags": ["work", "trip"]}`),
+			wantAction:  entity.ADD,
+			wantTags:    entity.MustNewTags("work", "trip"),
+			mockExpense: entity.Expense{Id: "1", Amount: 120, Tags: entity.MustNewTags("trip", "work")},
+			wantStatus:  200,
+			wantResult:  []byte(`{"tags": ["trip", "work"]}`),
+		},
+		"set tags": {
+			method:      http.MethodPut,
+			sent:        []byte(`{"tags": ["work"]}`),
+			wantAction:  entity.SET,
+			wantTags:    entity.MustNewTags("work"),
+			mockExpense: entity.Expense{Id: "1", Amount: 120, Tags: entity.MustNewTags("work")},
+			wantStatus:  200,
+			wantResult:  []byte(`{"tags": ["work"]}`),
+		},
+		"delete the last tag": {
+			method:      http.MethodDelete,
+			sent:        []byte(`{"tags": ["trip"]}`),
+			wantAction:  entity.DEL,
+			wantTags:    entity.MustNewTags("trip"),
+			mockExpense: entity.Expense{Id: "1", Amount: 120},
+			wantStatus:  200,
+			wantResult:  []byte(`{"tags": []}`),
+		},
+		"invalid tags": {
+			method:     http.MethodPost,
+			sent:       []byte(`{"tags": ["my trip"]}`),
+			wantStatus: 400,
+			wantResult: []byte(`{
+				"code": "INVALID_REQUEST",
+				"message": "invalid tags",
+				"fields": [{"field": "tag", "code": "no_space", "message": "can't have spaces"}]
+			}`),
+		},
+		"expense not found": {
+			method:     http.MethodPost,
+			sent:       []byte(`{"tags": ["trip"]}`),
+			wantAction: entity.ADD,
+			wantTags:   entity.MustNewTags("trip"),
+			mockGetErr: entity.ErrNotFound,
+			wantStatus: 404,
+			wantResult: []byte(`{
+				"code":     "NOT_FOUND",
+				"message": "expense not found"
+			}`),
+		},
+		"unknown error": {
+			method:     http.MethodPost,
+			sent:       []byte(`{"tags": ["trip"]}`),
+			wantAction: entity.ADD,
+			wantTags:   entity.MustNewTags("trip"),
+			mockErr:    errors.New("unknown error"),
+			wantStatus: 500,
+			wantResult: []byte(`{"code": "INTERNAL_ERROR", "message": "internal error"}`),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockedRepo := new(mockExpenseRepo)
+			if tc.wantTags != nil {
+				mockedRepo.
+					On("UpdateTags", mock.Anything, byId, tc.wantAction, tc.wantTags).
+					Return(int64(1), tc.mockErr)
+			}
+			if tc.wantTags != nil && tc.mockErr == nil {
+				mockedRepo.
+					On("Get", mock.Anything, "1").
+					Return(tc.mockExpense, tc.mockGetErr)
+			}
+			ts := httptest.NewServer(createHandler(context.Background(), mockedRepo))
+			defer ts.Close()
+
+			req, err := http.NewRequest(tc.method, ts.URL+"/api/expense/1/tags", bytes.NewBuffer(tc.sent))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Accept", "application/json")
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tc.wantStatus, res.StatusCode)
+
+			got, err := io.ReadAll(res.Body)
+			res.Body.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			mockedRepo.AssertExpectations(t)
+			assert.JSONEq(t, string(tc.wantResult), string(got))
+		})
+	}
+}
+
+func TestBulkUpdateExpenseTags(t *testing.T) {
+	tests := map[string]struct {
+		method     string
+		query      string
+		sent       []byte
+		wantFilter *entity.ExpenseFilter
+		wantAction entity.TagAction
+		wantTags   *entity.Tags
+		mockResult int64
+		mockErr    error
+		wantResult []byte
+		wantStatus int
+		callMock   bool
+	}{
+		"add tag to search result": {
+			method:     http.MethodPost,
+			query:      "?what[regex]=taxi",
+			sent:       []byte(`{"tags": ["reimbursable"]}`),
+			callMock:   true,
+			wantFilter: entity.MustNewExpenseFilter(entity.FilterKind(entity.EXPENSE), entity.FilterWhat(entity.REGEX, "taxi")),
+			wantAction: entity.ADD,
+			wantTags:   entity.MustNewTags("reimbursable"),
+			mockResult: 3,
+			wantStatus: 200,
+			wantResult: []byte(`{"updated": 3}`),
+		},
+		"set tags": {
+			method:     http.MethodPut,
+			query:      "?tag=trip",
+			sent:       []byte(`{"tags": []}`),
+			callMock:   true,
+			wantFilter: entity.MustNewExpenseFilter(entity.FilterKind(entity.EXPENSE), entity.FilterTag(entity.EQUALS, "trip")),
+			wantAction: entity.SET,
+			wantTags:   entity.MustNewTags(),
+			mockResult: 1,
+			wantStatus: 200,
+			wantResult: []byte(`{"updated": 1}`),
+		},
+		"delete tags": {
+			method:     http.MethodDelete,
+			query:      "?tag=trip",
+			sent:       []byte(`{"tags": ["trip"]}`),
+			callMock:   true,
+			wantFilter: entity.MustNewExpenseFilter(entity.FilterKind(entity.EXPENSE), entity.FilterTag(entity.EQUALS, "trip")),
+			wantAction: entity.DEL,
+			wantTags:   entity.MustNewTags("trip"),
+			wantStatus: 200,
+			wantResult: []byte(`{"updated": 0}`),
+		},
+		"filter required": {
+			method:     http.MethodPost,
+			sent:       []byte(`{"tags": ["reimbursable"]}`),
+			wantStatus: 400,
+			wantResult: []byte(`{
+				"code": "FILTER_REQUIRED",
+				"message": "at least one filter is required"
+			}`),
+		},
+		"invalid tags": {
+			method:     http.MethodPost,
+			query:      "?tag=trip",
+			sent:       []byte(`{"tags": [""]}`),
+			wantStatus: 400,
+			wantResult: []byte(`{
+				"code": "INVALID_REQUEST",
+				"message": "invalid tags",
+				"fields": [{"field": "tag", "code": "no_empty", "message": "can't be empty"}]
+			}`),
+		},
+		"unknown error": {
+			method:     http.MethodPost,
+			query:      "?tag=trip",
+			sent:       []byte(`{"tags": ["trip"]}`),
+			callMock:   true,
+			wantFilter: entity.MustNewExpenseFilter(entity.FilterKind(entity.EXPENSE), entity.FilterTag(entity.EQUALS, "trip")),
+			wantAction: entity.ADD,
+			wantTags:   entity.MustNewTags("trip"),
+			mockErr:    errors.New("unknown error"),
+			wantStatus: 500,
+			wantResult: []byte(`{"code": "INTERNAL_ERROR", "message": "internal error"}`),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockedRepo := new(mockExpenseRepo)
+			if tc.callMock {
+				mockedRepo.
+					On("UpdateTags", mock.Anything, tc.wantFilter, tc.wantAction, tc.wantTags).
+					Return(tc.mockResult, tc.mockErr)
+			}
+			ts := httptest.NewServer(createHandler(context.Background(), mockedRepo))
+			defer ts.Close()
+
+			req, err := http.NewRequest(tc.method, ts.URL+"/api/expense/tags"+tc.query, bytes.NewBuffer(tc.sent))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Accept", "application/json")
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tc.wantStatus, res.StatusCode)
+
+			got, err := io.ReadAll(res.Body)
+			res.Body.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			mockedRepo.AssertExpectations(t)
+			assert.JSONEq(t, string(tc.wantResult), string(got))
+		})
+	}
+}
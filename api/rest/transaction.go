@@ -0,0 +1,195 @@
+package rest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/axpira/backend/entity"
+	"github.com/axpira/backend/entity/config"
+	"github.com/go-chi/chi/v5"
+)
+
+// kindRepository restricts an ExpenseRepository to the transactions of one kind,
+// so /api/expense, /api/income and /api/transfer share the same handlers
+type kindRepository struct {
+	ExpenseRepository
+	kind entity.TransactionKind
+}
+
+func newKindRepository(repo ExpenseRepository, kind entity.TransactionKind) kindRepository {
+	return kindRepository{
+		ExpenseRepository: repo,
+		kind:              kind,
+	}
+}
+
+func (r kindRepository) Create(ctx context.Context, expense entity.Expense) (string, error) {
+	expense.Kind = r.kind
+	if err := expense.AllocateSplit(); err != nil {
+		return "", err
+	}
+	if err := expense.Validate(); err != nil {
+		return "", err
+	}
+	return r.ExpenseRepository.Create(ctx, expense)
+}
+
+func (r kindRepository) Get(ctx context.Context, id string) (entity.Expense, error) {
+	expense, err := r.ExpenseRepository.Get(ctx, id)
+	if err != nil {
+		return entity.Expense{}, err
+	}
+	if expense.KindOrDefault() != r.kind {
+		return entity.Expense{}, fmt.Errorf("%s %s was %w", r.kind, id, entity.ErrNotFound)
+	}
+	return expense, nil
+}
+
+// Update never changes the kind, the update is validated merged with the stored transaction.
+// A new amount allocates the stored split again
+func (r kindRepository) Update(ctx context.Context, expense entity.Expense) error {
+	stored, err := r.Get(ctx, expense.Id)
+	if err != nil {
+		return err
+	}
+	if expense.PaymentMethodId != "" {
+		stored.PaymentMethodId = expense.PaymentMethodId
+	}
+	if expense.ToPaymentMethodId != "" {
+		stored.ToPaymentMethodId = expense.ToPaymentMethodId
+	}
+	if expense.Amount != 0 {
+		stored.Amount = expense.Amount
+	}
+	if expense.Who != "" {
+		stored.Who = expense.Who
+	}
+	if expense.Split != nil {
+		stored.Split = expense.Split
+	}
+	if expense.Details != nil {
+		stored.Details = expense.Details
+	}
+	if !expense.When.IsZero() {
+		stored.When = expense.When
+	}
+	if expense.Currency != "" && expense.Currency != stored.Currency {
+		if err := currencyChangeError(stored, expense); err != nil {
+			return err
+		}
+		stored.Currency = expense.Currency
+	}
+	for _, s := range []struct{ from, to *string }{
+		{&expense.Where, &stored.Where},
+		{&expense.What, &stored.What},
+		{&expense.CategoryId, &stored.CategoryId},
+		{&expense.PlaceId, &stored.PlaceId},
+		{&expense.PersonId, &stored.PersonId},
+	} {
+		if *s.from != "" {
+			*s.to = *s.from
+		}
+	}
+	if err := stored.AllocateSplit(); err != nil {
+		return err
+	}
+	if err := stored.Validate(); err != nil {
+		return err
+	}
+	if expense.Split != nil || expense.Amount != 0 {
+		expense.Split = stored.Split
+	}
+	expense.Kind = ""
+	return r.ExpenseRepository.Update(ctx, expense)
+}
+
+// currencyChangeError asks for the amounts stored in the minor units of the previous currency,
+// an update can't keep them with another currency
+func currencyChangeError(stored, expense entity.Expense) error {
+	var err error
+	if expense.Amount == 0 {
+		err = entity.NewFieldError(err, "amount", "no_empty", "must be sent to change the currency")
+	}
+	if stored.Split != nil && stored.Split.Kind == entity.EXACT && expense.Split == nil {
+		err = entity.NewFieldError(err, "split", "no_empty", "must be sent to change the currency")
+	}
+	if len(stored.Details) > 0 && expense.Details == nil {
+		err = entity.NewFieldError(err, "details", "no_empty", "must be sent to change the currency")
+	}
+	return err
+}
+
+func (r kindRepository) Replace(ctx context.Context, expense entity.Expense) error {
+	if _, err := r.Get(ctx, expense.Id); err != nil {
+		return err
+	}
+	expense.Kind = r.kind
+	if err := expense.AllocateSplit(); err != nil {
+		return err
+	}
+	if err := expense.Validate(); err != nil {
+		return err
+	}
+	return r.ExpenseRepository.Replace(ctx, expense)
+}
+
+func (r kindRepository) Delete(ctx context.Context, id string) error {
+	if _, err := r.Get(ctx, id); err != nil {
+		return err
+	}
+	return r.ExpenseRepository.Delete(ctx, id)
+}
+
+func (r kindRepository) Search(ctx context.Context, filter *entity.ExpenseFilter) ([]entity.Expense, error) {
+	return r.ExpenseRepository.Search(ctx, r.filter(filter))
+}
+
+func (r kindRepository) SearchPage(ctx context.Context, filter *entity.ExpenseFilter, page entity.PageRequest) (entity.Page, error) {
+	return r.ExpenseRepository.SearchPage(ctx, r.filter(filter), page)
+}
+
+func (r kindRepository) UpdateTags(ctx context.Context, filter *entity.ExpenseFilter, action entity.TagAction, tags *entity.Tags) (int64, error) {
+	return r.ExpenseRepository.UpdateTags(ctx, r.filter(filter), action, tags)
+}
+
+// filter returns a copy of filter restricted to the kind
+func (r kindRepository) filter(filter *entity.ExpenseFilter) *entity.ExpenseFilter {
+	f := entity.ExpenseFilter{}
+	if filter != nil {
+		f = *filter
+	}
+	f.Kind = append(f.Kind[:len(f.Kind):len(f.Kind)], r.kind)
+	return &f
+}
+
+func transactionRoutes(repo ExpenseRepository, o options) func(r chi.Router) {
+	return func(r chi.Router) {
+		var create []func(http.Handler) http.Handler
+		if o.idempotencyRepo != nil {
+			create = append(create, Idempotent(o.idempotencyRepo, o.idempotencyWindow))
+		}
+		r.With(create...).Post("/", createExpense(repo))
+		r.Get("/", searchExpense(repo))
+		r.Route("/tags", func(r chi.Router) {
+			r.Put("/", bulkUpdateExpenseTags(repo, entity.SET))
+			r.Post("/", bulkUpdateExpenseTags(repo, entity.ADD))
+			r.Delete("/", bulkUpdateExpenseTags(repo, entity.DEL))
+		})
+		r.Route("/{expenseID}", func(r chi.Router) {
+			r.Get("/", getExpense(repo))
+			r.Put("/", replaceExpense(repo))
+			r.Patch("/", updateExpense(repo))
+			r.Delete("/", deleteExpense(repo))
+			r.Route("/tags", func(r chi.Router) {
+				r.Put("/", updateExpenseTags(repo, entity.SET))
+				r.Post("/", updateExpenseTags(repo, entity.ADD))
+				r.Delete("/", updateExpenseTags(repo, entity.DEL))
+			})
+			if o.exchangeRateRepo != nil {
+				converter := entity.NewConverter(entity.Currency(config.Config.BaseCurrency), o.exchangeRateRepo)
+				r.Get("/converted", convertExpense(repo, converter))
+			}
+		})
+	}
+}
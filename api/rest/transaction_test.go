@@ -0,0 +1,172 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/axpira/backend/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestTransactionKinds(t *testing.T) {
+	tests := map[string]struct {
+		method     string
+		path       string
+		sent       []byte
+		setup      func(*mockExpenseRepo)
+		wantResult []byte
+		wantStatus int
+	}{
+		"create income": {
+			method: http.MethodPost,
+			path:   "/api/income",
+			sent:   []byte(`{"amount": "1000.00", "what": "salary"}`),
+			setup: func(m *mockExpenseRepo) {
+				m.On("Create", mock.Anything, entity.Expense{Kind: entity.INCOME, Amount: 100000, What: "salary"}).Return("1", nil)
+			},
+			wantStatus: 200,
+			wantResult: []byte(`{"id": "1"}`),
+		},
+		"create transfer": {
+			method: http.MethodPost,
+			path:   "/api/transfer",
+			sent:   []byte(`{"amount": "50.00", "paymentMethodId": "checking", "toPaymentMethodId": "savings"}`),
+			setup: func(m *mockExpenseRepo) {
+				m.On("Create", mock.Anything, entity.Expense{
+					Kind:              entity.TRANSFER,
+					Amount:            5000,
+					PaymentMethodId:   "checking",
+					ToPaymentMethodId: "savings",
+				}).Return("2", nil)
+			},
+			wantStatus: 200,
+			wantResult: []byte(`{"id": "2"}`),
+		},
+		"create transfer without destination": {
+			method:     http.MethodPost,
+			path:       "/api/transfer",
+			sent:       []byte(`{"amount": "50.00", "paymentMethodId": "checking"}`),
+			wantStatus: 400,
+			wantResult: []byte(`{
+				"code": "INVALID_REQUEST",
+				"message": "invalid expense",
+				"fields": [
+					{"field": "toPaymentMethodId", "code": "no_empty", "message": "can't be empty on a transfer"}
+				]
+			}`),
+		},
+		"expense with destination": {
+			method:     http.MethodPost,
+			path:       "/api/expense",
+			sent:       []byte(`{"amount": "50.00", "toPaymentMethodId": "savings"}`),
+			wantStatus: 400,
+			wantResult: []byte(`{
+				"code": "INVALID_REQUEST",
+				"message": "invalid expense",
+				"fields": [
+					{"field": "toPaymentMethodId", "code": "not_allowed", "message": "only transfers have a destination"}
+				]
+			}`),
+		},
+		"income with split": {
+			method:     http.MethodPost,
+			path:       "/api/income",
+			sent:       []byte(`{"amount": "50.00", "who": "ana", "split": {"kind": "equal", "shares": [{"who": "ana"}, {"who": "bob"}]}}`),
+			wantStatus: 400,
+			wantResult: []byte(`{
+				"code": "INVALID_REQUEST",
+				"message": "invalid expense",
+				"fields": [
+					{"field": "split", "code": "not_allowed", "message": "only expenses and settlements are split"}
+				]
+			}`),
+		},
+		"get transfer on the expense view": {
+			method: http.MethodGet,
+			path:   "/api/expense/3",
+			setup: func(m *mockExpenseRepo) {
+				m.On("Get", mock.Anything, "3").Return(entity.Expense{Id: "3", Kind: entity.TRANSFER}, nil)
+			},
+			wantStatus: 404,
+			wantResult: []byte(`{"code": "NOT_FOUND", "message": "expense not found"}`),
+		},
+		"delete income on the expense view": {
+			method: http.MethodDelete,
+			path:   "/api/expense/4",
+			setup: func(m *mockExpenseRepo) {
+				m.On("Get", mock.Anything, "4").Return(entity.Expense{Id: "4", Kind: entity.INCOME}, nil)
+			},
+			wantStatus: 404,
+			wantResult: []byte(`{"code": "NOT_FOUND", "message": "expense not found"}`),
+		},
+		"update transfer to the same account": {
+			method: http.MethodPatch,
+			path:   "/api/transfer/5",
+			sent:   []byte(`{"toPaymentMethodId": "checking"}`),
+			setup: func(m *mockExpenseRepo) {
+				m.On("Get", mock.Anything, "5").Return(entity.Expense{
+					Id:                "5",
+					Kind:              entity.TRANSFER,
+					Amount:            5000,
+					PaymentMethodId:   "checking",
+					ToPaymentMethodId: "savings",
+				}, nil)
+			},
+			wantStatus: 400,
+			wantResult: []byte(`{
+				"code": "INVALID_REQUEST",
+				"message": "invalid expense",
+				"fields": [
+					{"field": "toPaymentMethodId", "code": "same_account", "message": "must be other than paymentMethodId"}
+				]
+			}`),
+		},
+		"search transfers": {
+			method: http.MethodGet,
+			path:   "/api/transfer",
+			setup: func(m *mockExpenseRepo) {
+				m.On("SearchPage", mock.Anything, entity.MustNewExpenseFilter(entity.FilterKind(entity.TRANSFER)), defaultPage).
+					Return(entity.Page{Expenses: []entity.Expense{
+						{Id: "2", Kind: entity.TRANSFER, Amount: 5000, PaymentMethodId: "checking", ToPaymentMethodId: "savings"},
+					}}, nil)
+			},
+			wantStatus: 200,
+			wantResult: []byte(`{"items": [{"id": "2", "amount": "50.00", "paymentMethodId": "checking", "toPaymentMethodId": "savings"}]}`),
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockedRepo := new(mockExpenseRepo)
+			if tc.setup != nil {
+				tc.setup(mockedRepo)
+			}
+			ts := httptest.NewServer(createHandler(context.Background(), mockedRepo))
+			defer ts.Close()
+
+			req, err := http.NewRequest(tc.method, ts.URL+tc.path, bytes.NewBuffer(tc.sent))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Accept", "application/json")
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tc.wantStatus, res.StatusCode)
+
+			got, err := io.ReadAll(res.Body)
+			res.Body.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			mockedRepo.AssertExpectations(t)
+			assert.JSONEq(t, string(tc.wantResult), string(got))
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/axpira/backend/entity"
+	"github.com/axpira/backend/entity/config"
+	"github.com/rs/zerolog/log"
+)
+
+type ExpenseRepository interface {
+	Create(ctx context.Context, expense entity.Expense) (string, error)
+}
+
+type RecurringExpenseRepository interface {
+	List(ctx context.Context) ([]entity.RecurringExpense, error)
+	Advance(ctx context.Context, id string, next int) error
+}
+
+// Recurring creates the expenses of every recurring expense when they come due.
+// An occurrence already created is a conflict on the repository, so a restart between
+// Create and Advance never duplicates it
+type Recurring struct {
+	expenseRepo   ExpenseRepository
+	recurringRepo RecurringExpenseRepository
+	interval      time.Duration
+	now           func() time.Time
+
+	mu      sync.Mutex
+	lastErr error
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+func NewRecurring(expenseRepo ExpenseRepository, recurringRepo RecurringExpenseRepository, interval time.Duration) *Recurring {
+	return &Recurring{
+		expenseRepo:   expenseRepo,
+		recurringRepo: recurringRepo,
+		interval:      interval,
+		now:           time.Now,
+	}
+}
+
+// Start runs once right away and then every interval until Stop
+func (s *Recurring) Start(ctx context.Context) {
+	ctx, s.cancel = context.WithCancel(ctx)
+	log.Ctx(ctx).Info().Dur("interval", s.interval).Msg("start recurring expenses generator")
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			s.run(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (s *Recurring) Stop(ctx context.Context) error {
+	log.Ctx(ctx).Info().Msg("stop recurring expenses generator")
+	if s.cancel != nil {
+		s.cancel()
+	}
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Status returns the error of the last run
+func (s *Recurring) Status() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastErr
+}
+
+func (s *Recurring) run(ctx context.Context) {
+	created, err := s.Generate(ctx)
+	if err != nil {
+		log.Ctx(ctx).Err(err).Msg("error on generate recurring expenses")
+	} else {
+		log.Ctx(ctx).Debug().Int("created", created).Msg("recurring expenses generated")
+	}
+	s.mu.Lock()
+	s.lastErr = err
+	s.mu.Unlock()
+}
+
+// Generate creates every occurrence due until now and returns how many were created.
+// A recurring expense that fails doesn't stop the others
+func (s *Recurring) Generate(ctx context.Context) (int, error) {
+	recurrings, err := s.recurringRepo.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	now := s.now()
+	created := 0
+	failed := 0
+	var firstErr error
+	for _, recurring := range recurrings {
+		n, err := s.generate(ctx, recurring, now)
+		created += n
+		if err != nil {
+			log.Ctx(ctx).Err(err).Str("recurring_id", recurring.Id).Msg("error on generate occurrences")
+			if firstErr == nil {
+				firstErr = fmt.Errorf("recurring expense %s: %w", recurring.Id, err)
+			}
+			failed++
+		}
+	}
+	if failed > 0 {
+		return created, fmt.Errorf("%d recurring expenses failed, first: %w", failed, firstErr)
+	}
+	return created, nil
+}
+
+func (s *Recurring) generate(ctx context.Context, recurring entity.RecurringExpense, now time.Time) (int, error) {
+	created := 0
+	next := recurring.NextOccurrence
+	for _, n := range recurring.Schedule.Due(recurring.NextOccurrence, now) {
+		occurrence := recurring.NewOccurrence(n)
+		if occurrence.Currency == "" {
+			occurrence.Currency = entity.Currency(config.Config.DefaultCurrency)
+		}
+		_, err := s.expenseRepo.Create(ctx, occurrence)
+		if err != nil && !errors.Is(err, entity.ErrConflict) {
+			return created, s.advance(ctx, recurring, next, err)
+		}
+		if err == nil {
+			created++
+		}
+		next = n + 1
+	}
+	return created, s.advance(ctx, recurring, next, nil)
+}
+
+// advance saves how far the recurring expense went, keeping the error that stopped it
+func (s *Recurring) advance(ctx context.Context, recurring entity.RecurringExpense, next int, err error) error {
+	if next == recurring.NextOccurrence {
+		return err
+	}
+	advanceErr := s.recurringRepo.Advance(ctx, recurring.Id, next)
+	if err == nil {
+		return advanceErr
+	}
+	if advanceErr != nil {
+		log.Ctx(ctx).Err(advanceErr).Str("recurring_id", recurring.Id).Msg("error on advance")
+	}
+	return err
+}
@@ -0,0 +1,119 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/axpira/backend/entity"
+	"github.com/axpira/backend/entity/config"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeExpenseRepo struct {
+	created []entity.Expense
+	// existing are the occurrences created before, a conflict on create
+	existing map[int]bool
+	failOn   int
+}
+
+func (f *fakeExpenseRepo) Create(ctx context.Context, expense entity.Expense) (string, error) {
+	if f.existing[expense.Occurrence] {
+		return "", entity.ErrConflict
+	}
+	if f.failOn > 0 && expense.Occurrence == f.failOn {
+		return "", errors.New("database is down")
+	}
+	f.created = append(f.created, expense)
+	return "id", nil
+}
+
+type fakeRecurringRepo struct {
+	recurrings []entity.RecurringExpense
+	advanced   map[string]int
+}
+
+func (f *fakeRecurringRepo) List(ctx context.Context) ([]entity.RecurringExpense, error) {
+	return f.recurrings, nil
+}
+
+func (f *fakeRecurringRepo) Advance(ctx context.Context, id string, next int) error {
+	f.advanced[id] = next
+	return nil
+}
+
+func TestGenerate(t *testing.T) {
+	defaultCurrency := config.Config.DefaultCurrency
+	config.Config.DefaultCurrency = "BRL"
+	defer func() { config.Config.DefaultCurrency = defaultCurrency }()
+	rent := entity.RecurringExpense{
+		Id:       "rent",
+		Template: entity.Expense{Kind: entity.EXPENSE, Amount: 150000, What: "rent"},
+		Schedule: entity.Schedule{Frequency: entity.MONTHLY, Start: time.Date(2021, 1, 5, 0, 0, 0, 0, time.UTC)},
+	}
+	now := time.Date(2021, 4, 10, 0, 0, 0, 0, time.UTC)
+	tests := map[string]struct {
+		recurring    entity.RecurringExpense
+		existing     map[int]bool
+		failOn       int
+		wantCreated  []int
+		wantAdvanced map[string]int
+		wantErr      bool
+	}{
+		"creates every due occurrence": {
+			recurring:    rent,
+			wantCreated:  []int{0, 1, 2, 3},
+			wantAdvanced: map[string]int{"rent": 4},
+		},
+		"skips the occurrences already created": {
+			recurring:    rent,
+			existing:     map[int]bool{0: true, 1: true},
+			wantCreated:  []int{2, 3},
+			wantAdvanced: map[string]int{"rent": 4},
+		},
+		"nothing due": {
+			recurring: func() entity.RecurringExpense {
+				r := rent
+				r.NextOccurrence = 4
+				return r
+			}(),
+			wantAdvanced: map[string]int{},
+		},
+		"advances until the failure": {
+			recurring:    rent,
+			failOn:       2,
+			wantCreated:  []int{0, 1},
+			wantAdvanced: map[string]int{"rent": 2},
+			wantErr:      true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			expenseRepo := &fakeExpenseRepo{existing: tc.existing, failOn: tc.failOn}
+			recurringRepo := &fakeRecurringRepo{
+				recurrings: []entity.RecurringExpense{tc.recurring},
+				advanced:   map[string]int{},
+			}
+			s := NewRecurring(expenseRepo, recurringRepo, time.Hour)
+			s.now = func() time.Time { return now }
+
+			created, err := s.Generate(context.Background())
+			if tc.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, len(tc.wantCreated), created)
+			var occurrences []int
+			for _, e := range expenseRepo.created {
+				assert.Equal(t, "rent", e.RecurringId)
+				assert.Equal(t, entity.Currency("BRL"), e.Currency, "must write the default currency the template lacks")
+				assert.Equal(t, tc.recurring.Schedule.Occurrence(e.Occurrence), e.When)
+				occurrences = append(occurrences, e.Occurrence)
+			}
+			assert.Equal(t, tc.wantCreated, occurrences)
+			assert.Equal(t, tc.wantAdvanced, recurringRepo.advanced)
+		})
+	}
+}
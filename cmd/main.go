@@ -6,6 +6,7 @@ import (
 	"os/signal"
 
 	"github.com/axpira/backend/api/rest"
+	"github.com/axpira/backend/api/scheduler"
 	"github.com/axpira/backend/entity/config"
 	"github.com/axpira/backend/infrastructure/repository/postgres"
 	"github.com/rs/zerolog"
@@ -27,12 +28,29 @@ func main() {
 
 	l.Info().Msgf("%v", config.Config)
 
-	repo, err := postgres.NewExpenseRepository(ctx)
-	fatalOnError(l, err, "error on create repository")
-	restService, err := rest.New(ctx, repo)
+	db, err := postgres.Open(ctx)
+	fatalOnError(l, err, "error on open database")
+	defer db.Close()
+
+	repo := postgres.NewExpenseRepository(db)
+	recurringRepo := postgres.NewRecurringExpenseRepository(db)
+	restService, err := rest.New(ctx, repo,
+		rest.WithCategoryRepository(postgres.NewCategoryRepository(db)),
+		rest.WithPaymentMethodRepository(postgres.NewPaymentMethodRepository(db)),
+		rest.WithExchangeRateRepository(postgres.NewExchangeRateRepository(db)),
+		rest.WithRecurringExpenseRepository(recurringRepo),
+		rest.WithPlaceRepository(postgres.NewPlaceRepository(db)),
+		rest.WithPersonRepository(postgres.NewPersonRepository(db)),
+		rest.WithBudgetRepository(postgres.NewBudgetRepository(db)),
+		rest.WithReportRepository(postgres.NewReportRepository(db)),
+		rest.WithExportRepository(repo),
+		rest.WithIdempotencyRepository(postgres.NewIdempotencyRepository(db), config.Config.IdempotencyWindow),
+	)
 	fatalOnError(l, err, "error on create rest service")
 
 	restService.Start(ctx)
+	recurringService := scheduler.NewRecurring(repo, recurringRepo, config.Config.RecurringInterval)
+	recurringService.Start(ctx)
 
 	l.Info().Str("service", "rest").Str("action", "started").Msg("waiting connection")
 
@@ -41,6 +59,7 @@ func main() {
 	<-stop
 
 	restService.Stop(ctx)
+	recurringService.Stop(ctx)
 	l.Info().
 		Str("service", "rest").
 		Str("action", "stopped").
@@ -0,0 +1,180 @@
+package entity
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+)
+
+var budgetPeriods = []Frequency{
+	WEEKLY,
+	MONTHLY,
+	YEARLY,
+}
+
+func isBudgetPeriod(f Frequency) bool {
+	for _, v := range budgetPeriods {
+		if v == f {
+			return true
+		}
+	}
+	return false
+}
+
+// Bounds returns the period of the frequency holding t, from its start to the start of the next
+// one. Weeks start on monday, months and years are the calendar ones in the location of t
+func (f Frequency) Bounds(t time.Time) (time.Time, time.Time) {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	switch f {
+	case WEEKLY:
+		start := day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+		return start, start.AddDate(0, 0, 7)
+	case MONTHLY:
+		start := day.AddDate(0, 0, 1-day.Day())
+		return start, start.AddDate(0, 1, 0)
+	case YEARLY:
+		start := time.Date(t.Year(), 1, 1, 0, 0, 0, 0, t.Location())
+		return start, start.AddDate(1, 0, 0)
+	}
+	return day, day.AddDate(0, 0, 1)
+}
+
+// Budget limits the spending on a category, with its descendants, or on a tag
+type Budget struct {
+	Id         string
+	Name       string
+	CategoryId string
+	Tag        string
+	Period     Frequency
+	Limit      Money
+	// Rollover carries what was left of every period since Start to the next one,
+	// overspending is carried too
+	Rollover bool
+	Start    time.Time
+}
+
+func (b Budget) Validate() error {
+	var err error
+	if strings.TrimSpace(b.Name) == "" {
+		err = NewFieldError(err, "name", "no_empty", "can't be empty")
+	}
+	if (b.CategoryId == "") == (b.Tag == "") {
+		err = NewFieldError(err, "categoryId", "invalid_scope", "set either a category or a tag")
+	}
+	if b.Tag != "" {
+		_, tagErr := validateTag(nil, b.Tag)
+		err = MergeFieldErrors(err, tagErr)
+	}
+	if !isBudgetPeriod(b.Period) {
+		err = NewFieldError(err, "period", "invalid", fmt.Sprintf("must be one of %v", budgetPeriods))
+	}
+	if b.Limit.Amount <= 0 {
+		err = NewFieldError(err, "limit", "invalid", "must be greater than zero")
+	}
+	if b.Start.IsZero() {
+		err = NewFieldError(err, "start", "no_empty", "can't be empty")
+	}
+	return err
+}
+
+// Since returns from when the expenses count for the status at now, the first period
+// with rollover or the current one without it
+func (b Budget) Since(now time.Time) time.Time {
+	if b.Rollover && b.Start.Before(now) {
+		start, _ := b.Period.Bounds(b.Start)
+		return start
+	}
+	start, _ := b.Period.Bounds(now)
+	return start
+}
+
+// Filters match the spending of the budget from since until the end of the period of now,
+// in every currency so Status can tell the ones it doesn't count
+func (b Budget) Filters(now time.Time) []func(*ExpenseFilter) error {
+	_, end := b.Period.Bounds(now)
+	filters := []func(*ExpenseFilter) error{
+		FilterWhen(GE, b.Since(now)),
+		FilterWhen(LT, end),
+	}
+	if b.CategoryId != "" {
+		return append(filters, FilterCategory(EQUALS, b.CategoryId))
+	}
+	return append(filters, FilterTag(EQUALS, b.Tag))
+}
+
+type BudgetStatus struct {
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+	// Available is the limit plus what was carried from the previous periods
+	Available Money
+	Carried   Money
+	Spent     Money
+	Remaining Money
+	// Projected is the spending at the end of the period if it goes on at the same pace
+	Projected Money
+	// Excluded is how many expenses since Since weren't counted for being in
+	// ExcludedCurrencies, other than the one of the limit
+	Excluded           int
+	ExcludedCurrencies []Currency
+}
+
+// Status computes the current period at now from the expenses matching Filters,
+// the ones that aren't spending are ignored and the ones in other currencies are excluded
+func (b Budget) Status(now time.Time, expenses []Expense) BudgetStatus {
+	start, end := b.Period.Bounds(now)
+	var carried, spent int64
+	if b.Rollover {
+		for periodStart := b.Since(now); periodStart.Before(start); {
+			_, periodEnd := b.Period.Bounds(periodStart)
+			carried += b.Limit.Amount - b.spentBetween(expenses, periodStart, periodEnd)
+			periodStart = periodEnd
+		}
+	}
+	spent = b.spentBetween(expenses, start, end)
+	projected := spent
+	if elapsed := now.Sub(start); elapsed > 0 && now.Before(end) {
+		projected = round(new(big.Rat).SetFrac(
+			new(big.Int).Mul(big.NewInt(spent), big.NewInt(int64(end.Sub(start)))),
+			big.NewInt(int64(elapsed)),
+		))
+	}
+	money := func(amount int64) Money {
+		return Money{Amount: amount, Currency: b.Limit.Currency}
+	}
+	available := b.Limit.Amount + carried
+	status := BudgetStatus{
+		PeriodStart: start,
+		PeriodEnd:   end,
+		Available:   money(available),
+		Carried:     money(carried),
+		Spent:       money(spent),
+		Remaining:   money(available - spent),
+		Projected:   money(projected),
+	}
+	since, excluded := b.Since(now), map[Currency]bool{}
+	for _, e := range expenses {
+		if e.IsSpending() && e.Currency != b.Limit.Currency && !e.When.Before(since) && e.When.Before(end) {
+			status.Excluded++
+			if !excluded[e.Currency] {
+				excluded[e.Currency] = true
+				status.ExcludedCurrencies = append(status.ExcludedCurrencies, e.Currency)
+			}
+		}
+	}
+	sort.Slice(status.ExcludedCurrencies, func(i, j int) bool {
+		return status.ExcludedCurrencies[i] < status.ExcludedCurrencies[j]
+	})
+	return status
+}
+
+func (b Budget) spentBetween(expenses []Expense, start, end time.Time) int64 {
+	var spent int64
+	for _, e := range expenses {
+		if e.IsSpending() && e.Currency == b.Limit.Currency && !e.When.Before(start) && e.When.Before(end) {
+			spent += e.Amount
+		}
+	}
+	return spent
+}
@@ -0,0 +1,108 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFrequencyBounds(t *testing.T) {
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+	now := time.Date(2021, 3, 14, 18, 30, 0, 0, time.UTC)
+	tests := map[Frequency][2]time.Time{
+		WEEKLY:  {date(2021, 3, 8), date(2021, 3, 15)},
+		MONTHLY: {date(2021, 3, 1), date(2021, 4, 1)},
+		YEARLY:  {date(2021, 1, 1), date(2022, 1, 1)},
+		DAILY:   {date(2021, 3, 14), date(2021, 3, 15)},
+	}
+	for frequency, want := range tests {
+		t.Run(string(frequency), func(t *testing.T) {
+			start, end := frequency.Bounds(now)
+			assert.Equal(t, want[0], start)
+			assert.Equal(t, want[1], end)
+		})
+	}
+}
+
+func TestBudgetValidate(t *testing.T) {
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	limit := Money{Amount: 10000, Currency: "BRL"}
+	assert.NoError(t, Budget{Name: "Food", CategoryId: "food", Period: MONTHLY, Limit: limit, Start: start}.Validate())
+	assert.NoError(t, Budget{Name: "Trip", Tag: "trip", Period: YEARLY, Limit: limit, Start: start}.Validate())
+
+	fieldErrors := UnwrapFieldErrors(Budget{Name: "Both", CategoryId: "food", Tag: "trip", Period: DAILY, Start: start}.Validate())
+	fields := make([]string, len(fieldErrors))
+	for i, f := range fieldErrors {
+		fields[i] = f.Field()
+	}
+	assert.Equal(t, []string{"limit", "period", "categoryId"}, fields)
+
+	fieldErrors = UnwrapFieldErrors(Budget{}.Validate())
+	assert.Len(t, fieldErrors, 5, "must require name, scope, period, limit and start")
+}
+
+func TestBudgetStatus(t *testing.T) {
+	date := func(month time.Month, day int) time.Time {
+		return time.Date(2021, month, day, 12, 0, 0, 0, time.UTC)
+	}
+	expenses := []Expense{
+		{Amount: 8000, Currency: "BRL", When: date(1, 20)},
+		{Amount: 11000, Currency: "BRL", When: date(2, 5)},
+		{Amount: 1000, Currency: "BRL", When: date(3, 2)},
+		{Amount: 2000, Currency: "BRL", When: date(3, 10)},
+		{Kind: INCOME, Amount: 5000, Currency: "BRL", When: date(3, 11)},
+		{Amount: 3000, Currency: "USD", When: date(2, 8)},
+		{Amount: 700, Currency: "EUR", When: date(3, 12)},
+		{Amount: 900, Currency: "USD", When: date(3, 13)},
+	}
+	now := time.Date(2021, 3, 16, 0, 0, 0, 0, time.UTC)
+	brl := func(amount int64) Money {
+		return Money{Amount: amount, Currency: "BRL"}
+	}
+	budget := Budget{
+		Name:       "Food",
+		CategoryId: "food",
+		Period:     MONTHLY,
+		Limit:      brl(10000),
+		Start:      date(1, 10),
+	}
+
+	t.Run("without rollover", func(t *testing.T) {
+		assert.Equal(t, time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC), budget.Since(now))
+		assert.Equal(t, BudgetStatus{
+			PeriodStart: time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC),
+			PeriodEnd:   time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC),
+			Available:   brl(10000),
+			Carried:     brl(0),
+			Spent:       brl(3000),
+			Remaining:   brl(7000),
+			Projected:   brl(6200),
+			Excluded:    2,
+			// the USD of february is before the period
+			ExcludedCurrencies: []Currency{"EUR", "USD"},
+		}, budget.Status(now, expenses))
+	})
+
+	t.Run("with rollover", func(t *testing.T) {
+		budget := budget
+		budget.Rollover = true
+		assert.Equal(t, time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), budget.Since(now))
+		status := budget.Status(now, expenses)
+		assert.Equal(t, brl(1000), status.Carried, "must carry what was left and what was overspent")
+		assert.Equal(t, brl(11000), status.Available)
+		assert.Equal(t, brl(8000), status.Remaining)
+		assert.Equal(t, 3, status.Excluded, "must report the other currencies of every period")
+		assert.Equal(t, []Currency{"EUR", "USD"}, status.ExcludedCurrencies)
+	})
+
+	t.Run("filters", func(t *testing.T) {
+		filter, err := NewExpenseFilter(budget.Filters(now)...)
+		assert.NoError(t, err)
+		assert.Equal(t, []StrFilter{{EQUALS, "food"}}, filter.Category)
+		assert.Empty(t, filter.Currency, "must search every currency to report the excluded")
+		assert.Len(t, filter.When, 2)
+	})
+}
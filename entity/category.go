@@ -0,0 +1,22 @@
+package entity
+
+import (
+	"strings"
+)
+
+type Category struct {
+	Id       string
+	Name     string
+	ParentId string
+}
+
+func (c Category) Validate() error {
+	var err error
+	if strings.TrimSpace(c.Name) == "" {
+		err = NewFieldError(err, "name", "no_empty", "can't be empty")
+	}
+	if c.ParentId != "" && c.ParentId == c.Id {
+		err = NewFieldError(err, "parentId", "cycle", "can't be its own parent")
+	}
+	return err
+}
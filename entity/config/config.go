@@ -1,12 +1,20 @@
 package config
 
 import (
+	"time"
+
 	"github.com/caarlos0/env/v6"
 )
 
 type config struct {
-	Port        int    `env:"PORT" envDefault:"3000"`
-	DatabaseUrl string `env:"DATABASE_URL"`
+	Port            int    `env:"PORT" envDefault:"3000"`
+	DatabaseUrl     string `env:"DATABASE_URL"`
+	DefaultCurrency string `env:"DEFAULT_CURRENCY" envDefault:"BRL"`
+	BaseCurrency    string `env:"BASE_CURRENCY" envDefault:"BRL"`
+	// RecurringInterval is how often the recurring expenses are checked
+	RecurringInterval time.Duration `env:"RECURRING_INTERVAL" envDefault:"1h"`
+	// IdempotencyWindow is how long the responses to requests with an Idempotency-Key are replayed
+	IdempotencyWindow time.Duration `env:"IDEMPOTENCY_WINDOW" envDefault:"24h"`
 }
 
 var Config config
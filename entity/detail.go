@@ -0,0 +1,99 @@
+package entity
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// QuantityUnit is 1 in the unit of Detail.Quantity, thousandths
+const QuantityUnit = 1000
+
+// Detail is a line item of a transaction, like a product on a receipt
+type Detail struct {
+	Description string
+	// Quantity is in thousandths, 1500 is 1.5
+	Quantity int64
+	// UnitPrice is in the minor unit of the transaction currency
+	UnitPrice  int64
+	CategoryId string
+	Tags       *Tags
+}
+
+// Total is Quantity times UnitPrice, rounded half away from zero to the minor unit.
+// ValidateDetails refuses the details whose total doesn't fit an int64
+func (d Detail) Total() int64 {
+	return d.total().Int64()
+}
+
+// total multiplies in big.Int, Quantity * UnitPrice doesn't fit an int64 on large values
+func (d Detail) total() *big.Int {
+	return roundInt(new(big.Rat).SetFrac(
+		new(big.Int).Mul(big.NewInt(d.Quantity), big.NewInt(d.UnitPrice)),
+		big.NewInt(QuantityUnit),
+	))
+}
+
+// ValidateDetails checks every line item, their totals must sum the amount
+func (t Transaction) ValidateDetails() error {
+	if len(t.Details) == 0 {
+		return nil
+	}
+	var err error
+	total := new(big.Int)
+	for i, d := range t.Details {
+		field := fmt.Sprintf("details[%d]", i)
+		if strings.TrimSpace(d.Description) == "" {
+			err = NewFieldError(err, field+".description", "no_empty", "can't be empty")
+		}
+		if d.Quantity <= 0 {
+			err = NewFieldError(err, field+".quantity", "invalid", "must be greater than zero")
+		}
+		detailTotal := d.total()
+		if !detailTotal.IsInt64() {
+			err = NewFieldError(err, field+".unitPrice", "out_of_range", "the quantity times the unit price is too large")
+		}
+		total.Add(total, detailTotal)
+	}
+	if err != nil {
+		return err
+	}
+	if !total.IsInt64() {
+		return NewFieldError(nil, "details", "out_of_range", "the totals sum more than an amount holds")
+	}
+	if total.Int64() != t.Amount {
+		return NewFieldError(nil, "details", "invalid_sum", fmt.Sprintf("the totals sum %s, not the amount", Money{Amount: total.Int64(), Currency: t.Currency}))
+	}
+	return nil
+}
+
+// ParseQuantity reads a quantity with up to 3 decimal places, like "1.5", in thousandths
+func ParseQuantity(value string) (int64, error) {
+	quantity, code := parseDecimal(value, 3)
+	if code != "" {
+		return 0, NewFieldError(nil, "quantity", "invalid", fmt.Sprintf("%q is not a quantity with up to 3 decimal places", value))
+	}
+	return quantity, nil
+}
+
+// FormatQuantity is the inverse of ParseQuantity, without trailing zeros
+func FormatQuantity(quantity int64) string {
+	sign := ""
+	if quantity < 0 {
+		sign = "-"
+		quantity = -quantity
+	}
+	s := strconv.FormatInt(quantity/QuantityUnit, 10)
+	if fraction := quantity % QuantityUnit; fraction != 0 {
+		s += strings.TrimRight(fmt.Sprintf(".%03d", fraction), "0")
+	}
+	return sign + s
+}
+
+func FilterDetail(t StrFilterType, value string) func(*ExpenseFilter) error {
+	return func(e *ExpenseFilter) error {
+		e.Detail = append(e.Detail, StrFilter{Type: t, Value: value})
+		return nil
+	}
+}
@@ -0,0 +1,83 @@
+package entity
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateDetails(t *testing.T) {
+	tests := map[string]struct {
+		transaction Transaction
+		wantFields  []string
+	}{
+		"without details": {
+			transaction: Transaction{Amount: 1000},
+		},
+		"totals sum the amount": {
+			transaction: Transaction{Amount: 701, Details: []Detail{
+				// 1.5 * 3.00 and 0.333 * 7.53, 2.50749 rounded to 2.51
+				{Description: "bread", Quantity: 1500, UnitPrice: 300},
+				{Description: "cheese", Quantity: 333, UnitPrice: 753},
+			}},
+		},
+		"totals must sum the amount": {
+			transaction: Transaction{Amount: 1000, Details: []Detail{
+				{Description: "bread", Quantity: 1000, UnitPrice: 300},
+			}},
+			wantFields: []string{"details"},
+		},
+		"totals can't wrap around": {
+			// 4611686018427388.154 * 0.04 wraps around to 0.01 on an int64
+			transaction: Transaction{Amount: 1, Details: []Detail{
+				{Description: "gold", Quantity: 1<<62 + 250, UnitPrice: 4},
+			}},
+			wantFields: []string{"details"},
+		},
+		"total too large": {
+			transaction: Transaction{Amount: 1000, Details: []Detail{
+				{Description: "gold", Quantity: math.MaxInt64, UnitPrice: math.MaxInt64},
+			}},
+			wantFields: []string{"details[0].unitPrice"},
+		},
+		"totals sum too large": {
+			transaction: Transaction{Amount: 1000, Details: []Detail{
+				{Description: "gold", Quantity: 1000, UnitPrice: math.MaxInt64},
+				{Description: "silver", Quantity: 1000, UnitPrice: 1},
+			}},
+			wantFields: []string{"details"},
+		},
+		"invalid line items": {
+			transaction: Transaction{Amount: 0, Details: []Detail{
+				{Description: " ", Quantity: 1000},
+				{Description: "milk", Quantity: 0},
+			}},
+			wantFields: []string{"details[0].description", "details[1].quantity"},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := tc.transaction.ValidateDetails()
+			var fields []string
+			for _, f := range UnwrapFieldErrors(err) {
+				fields = append(fields, f.Field())
+			}
+			assert.ElementsMatch(t, tc.wantFields, fields)
+		})
+	}
+}
+
+func TestParseQuantity(t *testing.T) {
+	for value, want := range map[string]int64{"1": 1000, "1.5": 1500, "0.125": 125, "2.50": 2500} {
+		got, err := ParseQuantity(value)
+		assert.NoError(t, err, value)
+		assert.Equal(t, want, got, value)
+		assert.Equal(t, value == "2.50", FormatQuantity(got) != value, "must format %q back without trailing zeros", value)
+	}
+	for _, value := range []string{"", "1.0001", "abc", "1,5"} {
+		_, err := ParseQuantity(value)
+		assert.Error(t, err, value)
+	}
+	assert.Equal(t, "2.5", FormatQuantity(2500))
+}
@@ -88,17 +88,54 @@ func (t Tags) String() string {
 	return fmt.Sprintf("%v", t.values)
 }
 
-type Expense struct {
-	Id     string
-	Amount int64
-	When   time.Time
-	Where  string
-	Who    string
-	What   string
+// Transaction is an expense, an income or a transfer between our own accounts
+type Transaction struct {
+	Id              string
+	Kind            TransactionKind
+	Amount          int64
+	Currency        Currency
+	When            time.Time
+	Where           string
+	Who             string
+	What            string
+	Tags            *Tags
+	CategoryId      string
+	PaymentMethodId string
+	// ToPaymentMethodId is where a transfer goes to, PaymentMethodId is where it comes from
+	ToPaymentMethodId string
+	// PlaceId and PersonId reference the Place and the Person that Where and Who name
+	PlaceId  string
+	PersonId string
+	// Split divides the amount between people, nil when it isn't shared
+	Split *Split
+	// Details are the line items, their totals sum Amount
+	Details []Detail
+	// Metadata is free-form, like an invoice number or a project code
+	Metadata map[string]string
+	// RecurringId and Occurrence link the expenses created by a RecurringExpense
+	RecurringId string
+	Occurrence  int
+	// ExternalId identifies the transaction on the statement it was imported from, it's unique
+	ExternalId string
+}
+
+// Expense is the transaction most of the API deals with
+type Expense = Transaction
+
+// Money returns the amount of the expense with its currency
+func (e Expense) Money() Money {
+	return Money{Amount: e.Amount, Currency: e.Currency}
 }
 
 type UpdateExpenseFunc func(*Expense) error
 
+func (e *Expense) UpdateTags(action TagAction, values ...string) error {
+	if e.Tags == nil {
+		e.Tags = new(Tags)
+	}
+	return updateTags(e.Tags, action, values...)
+}
+
 type TagAction int
 
 const (
@@ -156,18 +193,24 @@ type StrFilter struct {
 }
 
 type ExpenseFilter struct {
-	Amount []IntFilter
-	When   []TimeFilter
-	// 	Where         URN
-	// 	Who           URN
-	What          []StrFilter
+	Id       []StrFilter
+	Kind     []TransactionKind
+	Amount   []IntFilter
+	Currency []StrFilter
+	When     []TimeFilter
+	Where    []URN
+	Who      []URN
+	What     []StrFilter
+	// Detail matches the description of any line item
+	Detail        []StrFilter
 	Tag           []StrFilter
 	Category      []StrFilter
 	PaymentMethod []StrFilter
-	// 	// Details       []Detail
-	// 	Metadata  map[string]string
-	CreatedAt []TimeFilter
-	UpdatedAt []TimeFilter
+	Metadata      []MetadataFilter
+	CreatedAt     []TimeFilter
+	UpdatedAt     []TimeFilter
+	// Split keeps only the transactions split between people
+	Split bool
 }
 
 func MustNewExpenseFilter(filters ...func(ef *ExpenseFilter) error) *ExpenseFilter {
@@ -196,3 +239,73 @@ func FilterAmountInt(t OpFilterType, value int) func(*ExpenseFilter) error {
 		return nil
 	}
 }
+
+func FilterWhen(t OpFilterType, value time.Time) func(*ExpenseFilter) error {
+	return func(e *ExpenseFilter) error {
+		e.When = append(e.When, TimeFilter{t, value})
+		return nil
+	}
+}
+
+func FilterId(t StrFilterType, value string) func(*ExpenseFilter) error {
+	return func(e *ExpenseFilter) error {
+		e.Id = append(e.Id, StrFilter{t, value})
+		return nil
+	}
+}
+
+func FilterCurrency(t StrFilterType, value string) func(*ExpenseFilter) error {
+	return func(e *ExpenseFilter) error {
+		e.Currency = append(e.Currency, StrFilter{t, value})
+		return nil
+	}
+}
+
+func FilterWhat(t StrFilterType, value string) func(*ExpenseFilter) error {
+	return func(e *ExpenseFilter) error {
+		e.What = append(e.What, StrFilter{t, value})
+		return nil
+	}
+}
+
+func FilterTag(t StrFilterType, value string) func(*ExpenseFilter) error {
+	return func(e *ExpenseFilter) error {
+		e.Tag = append(e.Tag, StrFilter{t, value})
+		return nil
+	}
+}
+
+func FilterCategory(t StrFilterType, value string) func(*ExpenseFilter) error {
+	return func(e *ExpenseFilter) error {
+		e.Category = append(e.Category, StrFilter{t, value})
+		return nil
+	}
+}
+
+func FilterPaymentMethod(t StrFilterType, value string) func(*ExpenseFilter) error {
+	return func(e *ExpenseFilter) error {
+		e.PaymentMethod = append(e.PaymentMethod, StrFilter{t, value})
+		return nil
+	}
+}
+
+func FilterSplit() func(*ExpenseFilter) error {
+	return func(e *ExpenseFilter) error {
+		e.Split = true
+		return nil
+	}
+}
+
+func FilterCreatedAt(t OpFilterType, value time.Time) func(*ExpenseFilter) error {
+	return func(e *ExpenseFilter) error {
+		e.CreatedAt = append(e.CreatedAt, TimeFilter{t, value})
+		return nil
+	}
+}
+
+func FilterUpdatedAt(t OpFilterType, value time.Time) func(*ExpenseFilter) error {
+	return func(e *ExpenseFilter) error {
+		e.UpdatedAt = append(e.UpdatedAt, TimeFilter{t, value})
+		return nil
+	}
+}
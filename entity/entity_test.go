@@ -0,0 +1,137 @@
+package entity
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpenseUpdateTags(t *testing.T) {
+	tests := map[string]struct {
+		tags     *Tags
+		action   TagAction
+		values   []string
+		wantTags []string
+		wantErr  bool
+	}{
+		"set on expense without tags": {
+			action:   SET,
+			values:   []string{"trip", "work"},
+			wantTags: []string{"trip", "work"},
+		},
+		"add without duplicates": {
+			tags:     MustNewTags("trip"),
+			action:   ADD,
+			values:   []string{"work", " trip "},
+			wantTags: []string{"trip", "work"},
+		},
+		"del": {
+			tags:     MustNewTags("trip", "work"),
+			action:   DEL,
+			values:   []string{"trip", "unknown"},
+			wantTags: []string{"work"},
+		},
+		"invalid tag": {
+			tags:     MustNewTags("trip"),
+			action:   ADD,
+			values:   []string{"my work"},
+			wantTags: []string{"trip"},
+			wantErr:  true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			e := Expense{Tags: tc.tags}
+			err := e.UpdateTags(tc.action, tc.values...)
+			if tc.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.wantTags, e.Tags.Value())
+		})
+	}
+}
+
+func TestTransactionKind(t *testing.T) {
+	assert.True(t, Transaction{}.IsSpending(), "transactions stored before kinds existed are expenses")
+	assert.False(t, Transaction{Kind: TRANSFER}.IsSpending())
+	assert.False(t, Transaction{Kind: INCOME}.IsSpending())
+
+	assert.NoError(t, Transaction{Kind: TRANSFER, PaymentMethodId: "checking", ToPaymentMethodId: "savings"}.ValidateKind())
+	assert.Error(t, Transaction{Kind: "loan"}.ValidateKind())
+	fieldErrors := UnwrapFieldErrors(Transaction{Kind: TRANSFER}.ValidateKind())
+	assert.Len(t, fieldErrors, 2, "must require both accounts")
+
+	split := &Split{Kind: EQUAL, Shares: []Share{{Who: "ana"}, {Who: "bob"}}}
+	assert.NoError(t, Transaction{Split: split}.ValidateKind())
+	for _, transaction := range []Transaction{
+		{Kind: INCOME, Split: split},
+		{Kind: TRANSFER, Split: split, PaymentMethodId: "checking", ToPaymentMethodId: "savings"},
+	} {
+		fieldErrors = UnwrapFieldErrors(transaction.ValidateKind())
+		if assert.Len(t, fieldErrors, 1, "must not split a %s", transaction.Kind) {
+			assert.Equal(t, "split", fieldErrors[0].Field())
+			assert.Equal(t, "not_allowed", fieldErrors[0].Code())
+		}
+	}
+}
+
+func TestTransactionValidate(t *testing.T) {
+	now := time.Now()
+	tests := map[string]struct {
+		transaction Transaction
+		wantFields  []string
+	}{
+		"valid": {
+			transaction: Transaction{Amount: 1250, Currency: "BRL", When: now, What: strings.Repeat("é", 255)},
+		},
+		"valid without date nor currency": {
+			transaction: Transaction{Amount: 1250},
+		},
+		"empty": {
+			wantFields: []string{"amount"},
+		},
+		"negative amount and unknown currency": {
+			transaction: Transaction{Amount: -1250, Currency: "XYZ"},
+			wantFields:  []string{"amount", "currency"},
+		},
+		"too old": {
+			transaction: Transaction{Amount: 1250, When: time.Date(1899, 12, 31, 0, 0, 0, 0, time.UTC)},
+			wantFields:  []string{"when"},
+		},
+		"too far ahead": {
+			transaction: Transaction{Amount: 1250, When: now.AddDate(1, 1, 0)},
+			wantFields:  []string{"when"},
+		},
+		"longer than the columns": {
+			transaction: Transaction{
+				Amount:     1250,
+				What:       strings.Repeat("a", 256),
+				CategoryId: strings.Repeat("a", 129),
+				Details:    []Detail{{Description: strings.Repeat("a", 256), Quantity: QuantityUnit, UnitPrice: 1250}},
+			},
+			wantFields: []string{"what", "categoryId", "details[0].description"},
+		},
+		"with the kind and the details": {
+			transaction: Transaction{
+				Amount:  1250,
+				Kind:    TRANSFER,
+				Details: []Detail{{Description: "bread", Quantity: QuantityUnit, UnitPrice: 1000}},
+			},
+			wantFields: []string{"paymentMethodId", "toPaymentMethodId", "details"},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var fields []string
+			fieldErrors := UnwrapFieldErrors(tc.transaction.Validate())
+			for i := len(fieldErrors) - 1; i >= 0; i-- {
+				fields = append(fields, fieldErrors[i].Field())
+			}
+			assert.Equal(t, tc.wantFields, fields)
+		})
+	}
+}
@@ -5,11 +5,14 @@ import (
 	"fmt"
 )
 
+// ErrBusiness is the cause of the errors the client can fix or act on, FieldErrors included.
+// ErrTechnical is the cause of the ones it can't, their details aren't sent to it
 var (
-	ErrUnknown   = errors.New("unknown error")
 	ErrBusiness  = errors.New("")
 	ErrTechnical = errors.New("")
+	ErrUnknown   = fmt.Errorf("%wunknown error", ErrTechnical)
 	ErrNotFound  = fmt.Errorf("%wnot found", ErrBusiness)
+	ErrConflict  = fmt.Errorf("%wconflict", ErrBusiness)
 )
 
 func NewFieldError(err error, field, code, description string) FieldError {
@@ -21,6 +24,26 @@ func NewFieldError(err error, field, code, description string) FieldError {
 	}
 }
 
+// MergeFieldErrors chains the field errors of other onto err keeping their order
+func MergeFieldErrors(err, other error) error {
+	fieldErrors := UnwrapFieldErrors(other)
+	for i := len(fieldErrors) - 1; i >= 0; i-- {
+		f := fieldErrors[i]
+		err = NewFieldError(err, f.Field(), f.Code(), f.Description())
+	}
+	return err
+}
+
+// RenameFieldError moves the field errors of err to field, the one of the request they came from
+func RenameFieldError(err error, field string) error {
+	var res error
+	fieldErrors := UnwrapFieldErrors(err)
+	for i := len(fieldErrors) - 1; i >= 0; i-- {
+		res = NewFieldError(res, field, fieldErrors[i].Code(), fieldErrors[i].Description())
+	}
+	return res
+}
+
 func UnwrapFieldErrors(err error) []FieldError {
 	res := make([]FieldError, 0)
 	var fieldErr FieldError
@@ -59,6 +82,11 @@ func (f FieldError) Unwrap() error {
 	return f.err
 }
 
+// Is makes every FieldError an ErrBusiness
+func (f FieldError) Is(target error) bool {
+	return target == ErrBusiness
+}
+
 func (f FieldError) Field() string {
 	return f.field
 }
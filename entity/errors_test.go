@@ -2,6 +2,7 @@ package entity
 
 import (
 	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -65,8 +66,34 @@ func TestFieldError(t *testing.T) {
 		})
 	}
 
+	t.Run("must be a business error", func(t *testing.T) {
+		err := fmt.Errorf("wrapped: %w", NewFieldError(nil, "field", "code", "desc"))
+		assert.ErrorIs(t, err, ErrBusiness)
+		assert.False(t, errors.Is(err, ErrTechnical), "must not be a technical error")
+	})
+
 	// t.Run("need to return nil if has no FielErrors", func(t *testing.T) {
 	// 	Assert(t, 0, len(UnwrapFieldErrors(fmt.Errorf("%w", errors.New("error1")))), "")
 	// })
 
 }
+
+func TestMergeFieldErrors(t *testing.T) {
+	fieldsOf := func(err error) []string {
+		var fields []string
+		for _, f := range UnwrapFieldErrors(err) {
+			fields = append(fields, f.Field()+" "+f.Code())
+		}
+		return fields
+	}
+	err := NewFieldError(nil, "name", "no_empty", "can't be empty")
+	other := NewFieldError(NewFieldError(nil, "b", "code_b", "desc b"), "a", "code_a", "desc a")
+
+	assert.Equal(t, fieldsOf(NewFieldError(NewFieldError(err, "b", "code_b", "desc b"), "a", "code_a", "desc a")), fieldsOf(MergeFieldErrors(err, other)))
+	assert.Equal(t, fieldsOf(other), fieldsOf(MergeFieldErrors(nil, other)))
+	assert.Equal(t, err, MergeFieldErrors(err, nil))
+	assert.Nil(t, MergeFieldErrors(nil, nil))
+
+	assert.Equal(t, []string{"limit code_a", "limit code_b"}, fieldsOf(RenameFieldError(other, "limit")))
+	assert.Nil(t, RenameFieldError(nil, "limit"))
+}
@@ -0,0 +1,133 @@
+package entity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// ExchangeRate says how many To one From is worth since Date
+type ExchangeRate struct {
+	Date time.Time
+	From Currency
+	To   Currency
+	Rate *big.Rat
+}
+
+func (r ExchangeRate) Validate() error {
+	var err error
+	if r.Date.IsZero() {
+		err = NewFieldError(err, "date", "no_empty", "can't be empty")
+	}
+	if !r.From.IsValid() {
+		err = NewFieldError(err, "from", "invalid", fmt.Sprintf("unknown ISO 4217 currency %q", r.From))
+	}
+	if !r.To.IsValid() {
+		err = NewFieldError(err, "to", "invalid", fmt.Sprintf("unknown ISO 4217 currency %q", r.To))
+	}
+	if r.From == r.To {
+		err = NewFieldError(err, "to", "same_currency", "must be other than from")
+	}
+	if r.Rate == nil || r.Rate.Sign() <= 0 {
+		err = NewFieldError(err, "rate", "invalid", "must be greater than zero")
+	}
+	return err
+}
+
+type ExchangeRateFinder interface {
+	// Find returns the latest rate from -> to with Date on or before on
+	Find(ctx context.Context, from, to Currency, on time.Time) (ExchangeRate, error)
+}
+
+// Converter converts money to its base currency with the rate valid when it was spent
+type Converter struct {
+	base  Currency
+	rates ExchangeRateFinder
+}
+
+func NewConverter(base Currency, rates ExchangeRateFinder) *Converter {
+	return &Converter{
+		base:  base,
+		rates: rates,
+	}
+}
+
+func (c *Converter) Base() Currency {
+	return c.base
+}
+
+// Convert returns the expense amount in the base currency and the rate used
+func (c *Converter) Convert(ctx context.Context, e Expense) (Money, *big.Rat, error) {
+	return c.ConvertMoney(ctx, e.Money(), e.When)
+}
+
+// ConvertMoney uses the rate to the base currency, or the inverse of the rate from it
+func (c *Converter) ConvertMoney(ctx context.Context, m Money, on time.Time) (Money, *big.Rat, error) {
+	if !m.Currency.IsValid() {
+		return Money{}, nil, NewFieldError(nil, "currency", "invalid", fmt.Sprintf("unknown ISO 4217 currency %q", m.Currency))
+	}
+	if m.Currency == c.base {
+		return m, big.NewRat(1, 1), nil
+	}
+	rate, err := c.rate(ctx, m.Currency, on)
+	if err != nil {
+		return Money{}, nil, err
+	}
+	value := new(big.Rat).Mul(new(big.Rat).SetInt64(m.Amount), rate)
+	value.Mul(value, scale(c.base.MinorUnits()-m.Currency.MinorUnits()))
+	return Money{Amount: round(value), Currency: c.base}, rate, nil
+}
+
+func (c *Converter) rate(ctx context.Context, from Currency, on time.Time) (*big.Rat, error) {
+	r, err := c.rates.Find(ctx, from, c.base, on)
+	if err == nil {
+		return r.Rate, nil
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	r, err = c.rates.Find(ctx, c.base, from, on)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, fmt.Errorf("rate %s to %s on %s was %w", from, c.base, on.Format("2006-01-02"), ErrNotFound)
+		}
+		return nil, err
+	}
+	return new(big.Rat).Inv(r.Rate), nil
+}
+
+// scale returns 10^exp, exp can be negative
+func scale(exp int) *big.Rat {
+	pow := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(exp))), nil)
+	if exp < 0 {
+		return new(big.Rat).SetFrac(big.NewInt(1), pow)
+	}
+	return new(big.Rat).SetInt(pow)
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// round rounds half away from zero
+func round(r *big.Rat) int64 {
+	return roundInt(r).Int64()
+}
+
+// roundInt is round without the int64 limit
+func roundInt(r *big.Rat) *big.Int {
+	num := new(big.Int).Abs(r.Num())
+	q, m := new(big.Int).QuoRem(num, r.Denom(), new(big.Int))
+	if m.Mul(m, big.NewInt(2)).Cmp(r.Denom()) >= 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	if r.Sign() < 0 {
+		q.Neg(q)
+	}
+	return q
+}
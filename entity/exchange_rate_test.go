@@ -0,0 +1,55 @@
+package entity
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeRates map[string]*big.Rat
+
+func (f fakeRates) Find(_ context.Context, from, to Currency, _ time.Time) (ExchangeRate, error) {
+	rate, ok := f[string(from)+string(to)]
+	if !ok {
+		return ExchangeRate{}, fmt.Errorf("rate %s to %s was %w", from, to, ErrNotFound)
+	}
+	return ExchangeRate{From: from, To: to, Rate: rate}, nil
+}
+
+func TestConvert(t *testing.T) {
+	converter := NewConverter("BRL", fakeRates{
+		"USDBRL": big.NewRat(51234, 10000),
+		"BRLJPY": big.NewRat(20, 1),
+		"KWDBRL": big.NewRat(165, 10),
+	})
+	when := time.Date(2021, 5, 1, 10, 0, 0, 0, time.UTC)
+	tests := map[string]struct {
+		money    Money
+		want     int64
+		wantRate *big.Rat
+		wantErr  error
+	}{
+		"same currency":        {money: Money{1234, "BRL"}, want: 1234, wantRate: big.NewRat(1, 1)},
+		"direct rate":          {money: Money{1000, "USD"}, want: 5123, wantRate: big.NewRat(51234, 10000)},
+		"round half away":      {money: Money{-1000, "USD"}, want: -5123, wantRate: big.NewRat(51234, 10000)},
+		"inverse zero decimal": {money: Money{1500, "JPY"}, want: 7500, wantRate: big.NewRat(1, 20)},
+		"three decimals":       {money: Money{1001, "KWD"}, want: 1652, wantRate: big.NewRat(165, 10)},
+		"missing rate":         {money: Money{100, "EUR"}, wantErr: ErrNotFound},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, rate, err := converter.Convert(context.Background(), Expense{Amount: tc.money.Amount, Currency: tc.money.Currency, When: when})
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, Money{tc.want, "BRL"}, got)
+			assert.Equal(t, 0, tc.wantRate.Cmp(rate), "rate %v", rate)
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package entity
+
+import (
+	"strings"
+	"time"
+)
+
+// IdempotencyKey remembers the response to a request sent with an Idempotency-Key,
+// so retrying it gets the same response instead of doing it again
+type IdempotencyKey struct {
+	Key string
+	// RequestHash tells a retry apart from another request reusing the key
+	RequestHash string
+	// Status is 0 while the first request is still being answered
+	Status      int
+	ContentType string
+	Response    []byte
+	ExpiresAt   time.Time
+}
+
+func (k IdempotencyKey) Validate() error {
+	var err error
+	if strings.TrimSpace(k.Key) == "" {
+		err = NewFieldError(err, "idempotencyKey", "no_empty", "can't be empty")
+	}
+	return validateLength(err, "idempotencyKey", k.Key, maxTextLength)
+}
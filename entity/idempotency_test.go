@@ -0,0 +1,20 @@
+package entity
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotencyKeyValidate(t *testing.T) {
+	assert.NoError(t, IdempotencyKey{Key: "8e03978e-40d5-43e8-bc93-6894a57f9324"}.Validate())
+
+	var codes []string
+	for _, key := range []string{" ", strings.Repeat("a", maxTextLength+1)} {
+		for _, f := range UnwrapFieldErrors(IdempotencyKey{Key: key}.Validate()) {
+			codes = append(codes, f.Field()+" "+f.Code())
+		}
+	}
+	assert.Equal(t, []string{"idempotencyKey no_empty", "idempotencyKey too_long"}, codes)
+}
@@ -0,0 +1,76 @@
+package entity
+
+import (
+	"sort"
+	"strings"
+)
+
+// MetadataKeys returns the keys of metadata sorted, to read it always in the same order
+func MetadataKeys(metadata map[string]string) []string {
+	keys := make([]string, 0, len(metadata))
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func validateMetadataKey(err error, key string) (string, error) {
+	key = strings.Trim(key, " ")
+	if len(key) == 0 {
+		return "", NewFieldError(err, "metadata", "no_empty", "key can't be empty")
+	}
+	if strings.Contains(key, " ") {
+		return "", NewFieldError(err, "metadata."+key, "no_space", "key can't have spaces")
+	}
+	return key, nil
+}
+
+// NewMetadata trims the keys of values, they can't be empty nor have spaces like the tags
+func NewMetadata(values map[string]string) (map[string]string, error) {
+	var err error
+	metadata := make(map[string]string, len(values))
+	for _, key := range MetadataKeys(values) {
+		trimmed, keyErr := validateMetadataKey(err, key)
+		if keyErr != nil {
+			err = keyErr
+			continue
+		}
+		if _, ok := metadata[trimmed]; ok {
+			err = NewFieldError(err, "metadata."+trimmed, "duplicated", "key sent more than once")
+			continue
+		}
+		metadata[trimmed] = values[key]
+	}
+	if err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
+// MetadataFilter matches the transactions with Key, and its value when Type is set
+type MetadataFilter struct {
+	Key string
+	StrFilter
+}
+
+// FilterMetadataKey matches the transactions that have key, whatever its value
+func FilterMetadataKey(key string) func(*ExpenseFilter) error {
+	return func(e *ExpenseFilter) error {
+		if _, err := validateMetadataKey(nil, key); err != nil {
+			return err
+		}
+		e.Metadata = append(e.Metadata, MetadataFilter{Key: key})
+		return nil
+	}
+}
+
+func FilterMetadata(t StrFilterType, key, value string) func(*ExpenseFilter) error {
+	return func(e *ExpenseFilter) error {
+		if _, err := validateMetadataKey(nil, key); err != nil {
+			return err
+		}
+		e.Metadata = append(e.Metadata, MetadataFilter{Key: key, StrFilter: StrFilter{t, value}})
+		return nil
+	}
+}
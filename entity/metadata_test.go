@@ -0,0 +1,23 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewMetadata(t *testing.T) {
+	metadata, err := NewMetadata(map[string]string{" invoice ": "42", "project": ""})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"invoice": "42", "project": ""}, metadata)
+
+	_, err = NewMetadata(map[string]string{"invoice": "42", "invoice ": "43", " ": "a", "my key": "b"})
+	var fields []string
+	for _, f := range UnwrapFieldErrors(err) {
+		fields = append(fields, f.Field()+" "+f.Code())
+	}
+	assert.ElementsMatch(t, []string{"metadata no_empty", "metadata.invoice duplicated", "metadata.my key no_space"}, fields)
+
+	_, err = NewExpenseFilter(FilterMetadataKey(" "))
+	assert.Error(t, err, "must validate the filter keys")
+}
@@ -0,0 +1,69 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCurrency(t *testing.T) {
+	c, err := ParseCurrency(" usd ")
+	assert.NoError(t, err)
+	assert.Equal(t, Currency("USD"), c)
+
+	for _, code := range []string{"NGN", "KES", "PKR", "XOF"} {
+		_, err = ParseCurrency(code)
+		assert.NoError(t, err, "%s is in ISO 4217", code)
+	}
+
+	_, err = ParseCurrency("XAU")
+	assert.Error(t, err, "metals aren't currencies we spend")
+
+	_, err = ParseCurrency("XYZ")
+	var fieldErr FieldError
+	assert.ErrorAs(t, err, &fieldErr)
+	assert.Equal(t, "currency", fieldErr.Field())
+}
+
+func TestParseMoney(t *testing.T) {
+	tests := map[string]struct {
+		value    string
+		currency Currency
+		want     int64
+		wantCode string
+	}{
+		"two decimals":             {value: "12.34", currency: "BRL", want: 1234},
+		"pad missing decimals":     {value: "12.3", currency: "USD", want: 1230},
+		"no decimals":              {value: "12", currency: "EUR", want: 1200},
+		"zero decimal currency":    {value: "1500", currency: "JPY", want: 1500},
+		"three decimal currency":   {value: "1.5", currency: "KWD", want: 1500},
+		"four decimal currency":    {value: "1.5", currency: "CLF", want: 15000},
+		"negative":                 {value: "-0.05", currency: "BRL", want: -5},
+		"unknown currency default": {value: "1.2", want: 120},
+		"too many decimals":        {value: "1.5", currency: "JPY", wantCode: "invalid_precision"},
+		"comma":                    {value: "1,5", currency: "BRL", wantCode: "invalid"},
+		"empty":                    {value: "", currency: "BRL", wantCode: "invalid"},
+		"out of range":             {value: "99999999999999999999", currency: "BRL", wantCode: "invalid"},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseMoney(tc.value, tc.currency)
+			if tc.wantCode != "" {
+				var fieldErr FieldError
+				assert.ErrorAs(t, err, &fieldErr)
+				assert.Equal(t, tc.wantCode, fieldErr.Code())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, Money{Amount: tc.want, Currency: tc.currency}, got)
+		})
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	assert.Equal(t, "12.34", Money{1234, "BRL"}.String())
+	assert.Equal(t, "0.05", Money{5, "USD"}.String())
+	assert.Equal(t, "-0.05", Money{-5, "USD"}.String())
+	assert.Equal(t, "1500", Money{1500, "JPY"}.String())
+	assert.Equal(t, "1.500", Money{1500, "KWD"}.String())
+}
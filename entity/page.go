@@ -0,0 +1,84 @@
+package entity
+
+import (
+	"fmt"
+	"strings"
+)
+
+type SortField string
+
+const (
+	SORT_WHEN       SortField = "when"
+	SORT_AMOUNT     SortField = "amount"
+	SORT_CREATED_AT SortField = "createdAt"
+)
+
+var sortFields = []SortField{
+	SORT_WHEN,
+	SORT_AMOUNT,
+	SORT_CREATED_AT,
+}
+
+const (
+	DefaultPageLimit = 100
+	MaxPageLimit     = 1000
+)
+
+// Sort orders by Field and then by id, which is the order they were created in
+type Sort struct {
+	Field SortField
+	Desc  bool
+}
+
+// ParseSort reads a field like when, descending when it starts with a minus like -when.
+// An empty value sorts by when
+func ParseSort(value string) (Sort, error) {
+	s := Sort{Field: SortField(strings.TrimPrefix(value, "-")), Desc: strings.HasPrefix(value, "-")}
+	if value == "" {
+		s.Field = SORT_WHEN
+	}
+	return s, s.Validate()
+}
+
+func (s Sort) Validate() error {
+	for _, f := range sortFields {
+		if f == s.Field {
+			return nil
+		}
+	}
+	return NewFieldError(nil, "sort", "invalid", fmt.Sprintf("must be one of %v, with a minus to sort descending", sortFields))
+}
+
+func (s Sort) String() string {
+	if s.Desc {
+		return "-" + string(s.Field)
+	}
+	return string(s.Field)
+}
+
+// PageRequest asks for Limit expenses from Cursor on, the first page when it's empty.
+// A cursor only works with the filter and the sort of the page it came from
+type PageRequest struct {
+	Limit  int
+	Cursor string
+	Sort   Sort
+	// Count also asks for how many expenses match the filter
+	Count bool
+}
+
+func (p PageRequest) Validate() error {
+	var err error
+	if p.Limit < 1 || p.Limit > MaxPageLimit {
+		err = NewFieldError(err, "limit", "invalid", fmt.Sprintf("must be between 1 and %d", MaxPageLimit))
+	}
+	return MergeFieldErrors(err, p.Sort.Validate())
+}
+
+// Page has the cursors of the pages after and before it, empty when there's none
+type Page struct {
+	Expenses []Expense
+	Next     string
+	Prev     string
+	// Total is set when it was asked for with Count
+	Total *int64
+}
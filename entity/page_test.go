@@ -0,0 +1,37 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSort(t *testing.T) {
+	tests := map[string]struct {
+		value   string
+		want    Sort
+		wantErr bool
+	}{
+		"must sort by when when empty": {value: "", want: Sort{Field: SORT_WHEN}},
+		"must sort ascending":          {value: "amount", want: Sort{Field: SORT_AMOUNT}},
+		"must sort descending":         {value: "-createdAt", want: Sort{Field: SORT_CREATED_AT, Desc: true}},
+		"must not sort by other field": {value: "-what", want: Sort{Field: "what", Desc: true}, wantErr: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseSort(tc.value)
+			assert.Equal(t, tc.want, got)
+			if tc.wantErr {
+				fieldErrors := UnwrapFieldErrors(err)
+				if assert.Len(t, fieldErrors, 1) {
+					assert.Equal(t, "sort", fieldErrors[0].Field())
+				}
+				return
+			}
+			assert.NoError(t, err)
+			if tc.value != "" {
+				assert.Equal(t, tc.value, got.String(), "must format as it was parsed")
+			}
+		})
+	}
+}
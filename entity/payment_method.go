@@ -0,0 +1,50 @@
+package entity
+
+import (
+	"fmt"
+	"strings"
+)
+
+type PaymentMethodType string
+
+const (
+	CREDIT_CARD   PaymentMethodType = "credit_card"
+	DEBIT_CARD    PaymentMethodType = "debit_card"
+	CASH          PaymentMethodType = "cash"
+	PIX           PaymentMethodType = "pix"
+	BANK_TRANSFER PaymentMethodType = "bank_transfer"
+)
+
+var paymentMethodTypes = []PaymentMethodType{
+	CREDIT_CARD,
+	DEBIT_CARD,
+	CASH,
+	PIX,
+	BANK_TRANSFER,
+}
+
+func (t PaymentMethodType) IsValid() bool {
+	for _, v := range paymentMethodTypes {
+		if v == t {
+			return true
+		}
+	}
+	return false
+}
+
+type PaymentMethod struct {
+	Id   string
+	Name string
+	Type PaymentMethodType
+}
+
+func (p PaymentMethod) Validate() error {
+	var err error
+	if strings.TrimSpace(p.Name) == "" {
+		err = NewFieldError(err, "name", "no_empty", "can't be empty")
+	}
+	if !p.Type.IsValid() {
+		err = NewFieldError(err, "type", "invalid", fmt.Sprintf("must be one of %v", paymentMethodTypes))
+	}
+	return err
+}
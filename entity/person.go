@@ -0,0 +1,25 @@
+package entity
+
+import (
+	"strings"
+)
+
+// Person is who paid or received the money. The free text of Expense.Who
+// is matched against its name and aliases
+type Person struct {
+	Id      string
+	Name    string
+	Aliases []string
+}
+
+func (p Person) URN() URN {
+	return NewURN(PERSON, p.Id)
+}
+
+func (p Person) Validate() error {
+	var err error
+	if strings.TrimSpace(p.Name) == "" {
+		err = NewFieldError(err, "name", "no_empty", "can't be empty")
+	}
+	return validateAliases(err, p.Name, p.Aliases)
+}
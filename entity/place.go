@@ -0,0 +1,46 @@
+package entity
+
+import (
+	"strings"
+)
+
+type GeoPoint struct {
+	Latitude  float64
+	Longitude float64
+}
+
+func (g GeoPoint) Validate() error {
+	var err error
+	if g.Latitude < -90 || g.Latitude > 90 {
+		err = NewFieldError(err, "location.latitude", "invalid", "must be between -90 and 90")
+	}
+	if g.Longitude < -180 || g.Longitude > 180 {
+		err = NewFieldError(err, "location.longitude", "invalid", "must be between -180 and 180")
+	}
+	return err
+}
+
+// Place is where the money was spent, like a merchant. The free text of
+// Expense.Where is matched against its name and aliases
+type Place struct {
+	Id       string
+	Name     string
+	Aliases  []string
+	Location *GeoPoint
+}
+
+func (p Place) URN() URN {
+	return NewURN(PLACE, p.Id)
+}
+
+func (p Place) Validate() error {
+	var err error
+	if strings.TrimSpace(p.Name) == "" {
+		err = NewFieldError(err, "name", "no_empty", "can't be empty")
+	}
+	err = validateAliases(err, p.Name, p.Aliases)
+	if p.Location != nil {
+		err = MergeFieldErrors(err, p.Location.Validate())
+	}
+	return err
+}
@@ -0,0 +1,165 @@
+package entity
+
+import (
+	"fmt"
+	"time"
+)
+
+type Frequency string
+
+const (
+	DAILY   Frequency = "daily"
+	WEEKLY  Frequency = "weekly"
+	MONTHLY Frequency = "monthly"
+	YEARLY  Frequency = "yearly"
+)
+
+var frequencies = []Frequency{
+	DAILY,
+	WEEKLY,
+	MONTHLY,
+	YEARLY,
+}
+
+func (f Frequency) IsValid() bool {
+	for _, v := range frequencies {
+		if v == f {
+			return true
+		}
+	}
+	return false
+}
+
+// Schedule repeats every Interval days, weeks, months or years from Start,
+// until Until or Count occurrences when they are set
+type Schedule struct {
+	Frequency Frequency
+	Interval  int
+	// DayOfMonth is the day of monthly occurrences, the last day on shorter months. Start's day when 0
+	DayOfMonth int
+	Start      time.Time
+	Until      time.Time
+	Count      int
+}
+
+func (s Schedule) Validate() error {
+	var err error
+	if !s.Frequency.IsValid() {
+		err = NewFieldError(err, "frequency", "invalid", fmt.Sprintf("must be one of %v", frequencies))
+	}
+	if s.Interval < 0 {
+		err = NewFieldError(err, "interval", "invalid", "can't be negative")
+	}
+	if s.DayOfMonth < 0 || s.DayOfMonth > 31 {
+		err = NewFieldError(err, "dayOfMonth", "invalid", "must be between 1 and 31")
+	}
+	if s.DayOfMonth != 0 && s.Frequency != MONTHLY {
+		err = NewFieldError(err, "dayOfMonth", "not_allowed", "only monthly schedules have a day of month")
+	}
+	if s.Start.IsZero() {
+		err = NewFieldError(err, "start", "no_empty", "can't be empty")
+	}
+	if !s.Until.IsZero() && s.Until.Before(s.Start) {
+		err = NewFieldError(err, "until", "invalid", "can't be before start")
+	}
+	if s.Count < 0 {
+		err = NewFieldError(err, "count", "invalid", "can't be negative")
+	}
+	return err
+}
+
+func (s Schedule) interval() int {
+	if s.Interval == 0 {
+		return 1
+	}
+	return s.Interval
+}
+
+// Occurrence returns when the nth occurrence happens, the first one is 0
+func (s Schedule) Occurrence(n int) time.Time {
+	step := n * s.interval()
+	switch s.Frequency {
+	case DAILY:
+		return s.Start.AddDate(0, 0, step)
+	case WEEKLY:
+		return s.Start.AddDate(0, 0, 7*step)
+	case MONTHLY:
+		day := s.DayOfMonth
+		if day == 0 {
+			day = s.Start.Day()
+		}
+		return dateClamped(s.Start, s.Start.Year(), s.Start.Month()+time.Month(step), day)
+	case YEARLY:
+		return dateClamped(s.Start, s.Start.Year()+step, s.Start.Month(), s.Start.Day())
+	}
+	return time.Time{}
+}
+
+// Ended says if the schedule has no nth occurrence
+func (s Schedule) Ended(n int) bool {
+	if s.Count > 0 && n >= s.Count {
+		return true
+	}
+	return !s.Until.IsZero() && s.Occurrence(n).After(s.Until)
+}
+
+// Due returns the occurrences from next on that already happened at now
+func (s Schedule) Due(next int, now time.Time) []int {
+	var due []int
+	for n := next; !s.Ended(n) && !s.Occurrence(n).After(now); n++ {
+		due = append(due, n)
+	}
+	return due
+}
+
+// dateClamped builds the date with the clock of t, using the last day of the month when day is past it
+func dateClamped(t time.Time, year int, month time.Month, day int) time.Time {
+	first := time.Date(year, month, 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	if last := first.AddDate(0, 1, -1).Day(); day > last {
+		day = last
+	}
+	return first.AddDate(0, 0, day-1)
+}
+
+// RecurringExpense creates a copy of Template on every occurrence of Schedule
+type RecurringExpense struct {
+	Id       string
+	Template Expense
+	Schedule Schedule
+	// NextOccurrence is the first occurrence not created yet
+	NextOccurrence int
+}
+
+// Validate checks the template like the expenses it creates, its date is set by each occurrence
+func (r RecurringExpense) Validate() error {
+	err := r.Schedule.Validate()
+	template := r.Template
+	template.When = time.Time{}
+	return MergeFieldErrors(err, template.Validate())
+}
+
+// NewOccurrence returns the expense of the nth occurrence
+func (r RecurringExpense) NewOccurrence(n int) Expense {
+	e := r.Template
+	e.Id = ""
+	e.When = r.Schedule.Occurrence(n)
+	e.RecurringId = r.Id
+	e.Occurrence = n
+	if r.Template.Tags != nil {
+		e.Tags = MustNewTags(r.Template.Tags.Value()...)
+	}
+	if r.Template.Split != nil {
+		split := Split{Kind: r.Template.Split.Kind, Shares: append([]Share{}, r.Template.Split.Shares...)}
+		e.Split = &split
+	}
+	if r.Template.Details != nil {
+		e.Details = append([]Detail{}, r.Template.Details...)
+	}
+	if r.Template.Metadata != nil {
+		e.Metadata = make(map[string]string, len(r.Template.Metadata))
+		for key, value := range r.Template.Metadata {
+			e.Metadata[key] = value
+		}
+	}
+	return e
+}
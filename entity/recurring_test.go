@@ -0,0 +1,124 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduleOccurrence(t *testing.T) {
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 10, 0, 0, 0, time.UTC)
+	}
+	tests := map[string]struct {
+		schedule Schedule
+		want     []time.Time
+	}{
+		"daily every 3 days": {
+			schedule: Schedule{Frequency: DAILY, Interval: 3, Start: date(2021, 1, 30)},
+			want:     []time.Time{date(2021, 1, 30), date(2021, 2, 2), date(2021, 2, 5)},
+		},
+		"weekly": {
+			schedule: Schedule{Frequency: WEEKLY, Start: date(2021, 12, 25)},
+			want:     []time.Time{date(2021, 12, 25), date(2022, 1, 1), date(2022, 1, 8)},
+		},
+		"monthly on the last day": {
+			schedule: Schedule{Frequency: MONTHLY, DayOfMonth: 31, Start: date(2021, 1, 31)},
+			want:     []time.Time{date(2021, 1, 31), date(2021, 2, 28), date(2021, 3, 31), date(2021, 4, 30)},
+		},
+		"monthly from the start day": {
+			schedule: Schedule{Frequency: MONTHLY, Interval: 2, Start: date(2021, 11, 30)},
+			want:     []time.Time{date(2021, 11, 30), date(2022, 1, 30), date(2022, 3, 30)},
+		},
+		"yearly on february 29": {
+			schedule: Schedule{Frequency: YEARLY, Start: date(2020, 2, 29)},
+			want:     []time.Time{date(2020, 2, 29), date(2021, 2, 28), date(2022, 2, 28), date(2023, 2, 28), date(2024, 2, 29)},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			for n, want := range tc.want {
+				assert.Equal(t, want, tc.schedule.Occurrence(n), "occurrence %d", n)
+			}
+		})
+	}
+}
+
+func TestScheduleDue(t *testing.T) {
+	start := time.Date(2021, 1, 5, 0, 0, 0, 0, time.UTC)
+	now := time.Date(2021, 4, 5, 0, 0, 0, 0, time.UTC)
+	tests := map[string]struct {
+		schedule Schedule
+		next     int
+		want     []int
+	}{
+		"all due": {
+			schedule: Schedule{Frequency: MONTHLY, Start: start},
+			want:     []int{0, 1, 2, 3},
+		},
+		"from next": {
+			schedule: Schedule{Frequency: MONTHLY, Start: start},
+			next:     2,
+			want:     []int{2, 3},
+		},
+		"until count": {
+			schedule: Schedule{Frequency: MONTHLY, Start: start, Count: 2},
+			want:     []int{0, 1},
+		},
+		"until date": {
+			schedule: Schedule{Frequency: MONTHLY, Start: start, Until: time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)},
+			want:     []int{0, 1},
+		},
+		"ended": {
+			schedule: Schedule{Frequency: MONTHLY, Start: start, Count: 2},
+			next:     2,
+		},
+		"not started": {
+			schedule: Schedule{Frequency: MONTHLY, Start: now.AddDate(0, 0, 1)},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.want, tc.schedule.Due(tc.next, now))
+		})
+	}
+}
+
+func TestRecurringExpenseValidate(t *testing.T) {
+	r := RecurringExpense{
+		Template: Expense{Kind: EXPENSE, ToPaymentMethodId: "savings"},
+		Schedule: Schedule{Frequency: "hourly", DayOfMonth: 5},
+	}
+	var fields []string
+	for _, f := range UnwrapFieldErrors(r.Validate()) {
+		fields = append(fields, f.Field())
+	}
+	assert.ElementsMatch(t, []string{"frequency", "dayOfMonth", "start", "amount", "toPaymentMethodId"}, fields)
+
+	r = RecurringExpense{
+		Template: Expense{Kind: EXPENSE, Amount: 150000},
+		Schedule: Schedule{Frequency: MONTHLY, DayOfMonth: 5, Start: time.Now()},
+	}
+	assert.NoError(t, r.Validate())
+}
+
+func TestRecurringExpenseNewOccurrence(t *testing.T) {
+	r := RecurringExpense{
+		Id:       "rent",
+		Template: Expense{Id: "template", Kind: EXPENSE, Amount: 150000, What: "rent", Tags: MustNewTags("home")},
+		Schedule: Schedule{Frequency: MONTHLY, Start: time.Date(2021, 1, 5, 0, 0, 0, 0, time.UTC)},
+	}
+	got := r.NewOccurrence(2)
+	assert.Equal(t, Expense{
+		Kind:        EXPENSE,
+		Amount:      150000,
+		What:        "rent",
+		Tags:        MustNewTags("home"),
+		When:        time.Date(2021, 3, 5, 0, 0, 0, 0, time.UTC),
+		RecurringId: "rent",
+		Occurrence:  2,
+	}, got)
+	got.Tags.Add("other")
+	assert.Equal(t, []string{"home"}, r.Template.Tags.Value(), "must not share the template tags")
+}
@@ -0,0 +1,51 @@
+package entity
+
+import "fmt"
+
+// SummaryGroup is what the expenses are totaled by
+type SummaryGroup string
+
+const (
+	BY_MONTH    SummaryGroup = "month"
+	BY_WEEK     SummaryGroup = "week"
+	BY_CATEGORY SummaryGroup = "category"
+	BY_TAG      SummaryGroup = "tag"
+	BY_WHO      SummaryGroup = "who"
+	BY_WHERE    SummaryGroup = "where"
+)
+
+var summaryGroups = []SummaryGroup{
+	BY_MONTH,
+	BY_WEEK,
+	BY_CATEGORY,
+	BY_TAG,
+	BY_WHO,
+	BY_WHERE,
+}
+
+func (g SummaryGroup) IsValid() bool {
+	for _, v := range summaryGroups {
+		if v == g {
+			return true
+		}
+	}
+	return false
+}
+
+func (g SummaryGroup) Validate() error {
+	if !g.IsValid() {
+		return NewFieldError(nil, "groupBy", "invalid", fmt.Sprintf("must be one of %v", summaryGroups))
+	}
+	return nil
+}
+
+// Summary totals the expenses of a group in one currency. Key is the month as YYYY-MM, the monday
+// of the week as YYYY-MM-DD, the category id or the tag, empty when unset. For who and where it's
+// the URN of the person or place linked, like urn:place:<id>, or the free text without a link.
+// An expense with many tags counts in every one of them
+type Summary struct {
+	Key      string
+	Currency Currency
+	Total    int64
+	Count    int64
+}
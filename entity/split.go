@@ -0,0 +1,288 @@
+package entity
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+)
+
+type SplitKind string
+
+const (
+	EQUAL   SplitKind = "equal"
+	PERCENT SplitKind = "percent"
+	EXACT   SplitKind = "exact"
+)
+
+var splitKinds = []SplitKind{
+	EQUAL,
+	PERCENT,
+	EXACT,
+}
+
+func (k SplitKind) IsValid() bool {
+	for _, v := range splitKinds {
+		if v == k {
+			return true
+		}
+	}
+	return false
+}
+
+// WholePercent is 100% in the unit of Share.Percent, hundredths of a percent
+const WholePercent = 10000
+
+// Share is the part of one person on a split
+type Share struct {
+	Who string
+	// Percent is in hundredths of a percent, only on percent splits
+	Percent int64
+	// Amount is sent on exact splits and allocated on the others
+	Amount int64
+}
+
+// Split divides the amount of a transaction between people, Transaction.Who is who paid it
+type Split struct {
+	Kind   SplitKind
+	Shares []Share
+}
+
+func (s Split) validate(amount int64) error {
+	var err error
+	if !s.Kind.IsValid() {
+		return NewFieldError(err, "split.kind", "invalid", fmt.Sprintf("must be one of %v", splitKinds))
+	}
+	if amount < 0 {
+		return NewFieldError(err, "amount", "invalid", "can't split a negative amount")
+	}
+	if len(s.Shares) == 0 {
+		return NewFieldError(err, "split.shares", "no_empty", "can't be empty")
+	}
+	seen := make(map[string]bool, len(s.Shares))
+	var percent, total int64
+	for i, share := range s.Shares {
+		field := fmt.Sprintf("split.shares[%d]", i)
+		who := strings.TrimSpace(share.Who)
+		if who == "" {
+			err = NewFieldError(err, field+".who", "no_empty", "can't be empty")
+		} else if seen[who] {
+			err = NewFieldError(err, field+".who", "duplicated", fmt.Sprintf("%s has more than one share", who))
+		}
+		seen[who] = true
+		if s.Kind == PERCENT && share.Percent <= 0 {
+			err = NewFieldError(err, field+".percent", "invalid", "must be greater than zero")
+		}
+		if s.Kind != PERCENT && share.Percent != 0 {
+			err = NewFieldError(err, field+".percent", "not_allowed", "only percent splits have a percent")
+		}
+		if s.Kind == EXACT && share.Amount < 0 {
+			err = NewFieldError(err, field+".amount", "invalid", "can't be negative")
+		}
+		percent += share.Percent
+		total += share.Amount
+	}
+	if err != nil {
+		return err
+	}
+	if s.Kind == PERCENT && percent != WholePercent {
+		err = NewFieldError(err, "split.shares", "invalid_sum", "the percents must sum 100")
+	}
+	if s.Kind == EXACT && total != amount {
+		err = NewFieldError(err, "split.shares", "invalid_sum", "the amounts must sum the amount")
+	}
+	return err
+}
+
+// Allocate returns a copy of the split with the amount of every share, summing exactly amount.
+// Only exact splits keep the amounts they have, so a split already allocated can be allocated again.
+// The remainder of equal splits goes one minor unit each to the first shares and the one of
+// percent splits to the largest fractions, the first share on a tie
+func (s Split) Allocate(amount int64) (Split, error) {
+	if err := s.validate(amount); err != nil {
+		return Split{}, err
+	}
+	res := Split{Kind: s.Kind, Shares: make([]Share, len(s.Shares))}
+	copy(res.Shares, s.Shares)
+	for i := range res.Shares {
+		res.Shares[i].Who = strings.TrimSpace(res.Shares[i].Who)
+	}
+	n := int64(len(res.Shares))
+	switch s.Kind {
+	case EQUAL:
+		for i := range res.Shares {
+			res.Shares[i].Amount = amount / n
+			if int64(i) < amount%n {
+				res.Shares[i].Amount++
+			}
+		}
+	case PERCENT:
+		// amount * percent doesn't fit an int64 on large amounts
+		remainder := amount
+		fractions := make([]int64, n)
+		for i, share := range res.Shares {
+			q, m := new(big.Int).QuoRem(
+				new(big.Int).Mul(big.NewInt(amount), big.NewInt(share.Percent)),
+				big.NewInt(WholePercent),
+				new(big.Int),
+			)
+			res.Shares[i].Amount = q.Int64()
+			fractions[i] = m.Int64()
+			remainder -= res.Shares[i].Amount
+		}
+		order := make([]int, n)
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(a, b int) bool {
+			return fractions[order[a]] > fractions[order[b]]
+		})
+		for _, i := range order[:remainder] {
+			res.Shares[i].Amount++
+		}
+	}
+	return res, nil
+}
+
+// AllocateSplit validates the split of the transaction and fills the amount of its shares
+func (t *Transaction) AllocateSplit() error {
+	if t.Split == nil {
+		return nil
+	}
+	if strings.TrimSpace(t.Who) == "" {
+		return NewFieldError(nil, "who", "no_empty", "can't be empty on a split, it's who paid")
+	}
+	split, err := t.Split.Allocate(t.Amount)
+	if err != nil {
+		return err
+	}
+	t.Split = &split
+	return nil
+}
+
+// Balance is how much a person is owed on a currency, negative when the person owes
+type Balance struct {
+	Who      string
+	Amount   int64
+	Currency Currency
+}
+
+// Debt is a payment that settles the balances
+type Debt struct {
+	From     string
+	To       string
+	Amount   int64
+	Currency Currency
+}
+
+// Balances sums what every person paid minus their shares, only split expenses and settlements
+// count. The result is sorted by currency and person, without the ones already settled
+func Balances(transactions []Transaction) []Balance {
+	type key struct {
+		who      string
+		currency Currency
+	}
+	sums := make(map[key]int64)
+	for _, t := range transactions {
+		if t.Split == nil || !t.IsSplittable() {
+			continue
+		}
+		for _, share := range t.Split.Shares {
+			sums[key{t.Who, t.Currency}] += share.Amount
+			sums[key{share.Who, t.Currency}] -= share.Amount
+		}
+	}
+	balances := make([]Balance, 0, len(sums))
+	for k, amount := range sums {
+		if amount != 0 {
+			balances = append(balances, Balance{Who: k.who, Amount: amount, Currency: k.currency})
+		}
+	}
+	sort.Slice(balances, func(i, j int) bool {
+		if balances[i].Currency != balances[j].Currency {
+			return balances[i].Currency < balances[j].Currency
+		}
+		return balances[i].Who < balances[j].Who
+	})
+	return balances
+}
+
+// Debts returns who pays whom to settle the balances, matching the largest debtor with
+// the largest creditor of each currency
+func Debts(balances []Balance) []Debt {
+	byCurrency := make(map[Currency][]Balance)
+	var currencies []Currency
+	for _, b := range balances {
+		if _, ok := byCurrency[b.Currency]; !ok {
+			currencies = append(currencies, b.Currency)
+		}
+		byCurrency[b.Currency] = append(byCurrency[b.Currency], b)
+	}
+	sort.Slice(currencies, func(i, j int) bool { return currencies[i] < currencies[j] })
+	debts := make([]Debt, 0)
+	for _, currency := range currencies {
+		var debtors, creditors []Balance
+		for _, b := range byCurrency[currency] {
+			if b.Amount < 0 {
+				debtors = append(debtors, Balance{Who: b.Who, Amount: -b.Amount, Currency: currency})
+			} else if b.Amount > 0 {
+				creditors = append(creditors, b)
+			}
+		}
+		sortLargest(debtors)
+		sortLargest(creditors)
+		for len(debtors) > 0 && len(creditors) > 0 {
+			amount := debtors[0].Amount
+			if creditors[0].Amount < amount {
+				amount = creditors[0].Amount
+			}
+			debts = append(debts, Debt{From: debtors[0].Who, To: creditors[0].Who, Amount: amount, Currency: currency})
+			debtors[0].Amount -= amount
+			creditors[0].Amount -= amount
+			if debtors[0].Amount == 0 {
+				debtors = debtors[1:]
+			}
+			if creditors[0].Amount == 0 {
+				creditors = creditors[1:]
+			}
+			sortLargest(debtors)
+			sortLargest(creditors)
+		}
+	}
+	return debts
+}
+
+func sortLargest(balances []Balance) {
+	sort.SliceStable(balances, func(i, j int) bool {
+		if balances[i].Amount != balances[j].Amount {
+			return balances[i].Amount > balances[j].Amount
+		}
+		return balances[i].Who < balances[j].Who
+	})
+}
+
+// NewSettlement records that from paid amount to to, which zeroes that much of their debt
+func NewSettlement(from, to string, money Money) Transaction {
+	return Transaction{
+		Kind:     SETTLEMENT,
+		Amount:   money.Amount,
+		Currency: money.Currency,
+		Who:      from,
+		What:     fmt.Sprintf("%s settles up with %s", from, to),
+		Split:    &Split{Kind: EXACT, Shares: []Share{{Who: to, Amount: money.Amount}}},
+	}
+}
+
+// ParsePercent reads a percent with up to 2 decimal places, like "33.33", in hundredths of a percent
+func ParsePercent(value string) (int64, error) {
+	percent, code := parseDecimal(value, 2)
+	if code != "" || percent < 0 {
+		return 0, NewFieldError(nil, "percent", "invalid", fmt.Sprintf("%q is not a percent with up to 2 decimal places", value))
+	}
+	return percent, nil
+}
+
+// FormatPercent is the inverse of ParsePercent
+func FormatPercent(percent int64) string {
+	return Money{Amount: percent}.String()
+}
@@ -0,0 +1,159 @@
+package entity
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitAllocate(t *testing.T) {
+	tests := map[string]struct {
+		split      Split
+		amount     int64
+		want       []int64
+		wantFields []string
+	}{
+		"equal gives the remainder to the first shares": {
+			split:  Split{Kind: EQUAL, Shares: []Share{{Who: "ana"}, {Who: "bob"}, {Who: "carl"}}},
+			amount: 1000,
+			want:   []int64{334, 333, 333},
+		},
+		"equal without remainder": {
+			split:  Split{Kind: EQUAL, Shares: []Share{{Who: "ana"}, {Who: "bob"}}},
+			amount: 1000,
+			want:   []int64{500, 500},
+		},
+		"percent gives the remainder to the largest fractions": {
+			split: Split{Kind: PERCENT, Shares: []Share{
+				{Who: "ana", Percent: 3333},
+				{Who: "bob", Percent: 3333},
+				{Who: "carl", Percent: 3334},
+			}},
+			amount: 100,
+			// 33.33, 33.33 and 33.34
+			want: []int64{33, 33, 34},
+		},
+		"percent tie goes to the first share": {
+			split:  Split{Kind: PERCENT, Shares: []Share{{Who: "ana", Percent: 5000}, {Who: "bob", Percent: 5000}}},
+			amount: 101,
+			want:   []int64{51, 50},
+		},
+		"percent of a large amount": {
+			split: Split{Kind: PERCENT, Shares: []Share{
+				{Who: "ana", Percent: 3333},
+				{Who: "bob", Percent: 3333},
+				{Who: "carl", Percent: 3334},
+			}},
+			amount: math.MaxInt64,
+			want:   []int64{3074149899883696777, 3074149899883696776, 3075072237087382254},
+		},
+		"exact": {
+			split:  Split{Kind: EXACT, Shares: []Share{{Who: "ana", Amount: 700}, {Who: "bob", Amount: 300}}},
+			amount: 1000,
+			want:   []int64{700, 300},
+		},
+		"exact must sum the amount": {
+			split:      Split{Kind: EXACT, Shares: []Share{{Who: "ana", Amount: 700}, {Who: "bob", Amount: 200}}},
+			amount:     1000,
+			wantFields: []string{"split.shares"},
+		},
+		"percent must sum 100": {
+			split:      Split{Kind: PERCENT, Shares: []Share{{Who: "ana", Percent: 5000}, {Who: "bob", Percent: 4000}}},
+			amount:     1000,
+			wantFields: []string{"split.shares"},
+		},
+		"invalid shares": {
+			split:      Split{Kind: EQUAL, Shares: []Share{{Who: "ana"}, {Who: " "}, {Who: "ana", Percent: 10}}},
+			amount:     1000,
+			wantFields: []string{"split.shares[1].who", "split.shares[2].who", "split.shares[2].percent"},
+		},
+		"invalid kind": {
+			split:      Split{Kind: "half"},
+			wantFields: []string{"split.kind"},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := tc.split.Allocate(tc.amount)
+			if tc.wantFields != nil {
+				var fields []string
+				for _, f := range UnwrapFieldErrors(err) {
+					fields = append(fields, f.Field())
+				}
+				assert.ElementsMatch(t, tc.wantFields, fields)
+				return
+			}
+			assert.NoError(t, err)
+			var amounts []int64
+			var sum int64
+			for _, share := range got.Shares {
+				amounts = append(amounts, share.Amount)
+				sum += share.Amount
+			}
+			assert.Equal(t, tc.want, amounts)
+			assert.Equal(t, tc.amount, sum)
+		})
+	}
+}
+
+func TestBalances(t *testing.T) {
+	transactions := []Transaction{
+		{Who: "ana", Amount: 900, Currency: "BRL", Split: &Split{Kind: EQUAL, Shares: []Share{
+			{Who: "ana", Amount: 300}, {Who: "bob", Amount: 300}, {Who: "carl", Amount: 300},
+		}}},
+		{Who: "bob", Amount: 300, Currency: "BRL", Split: &Split{Kind: EXACT, Shares: []Share{
+			{Who: "carl", Amount: 300},
+		}}},
+		{Who: "carl", Amount: 1000, Currency: "JPY", Split: &Split{Kind: EXACT, Shares: []Share{
+			{Who: "ana", Amount: 1000},
+		}}},
+		{Who: "ana", Amount: 5000, Currency: "BRL"},
+		// stored before incomes were refused a split
+		{Kind: INCOME, Who: "bob", Amount: 600, Currency: "BRL", Split: &Split{Kind: EQUAL, Shares: []Share{
+			{Who: "ana", Amount: 300}, {Who: "bob", Amount: 300},
+		}}},
+	}
+	balances := Balances(transactions)
+	assert.Equal(t, []Balance{
+		{Who: "ana", Amount: 600, Currency: "BRL"},
+		{Who: "carl", Amount: -600, Currency: "BRL"},
+		{Who: "ana", Amount: -1000, Currency: "JPY"},
+		{Who: "carl", Amount: 1000, Currency: "JPY"},
+	}, balances)
+	assert.Equal(t, []Debt{
+		{From: "carl", To: "ana", Amount: 600, Currency: "BRL"},
+		{From: "ana", To: "carl", Amount: 1000, Currency: "JPY"},
+	}, Debts(balances))
+
+	transactions = append(transactions, NewSettlement("carl", "ana", Money{Amount: 600, Currency: "BRL"}))
+	assert.Equal(t, []Balance{
+		{Who: "ana", Amount: -1000, Currency: "JPY"},
+		{Who: "carl", Amount: 1000, Currency: "JPY"},
+	}, Balances(transactions), "the settlement zeroes the debt")
+}
+
+func TestDebts(t *testing.T) {
+	got := Debts([]Balance{
+		{Who: "ana", Amount: 500, Currency: "BRL"},
+		{Who: "bob", Amount: -300, Currency: "BRL"},
+		{Who: "carl", Amount: -200, Currency: "BRL"},
+		{Who: "dan", Amount: 0, Currency: "BRL"},
+	})
+	assert.Equal(t, []Debt{
+		{From: "bob", To: "ana", Amount: 300, Currency: "BRL"},
+		{From: "carl", To: "ana", Amount: 200, Currency: "BRL"},
+	}, got)
+}
+
+func TestParsePercent(t *testing.T) {
+	got, err := ParsePercent("33.33")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3333), got)
+	assert.Equal(t, "33.33", FormatPercent(got))
+
+	for _, invalid := range []string{"33.333", "-10", "abc"} {
+		_, err := ParsePercent(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
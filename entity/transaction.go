@@ -0,0 +1,161 @@
+package entity
+
+import (
+	"fmt"
+	"time"
+	"unicode/utf8"
+)
+
+type TransactionKind string
+
+const (
+	EXPENSE  TransactionKind = "expense"
+	INCOME   TransactionKind = "income"
+	TRANSFER TransactionKind = "transfer"
+	// SETTLEMENT is a payment between people that settles the balances of split transactions
+	SETTLEMENT TransactionKind = "settlement"
+)
+
+var transactionKinds = []TransactionKind{
+	EXPENSE,
+	INCOME,
+	TRANSFER,
+	SETTLEMENT,
+}
+
+func (k TransactionKind) IsValid() bool {
+	for _, v := range transactionKinds {
+		if v == k {
+			return true
+		}
+	}
+	return false
+}
+
+// KindOrDefault returns the kind of the transaction, the ones stored before kinds existed are expenses
+func (t Transaction) KindOrDefault() TransactionKind {
+	if t.Kind == "" {
+		return EXPENSE
+	}
+	return t.Kind
+}
+
+// IsSpending says if the transaction counts as spending, transfers only move money between our accounts
+func (t Transaction) IsSpending() bool {
+	return t.KindOrDefault() == EXPENSE
+}
+
+// IsSplittable says if the transaction can be split, incomes and transfers aren't owed by anyone
+func (t Transaction) IsSplittable() bool {
+	kind := t.KindOrDefault()
+	return kind == EXPENSE || kind == SETTLEMENT
+}
+
+// ValidateKind checks the fields that depend on the kind, a transfer needs both accounts,
+// a settlement is paid to one person and only expenses and settlements are split
+func (t Transaction) ValidateKind() error {
+	var err error
+	kind := t.KindOrDefault()
+	if !kind.IsValid() {
+		return NewFieldError(err, "kind", "invalid", fmt.Sprintf("must be one of %v", transactionKinds))
+	}
+	if t.Split != nil && !t.IsSplittable() {
+		err = NewFieldError(err, "split", "not_allowed", "only expenses and settlements are split")
+	}
+	if kind == SETTLEMENT && (t.Split == nil || t.Split.Kind != EXACT || len(t.Split.Shares) != 1) {
+		err = NewFieldError(err, "split", "invalid", "a settlement is an exact split with one share")
+	}
+	if kind != TRANSFER {
+		if t.ToPaymentMethodId != "" {
+			err = NewFieldError(err, "toPaymentMethodId", "not_allowed", "only transfers have a destination")
+		}
+		return err
+	}
+	if t.PaymentMethodId == "" {
+		err = NewFieldError(err, "paymentMethodId", "no_empty", "can't be empty on a transfer")
+	}
+	if t.ToPaymentMethodId == "" {
+		err = NewFieldError(err, "toPaymentMethodId", "no_empty", "can't be empty on a transfer")
+	}
+	if t.PaymentMethodId != "" && t.PaymentMethodId == t.ToPaymentMethodId {
+		err = NewFieldError(err, "toPaymentMethodId", "same_account", "must be other than paymentMethodId")
+	}
+	return err
+}
+
+// The longest ids and texts the columns of ops/db/create_tables.sql hold, in characters
+const (
+	maxIdLength   = 128
+	maxTextLength = 255
+)
+
+// minWhen and maxWhenAhead are the window of a sensible date for a transaction, from a past
+// long gone to what can be planned ahead
+var (
+	minWhen      = time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC)
+	maxWhenAhead = 1
+)
+
+func validateLength(err error, field, value string, max int) error {
+	if utf8.RuneCountInString(value) > max {
+		return NewFieldError(err, field, "too_long", fmt.Sprintf("can't have more than %d characters", max))
+	}
+	return err
+}
+
+// Validate checks everything the transaction needs before it's stored: an amount, the lengths
+// the columns hold, a date that makes sense and the fields of its kind and of its details.
+// The date and the currency are optional
+func (t Transaction) Validate() error {
+	var err error
+	if t.Amount == 0 {
+		err = NewFieldError(err, "amount", "no_empty", "can't be empty")
+	} else if t.Amount < 0 {
+		err = NewFieldError(err, "amount", "invalid", "must be greater than zero")
+	}
+	if t.Currency != "" && !t.Currency.IsValid() {
+		err = NewFieldError(err, "currency", "invalid", fmt.Sprintf("unknown ISO 4217 currency %q", t.Currency))
+	}
+	if !t.When.IsZero() {
+		maxWhen := time.Now().AddDate(maxWhenAhead, 0, 0)
+		if t.When.Before(minWhen) || t.When.After(maxWhen) {
+			err = NewFieldError(err, "when", "out_of_range",
+				fmt.Sprintf("must be between %s and %d year from now", minWhen.Format("2006-01-02"), maxWhenAhead))
+		}
+	}
+	for _, text := range []struct{ field, value string }{
+		{"where", t.Where},
+		{"who", t.Who},
+		{"what", t.What},
+		{"externalId", t.ExternalId},
+	} {
+		err = validateLength(err, text.field, text.value, maxTextLength)
+	}
+	for _, id := range []struct{ field, value string }{
+		{"categoryId", t.CategoryId},
+		{"paymentMethodId", t.PaymentMethodId},
+		{"toPaymentMethodId", t.ToPaymentMethodId},
+		{"whereUrn", t.PlaceId},
+		{"whoUrn", t.PersonId},
+		{"recurringId", t.RecurringId},
+	} {
+		err = validateLength(err, id.field, id.value, maxIdLength)
+	}
+	for i, d := range t.Details {
+		field := fmt.Sprintf("details[%d]", i)
+		err = validateLength(err, field+".description", d.Description, maxTextLength)
+		err = validateLength(err, field+".categoryId", d.CategoryId, maxIdLength)
+	}
+	err = MergeFieldErrors(err, t.ValidateKind())
+	return MergeFieldErrors(err, t.ValidateDetails())
+}
+
+func FilterKind(kind TransactionKind) func(*ExpenseFilter) error {
+	return func(e *ExpenseFilter) error {
+		if !kind.IsValid() {
+			return NewFieldError(nil, "kind", "invalid", fmt.Sprintf("must be one of %v", transactionKinds))
+		}
+		e.Kind = append(e.Kind, kind)
+		return nil
+	}
+}
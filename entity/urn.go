@@ -0,0 +1,79 @@
+package entity
+
+import (
+	"fmt"
+	"strings"
+)
+
+type URNKind string
+
+const (
+	PLACE  URNKind = "place"
+	PERSON URNKind = "person"
+)
+
+// URN references an entity by kind and id, like urn:place:01F5N1Q3ZKX4
+type URN string
+
+const urnPrefix = "urn:"
+
+func NewURN(kind URNKind, id string) URN {
+	if id == "" {
+		return ""
+	}
+	return URN(urnPrefix + string(kind) + ":" + id)
+}
+
+// Id returns the id referenced by the URN, it must be of kind
+func (u URN) Id(kind URNKind) (string, error) {
+	prefix := urnPrefix + string(kind) + ":"
+	id := strings.TrimPrefix(string(u), prefix)
+	if id == string(u) || strings.TrimSpace(id) == "" {
+		return "", NewFieldError(nil, "urn", "invalid", fmt.Sprintf("%q is not like %s<id>", u, prefix))
+	}
+	return id, nil
+}
+
+// NormalizeName is how free text is matched against names and aliases,
+// lower case without repeated spaces
+func NormalizeName(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+// validateAliases checks the aliases are not empty and don't repeat the name or each other
+func validateAliases(err error, name string, aliases []string) error {
+	seen := map[string]bool{NormalizeName(name): true}
+	for i, alias := range aliases {
+		field := fmt.Sprintf("aliases[%d]", i)
+		normalized := NormalizeName(alias)
+		if normalized == "" {
+			err = NewFieldError(err, field, "no_empty", "can't be empty")
+			continue
+		}
+		if seen[normalized] {
+			err = NewFieldError(err, field, "duplicated", "matches the name or another alias")
+		}
+		seen[normalized] = true
+	}
+	return err
+}
+
+func FilterWhere(urn URN) func(*ExpenseFilter) error {
+	return func(e *ExpenseFilter) error {
+		if _, err := urn.Id(PLACE); err != nil {
+			return err
+		}
+		e.Where = append(e.Where, urn)
+		return nil
+	}
+}
+
+func FilterWho(urn URN) func(*ExpenseFilter) error {
+	return func(e *ExpenseFilter) error {
+		if _, err := urn.Id(PERSON); err != nil {
+			return err
+		}
+		e.Who = append(e.Who, urn)
+		return nil
+	}
+}
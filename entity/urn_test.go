@@ -0,0 +1,59 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestURN(t *testing.T) {
+	urn := NewURN(PLACE, "starbucks")
+	assert.Equal(t, URN("urn:place:starbucks"), urn)
+	id, err := urn.Id(PLACE)
+	assert.NoError(t, err)
+	assert.Equal(t, "starbucks", id)
+
+	for _, invalid := range []URN{"urn:person:ana", "urn:place:", "starbucks", ""} {
+		_, err := invalid.Id(PLACE)
+		assert.Error(t, err, invalid)
+	}
+	assert.Empty(t, NewURN(PERSON, ""), "must not reference an empty id")
+}
+
+func TestNormalizeName(t *testing.T) {
+	assert.Equal(t, "starbucks #12", NormalizeName("  STARBUCKS \t #12 "))
+	assert.Equal(t, NormalizeName("Starbucks"), NormalizeName("starbucks "))
+}
+
+func TestPlaceValidate(t *testing.T) {
+	tests := map[string]struct {
+		place      Place
+		wantFields []string
+	}{
+		"valid": {
+			place: Place{Name: "Starbucks", Aliases: []string{"STARBUCKS #12"}, Location: &GeoPoint{Latitude: -23.56, Longitude: -46.65}},
+		},
+		"invalid": {
+			place: Place{
+				Name:     " ",
+				Aliases:  []string{"", "sbux", "SBUX "},
+				Location: &GeoPoint{Latitude: -91, Longitude: 181},
+			},
+			wantFields: []string{"name", "aliases[0]", "aliases[2]", "location.latitude", "location.longitude"},
+		},
+		"alias repeating the name": {
+			place:      Place{Name: "Starbucks", Aliases: []string{" starbucks"}},
+			wantFields: []string{"aliases[0]"},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var fields []string
+			for _, f := range UnwrapFieldErrors(tc.place.Validate()) {
+				fields = append(fields, f.Field())
+			}
+			assert.ElementsMatch(t, tc.wantFields, fields)
+		})
+	}
+	assert.Error(t, Person{Name: "Ana", Aliases: []string{"ANA"}}.Validate(), "persons validate the aliases too")
+}
@@ -8,7 +8,9 @@ require (
 	github.com/go-chi/chi/v5 v5.0.2
 	github.com/google/go-cmp v0.5.5
 	github.com/google/uuid v1.2.0
+	github.com/jackc/pgconn v1.8.1
 	github.com/jackc/pgproto3/v2 v2.0.7 // indirect
+	github.com/jackc/pgtype v1.7.0
 	github.com/jackc/pgx/v4 v4.11.0
 	github.com/oklog/ulid/v2 v2.0.2
 	github.com/rs/zerolog v1.21.0
@@ -0,0 +1,189 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/axpira/backend/entity"
+	"github.com/oklog/ulid/v2"
+	"github.com/rs/zerolog/log"
+)
+
+const BUDGET_TABLE_NAME = "tb_budget"
+
+type BudgetRepository interface {
+	Create(ctx context.Context, budget entity.Budget) (string, error)
+	Update(ctx context.Context, budget entity.Budget) error
+	Delete(ctx context.Context, id string) error
+	Get(ctx context.Context, id string) (entity.Budget, error)
+	List(ctx context.Context) ([]entity.Budget, error)
+}
+
+type budgetRepository struct {
+	db      DB
+	entropy io.Reader
+}
+
+func NewBudgetRepository(db DB) BudgetRepository {
+	return budgetRepository{
+		db:      db,
+		entropy: defaultEntropy(),
+	}
+}
+
+const budgetColumns = "name,category_id,tag,period,limit_amount,currency,rollover,start_at"
+
+// budgetArgs are the named args of budgetColumns
+func budgetArgs(b entity.Budget) []interface{} {
+	return []interface{}{
+		sql.Named("name", strings.TrimSpace(b.Name)),
+		sql.Named("category_id", sql.NullString{String: b.CategoryId, Valid: b.CategoryId != ""}),
+		sql.Named("tag", sql.NullString{String: strings.TrimSpace(b.Tag), Valid: b.Tag != ""}),
+		sql.Named("period", string(b.Period)),
+		sql.Named("limit_amount", b.Limit.Amount),
+		sql.Named("currency", string(b.Limit.Currency)),
+		sql.Named("rollover", b.Rollover),
+		sql.Named("start_at", b.Start.UTC()),
+	}
+}
+
+func (r budgetRepository) Create(ctx context.Context, budget entity.Budget) (string, error) {
+	if err := budget.Validate(); err != nil {
+		return "", err
+	}
+	id, err := ulid.New(ulid.Timestamp(time.Now().UTC()), r.entropy)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now().UTC()
+	query := fmt.Sprintf("INSERT INTO %s (id,%s,createdAt,updatedAt) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11);", BUDGET_TABLE_NAME, budgetColumns)
+	log.Ctx(ctx).Debug().Msgf("runing: %v", query)
+	args := append([]interface{}{sql.Named("id", id.String())}, budgetArgs(budget)...)
+	args = append(args, sql.Named("createdAt", now), sql.Named("updatedAt", now))
+	_, err = r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return "", translateError(err)
+	}
+	return id.String(), nil
+}
+
+func (r budgetRepository) Update(ctx context.Context, budget entity.Budget) error {
+	if strings.TrimSpace(budget.Id) == "" {
+		return entity.NewFieldError(nil, "id", "empty", "can't be empty")
+	}
+	if err := budget.Validate(); err != nil {
+		return err
+	}
+	query := fmt.Sprintf(
+		"UPDATE %s SET name = $2, category_id = $3, tag = $4, period = $5, limit_amount = $6, currency = $7, rollover = $8, start_at = $9, updatedAt = $10 WHERE id = $1;",
+		BUDGET_TABLE_NAME,
+	)
+	log.Ctx(ctx).Debug().Msgf("runing: %v", query)
+	args := append([]interface{}{sql.Named("id", budget.Id)}, budgetArgs(budget)...)
+	args = append(args, sql.Named("updatedAt", time.Now().UTC()))
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return translateError(err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnknown, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("id %s was %w", budget.Id, entity.ErrNotFound)
+	}
+	return nil
+}
+
+func (r budgetRepository) Delete(ctx context.Context, id string) error {
+	if strings.TrimSpace(id) == "" {
+		return entity.NewFieldError(nil, "id", "empty", "can't be empty")
+	}
+	query := fmt.Sprintf("DELETE FROM %s WHERE id = $1;", BUDGET_TABLE_NAME)
+	log.Ctx(ctx).Debug().Msgf("runing: %v", query)
+	result, err := r.db.ExecContext(ctx, query, sql.Named("id", id))
+	if err != nil {
+		return fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("id %s was %w", id, entity.ErrNotFound)
+	}
+	return nil
+}
+
+type budgetRow struct {
+	id          string
+	name        string
+	categoryId  sql.NullString
+	tag         sql.NullString
+	period      string
+	limitAmount int64
+	currency    string
+	rollover    bool
+	start       time.Time
+}
+
+func (b *budgetRow) Scan() []interface{} {
+	return []interface{}{&b.name, &b.categoryId, &b.tag, &b.period, &b.limitAmount, &b.currency, &b.rollover, &b.start}
+}
+
+func (b budgetRow) toBudget() entity.Budget {
+	return entity.Budget{
+		Id:         b.id,
+		Name:       b.name,
+		CategoryId: b.categoryId.String,
+		Tag:        b.tag.String,
+		Period:     entity.Frequency(b.period),
+		Limit:      entity.Money{Amount: b.limitAmount, Currency: entity.Currency(b.currency)},
+		Rollover:   b.rollover,
+		Start:      b.start.UTC(),
+	}
+}
+
+func (r budgetRepository) Get(ctx context.Context, id string) (entity.Budget, error) {
+	if strings.TrimSpace(id) == "" {
+		return entity.Budget{}, entity.NewFieldError(nil, "id", "empty", "can't be empty")
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE id = $1;", budgetColumns, BUDGET_TABLE_NAME)
+	log.Ctx(ctx).Debug().Msgf("runing: %v", query)
+	b := budgetRow{id: id}
+	err := r.db.QueryRowContext(ctx, query, sql.Named("id", id)).Scan(b.Scan()...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entity.Budget{}, fmt.Errorf("id %s was %w", id, entity.ErrNotFound)
+		}
+		return entity.Budget{}, fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+	}
+	return b.toBudget(), nil
+}
+
+func (r budgetRepository) List(ctx context.Context) ([]entity.Budget, error) {
+	query := fmt.Sprintf("SELECT id,%s FROM %s ORDER BY name, id;", budgetColumns, BUDGET_TABLE_NAME)
+	log.Ctx(ctx).Debug().Msgf("runing: %v", query)
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+	}
+	defer rows.Close()
+	budgets := make([]entity.Budget, 0)
+	for rows.Next() {
+		var b budgetRow
+		if err := rows.Scan(append([]interface{}{&b.id}, b.Scan()...)...); err != nil {
+			return nil, fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+		}
+		budgets = append(budgets, b.toBudget())
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+	}
+	return budgets, nil
+}
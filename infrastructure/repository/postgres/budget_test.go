@@ -0,0 +1,104 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/axpira/backend/entity"
+	"github.com/jackc/pgconn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBudgetCreate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	repo := NewBudgetRepository(db)
+	ctx := context.Background()
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	budget := entity.Budget{
+		Name:       " Food ",
+		CategoryId: "food",
+		Period:     entity.MONTHLY,
+		Limit:      entity.Money{Amount: 50000, Currency: "BRL"},
+		Start:      start,
+	}
+
+	_, err = repo.Create(ctx, entity.Budget{Name: "Food", CategoryId: "food", Period: entity.DAILY, Limit: budget.Limit, Start: start})
+	fieldErrors := entity.UnwrapFieldErrors(err)
+	if assert.Len(t, fieldErrors, 1, "must validate the period") {
+		assert.Equal(t, "period", fieldErrors[0].Field())
+	}
+
+	query := "INSERT INTO tb_budget \\(id,name,category_id,tag,period,limit_amount,currency,rollover,start_at,createdAt,updatedAt\\) " +
+		"VALUES \\(\\$1, \\$2, \\$3, \\$4, \\$5, \\$6, \\$7, \\$8, \\$9, \\$10, \\$11\\);"
+	args := []driver.Value{
+		anyULID{},
+		sql.Named("name", "Food"),
+		sql.Named("category_id", sql.NullString{String: "food", Valid: true}),
+		sql.Named("tag", sql.NullString{}),
+		sql.Named("period", "monthly"),
+		sql.Named("limit_amount", int64(50000)),
+		sql.Named("currency", "BRL"),
+		sql.Named("rollover", false),
+		sql.Named("start_at", start),
+		timeMatch{time.Now().UTC()},
+		timeMatch{time.Now().UTC()},
+	}
+	mock.ExpectExec(query).WithArgs(args...).WillReturnResult(sqlmock.NewResult(1, 1))
+	id, err := repo.Create(ctx, budget)
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.NoError(t, err)
+	assert.NotEmpty(t, id)
+
+	mock.ExpectExec(query).
+		WithArgs(args...).
+		WillReturnError(&pgconn.PgError{Code: pgForeignKeyViolation, ConstraintName: "fk_budget_category"})
+	_, err = repo.Create(ctx, budget)
+	assert.NoError(t, mock.ExpectationsWereMet())
+	fieldErrors = entity.UnwrapFieldErrors(err)
+	if assert.Len(t, fieldErrors, 1, "must say which reference doesn't exist") {
+		assert.Equal(t, "categoryId", fieldErrors[0].Field())
+	}
+}
+
+func TestBudgetGet(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	repo := NewBudgetRepository(db)
+	ctx := context.Background()
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	columns := []string{"name", "category_id", "tag", "period", "limit_amount", "currency", "rollover", "start_at"}
+	query := "SELECT name,category_id,tag,period,limit_amount,currency,rollover,start_at FROM tb_budget WHERE id = \\$1;"
+
+	mock.ExpectQuery(query).
+		WithArgs(sql.Named("id", "trip")).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("Trip", nil, "trip", "yearly", 300000, "BRL", true, start))
+	got, err := repo.Get(ctx, "trip")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.Budget{
+		Id:       "trip",
+		Name:     "Trip",
+		Tag:      "trip",
+		Period:   entity.YEARLY,
+		Limit:    entity.Money{Amount: 300000, Currency: "BRL"},
+		Rollover: true,
+		Start:    start,
+	}, got)
+
+	mock.ExpectQuery(query).
+		WithArgs(sql.Named("id", "unknown")).
+		WillReturnRows(sqlmock.NewRows(columns))
+	_, err = repo.Get(ctx, "unknown")
+	assert.ErrorIs(t, err, entity.ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,208 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/axpira/backend/entity"
+	"github.com/oklog/ulid/v2"
+	"github.com/rs/zerolog/log"
+)
+
+const CATEGORY_TABLE_NAME = "tb_category"
+
+// categoryTreeQuery selects the ids of the categories matching root and all their descendants
+func categoryTreeQuery(root string) string {
+	return fmt.Sprintf(
+		"WITH RECURSIVE tree AS (SELECT id FROM %[1]s WHERE %[2]s UNION ALL SELECT c.id FROM %[1]s c JOIN tree ON c.parent_id = tree.id) SELECT id FROM tree",
+		CATEGORY_TABLE_NAME,
+		root,
+	)
+}
+
+type CategoryRepository interface {
+	Create(ctx context.Context, category entity.Category) (string, error)
+	Update(ctx context.Context, category entity.Category) error
+	Delete(ctx context.Context, id, reassignTo string, orphan bool) error
+	Get(ctx context.Context, id string) (entity.Category, error)
+	List(ctx context.Context) ([]entity.Category, error)
+}
+
+type categoryRepository struct {
+	db      DB
+	entropy io.Reader
+}
+
+func NewCategoryRepository(db DB) CategoryRepository {
+	return categoryRepository{
+		db:      db,
+		entropy: defaultEntropy(),
+	}
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func (r categoryRepository) Create(ctx context.Context, category entity.Category) (string, error) {
+	if err := category.Validate(); err != nil {
+		return "", err
+	}
+	id, err := ulid.New(ulid.Timestamp(time.Now().UTC()), r.entropy)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now().UTC()
+	query := fmt.Sprintf("INSERT INTO %s (id,name,parent_id,createdAt,updatedAt) VALUES ($1, $2, $3, $4, $5);", CATEGORY_TABLE_NAME)
+	log.Ctx(ctx).Debug().Msgf("runing: %v", query)
+	_, err = r.db.ExecContext(ctx, query,
+		sql.Named("id", id.String()),
+		sql.Named("name", strings.TrimSpace(category.Name)),
+		sql.Named("parent_id", nullString(category.ParentId)),
+		sql.Named("createdAt", now),
+		sql.Named("updatedAt", now),
+	)
+	if err != nil {
+		return "", translateError(err)
+	}
+	return id.String(), nil
+}
+
+func (r categoryRepository) Update(ctx context.Context, category entity.Category) error {
+	if strings.TrimSpace(category.Id) == "" {
+		return entity.NewFieldError(nil, "id", "empty", "can't be empty")
+	}
+	if err := category.Validate(); err != nil {
+		return err
+	}
+	if category.ParentId != "" {
+		var cycle bool
+		query := fmt.Sprintf("SELECT EXISTS (%s WHERE id = $2);", categoryTreeQuery("id = $1"))
+		err := r.db.QueryRowContext(ctx, query,
+			sql.Named("id", category.Id),
+			sql.Named("parent_id", category.ParentId),
+		).Scan(&cycle)
+		if err != nil {
+			return fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+		}
+		if cycle {
+			return entity.NewFieldError(nil, "parentId", "cycle", "can't be a descendant of the category")
+		}
+	}
+	query := fmt.Sprintf("UPDATE %s SET name = $2, parent_id = $3, updatedAt = $4 WHERE id = $1;", CATEGORY_TABLE_NAME)
+	log.Ctx(ctx).Debug().Msgf("runing: %v", query)
+	result, err := r.db.ExecContext(ctx, query,
+		sql.Named("id", category.Id),
+		sql.Named("name", strings.TrimSpace(category.Name)),
+		sql.Named("parent_id", nullString(category.ParentId)),
+		sql.Named("updatedAt", time.Now().UTC()),
+	)
+	if err != nil {
+		return translateError(err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnknown, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("id %s was %w", category.Id, entity.ErrNotFound)
+	}
+	return nil
+}
+
+// Delete removes the category moving its children to its parent. Its expenses and the
+// details of expenses are moved to reassignTo or, if orphan, left without category; with
+// neither the delete is refused while the category has expenses or details.
+func (r categoryRepository) Delete(ctx context.Context, id, reassignTo string, orphan bool) error {
+	if strings.TrimSpace(id) == "" {
+		return entity.NewFieldError(nil, "id", "empty", "can't be empty")
+	}
+	if reassignTo != "" && orphan {
+		return entity.NewFieldError(nil, "reassignTo", "conflict", "can't reassign and orphan at the same time")
+	}
+	if reassignTo == id {
+		return entity.NewFieldError(nil, "reassignTo", "invalid", "can't reassign to the deleted category")
+	}
+	guard := ""
+	if reassignTo == "" && !orphan {
+		guard = fmt.Sprintf(
+			" AND NOT EXISTS (SELECT 1 FROM %s WHERE category_id = $1) AND NOT EXISTS (SELECT 1 FROM %s WHERE category_id = $1)",
+			TABLE_NAME,
+			DETAIL_TABLE_NAME,
+		)
+	}
+	query := fmt.Sprintf(
+		"WITH deleted AS (DELETE FROM %[1]s WHERE id = $1%[4]s RETURNING id, parent_id), "+
+			"children AS (UPDATE %[1]s c SET parent_id = d.parent_id FROM deleted d WHERE c.parent_id = d.id), "+
+			"expenses AS (UPDATE %[2]s e SET category_id = $2 FROM deleted d WHERE e.category_id = d.id), "+
+			"details AS (UPDATE %[3]s t SET category_id = $2 FROM deleted d WHERE t.category_id = d.id) "+
+			"SELECT count(*) FROM deleted;",
+		CATEGORY_TABLE_NAME,
+		TABLE_NAME,
+		DETAIL_TABLE_NAME,
+		guard,
+	)
+	log.Ctx(ctx).Debug().Msgf("runing: %v", query)
+	var deleted int
+	err := r.db.QueryRowContext(ctx, query,
+		sql.Named("id", id),
+		sql.Named("category_id", nullString(reassignTo)),
+	).Scan(&deleted)
+	if err != nil {
+		return translateError(err)
+	}
+	if deleted > 0 {
+		return nil
+	}
+	if _, err := r.Get(ctx, id); err != nil {
+		return err
+	}
+	return fmt.Errorf("category %s has expenses or details, reassign or orphan them: %w", id, entity.ErrConflict)
+}
+
+func (r categoryRepository) Get(ctx context.Context, id string) (entity.Category, error) {
+	if strings.TrimSpace(id) == "" {
+		return entity.Category{}, entity.NewFieldError(nil, "id", "empty", "can't be empty")
+	}
+	query := fmt.Sprintf("SELECT name, parent_id FROM %s WHERE id = $1;", CATEGORY_TABLE_NAME)
+	log.Ctx(ctx).Debug().Msgf("runing: %v", query)
+	var name string
+	var parentId sql.NullString
+	err := r.db.QueryRowContext(ctx, query, sql.Named("id", id)).Scan(&name, &parentId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entity.Category{}, fmt.Errorf("id %s was %w", id, entity.ErrNotFound)
+		}
+		return entity.Category{}, fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+	}
+	return entity.Category{Id: id, Name: name, ParentId: parentId.String}, nil
+}
+
+func (r categoryRepository) List(ctx context.Context) ([]entity.Category, error) {
+	query := fmt.Sprintf("SELECT id, name, parent_id FROM %s ORDER BY name, id;", CATEGORY_TABLE_NAME)
+	log.Ctx(ctx).Debug().Msgf("runing: %v", query)
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+	}
+	defer rows.Close()
+	categories := make([]entity.Category, 0)
+	for rows.Next() {
+		var c entity.Category
+		var parentId sql.NullString
+		if err := rows.Scan(&c.Id, &c.Name, &parentId); err != nil {
+			return nil, fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+		}
+		c.ParentId = parentId.String
+		categories = append(categories, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+	}
+	return categories, nil
+}
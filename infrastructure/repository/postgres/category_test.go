@@ -0,0 +1,243 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/axpira/backend/entity"
+	"github.com/jackc/pgconn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCategoryCreate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	repo := NewCategoryRepository(db)
+	ctx := context.Background()
+
+	_, err = repo.Create(ctx, entity.Category{Name: " "})
+	assert.True(t, errors.As(err, &entity.FieldError{}), "must validate the category")
+
+	const wantQuery = "INSERT INTO tb_category \\(id,name,parent_id,createdAt,updatedAt\\) VALUES \\(\\$1, \\$2, \\$3, \\$4, \\$5\\);"
+	tests := map[string]struct {
+		category  entity.Category
+		args      []driver.Value
+		mockErr   error
+		wantField string
+	}{
+		"must insert a root category": {
+			category: entity.Category{Name: " Food "},
+			args: []driver.Value{
+				anyULID{},
+				sql.Named("name", "Food"),
+				sql.Named("parent_id", sql.NullString{}),
+				timeMatch{time.Now().UTC()},
+				timeMatch{time.Now().UTC()},
+			},
+		},
+		"must return field error on unknown parent": {
+			category: entity.Category{Name: "Groceries", ParentId: "unknown"},
+			args: []driver.Value{
+				anyULID{},
+				sql.Named("name", "Groceries"),
+				sql.Named("parent_id", sql.NullString{String: "unknown", Valid: true}),
+				timeMatch{time.Now().UTC()},
+				timeMatch{time.Now().UTC()},
+			},
+			mockErr:   &pgconn.PgError{Code: pgForeignKeyViolation, ConstraintName: "fk_category_parent"},
+			wantField: "parentId",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			mock.
+				ExpectExec(wantQuery).
+				WithArgs(tc.args...).
+				WillReturnResult(sqlmock.NewResult(1, 1)).
+				WillReturnError(tc.mockErr)
+			id, gotErr := repo.Create(ctx, tc.category)
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unmet expectation error: %s", err)
+			}
+			if tc.wantField != "" {
+				fieldErrors := entity.UnwrapFieldErrors(gotErr)
+				if assert.Len(t, fieldErrors, 1) {
+					assert.Equal(t, tc.wantField, fieldErrors[0].Field())
+				}
+				return
+			}
+			assert.NoError(t, gotErr)
+			assert.NotEmpty(t, id)
+		})
+	}
+}
+
+func TestCategoryUpdate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	repo := NewCategoryRepository(db)
+	ctx := context.Background()
+
+	const (
+		cycleQuery  = "SELECT EXISTS \\(WITH RECURSIVE tree AS \\(SELECT id FROM tb_category WHERE id = \\$1 UNION ALL SELECT c.id FROM tb_category c JOIN tree ON c.parent_id = tree.id\\) SELECT id FROM tree WHERE id = \\$2\\);"
+		updateQuery = "UPDATE tb_category SET name = \\$2, parent_id = \\$3, updatedAt = \\$4 WHERE id = \\$1;"
+	)
+
+	t.Run("must refuse a descendant as parent", func(t *testing.T) {
+		mock.ExpectQuery(cycleQuery).
+			WithArgs(sql.Named("id", "food"), sql.Named("parent_id", "bakery")).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		err := repo.Update(ctx, entity.Category{Id: "food", Name: "Food", ParentId: "bakery"})
+		assert.NoError(t, mock.ExpectationsWereMet())
+		fieldErrors := entity.UnwrapFieldErrors(err)
+		if assert.Len(t, fieldErrors, 1) {
+			assert.Equal(t, "cycle", fieldErrors[0].Code())
+		}
+	})
+
+	t.Run("must update moving to another parent", func(t *testing.T) {
+		mock.ExpectQuery(cycleQuery).
+			WithArgs(sql.Named("id", "bakery"), sql.Named("parent_id", "groceries")).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectExec(updateQuery).
+			WithArgs(
+				sql.Named("id", "bakery"),
+				sql.Named("name", "Bakery"),
+				sql.Named("parent_id", sql.NullString{String: "groceries", Valid: true}),
+				timeMatch{time.Now().UTC()},
+			).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		err := repo.Update(ctx, entity.Category{Id: "bakery", Name: "Bakery", ParentId: "groceries"})
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.NoError(t, err)
+	})
+
+	t.Run("must return not found", func(t *testing.T) {
+		mock.ExpectExec(updateQuery).
+			WithArgs(
+				sql.Named("id", "unknown"),
+				sql.Named("name", "Food"),
+				sql.Named("parent_id", sql.NullString{}),
+				timeMatch{time.Now().UTC()},
+			).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		err := repo.Update(ctx, entity.Category{Id: "unknown", Name: "Food"})
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.ErrorIs(t, err, entity.ErrNotFound)
+	})
+}
+
+func TestCategoryDelete(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	repo := NewCategoryRepository(db)
+	ctx := context.Background()
+
+	assert.Error(t, repo.Delete(ctx, "", "", false), "must return error on empty id")
+	assert.Error(t, repo.Delete(ctx, "food", "other", true), "must not reassign and orphan")
+	assert.Error(t, repo.Delete(ctx, "food", "food", false), "must not reassign to itself")
+
+	const (
+		deleteQuery = "WITH deleted AS \\(DELETE FROM tb_category WHERE id = \\$1%s RETURNING id, parent_id\\), " +
+			"children AS \\(UPDATE tb_category c SET parent_id = d.parent_id FROM deleted d WHERE c.parent_id = d.id\\), " +
+			"expenses AS \\(UPDATE tb_expense e SET category_id = \\$2 FROM deleted d WHERE e.category_id = d.id\\), " +
+			"details AS \\(UPDATE tb_expense_detail t SET category_id = \\$2 FROM deleted d WHERE t.category_id = d.id\\) " +
+			"SELECT count\\(\\*\\) FROM deleted;"
+		guard = " AND NOT EXISTS \\(SELECT 1 FROM tb_expense WHERE category_id = \\$1\\)" +
+			" AND NOT EXISTS \\(SELECT 1 FROM tb_expense_detail WHERE category_id = \\$1\\)"
+		getQuery = "SELECT name, parent_id FROM tb_category WHERE id = \\$1;"
+	)
+
+	tests := map[string]struct {
+		reassignTo  string
+		orphan      bool
+		wantQuery   string
+		wantArg     sql.NullString
+		deleted     int
+		mockGetRows *sqlmock.Rows
+		wantErr     error
+	}{
+		"must delete a category without expenses": {
+			wantQuery: fmt.Sprintf(deleteQuery, guard),
+			deleted:   1,
+		},
+		"must reassign the expenses": {
+			reassignTo: "other",
+			wantQuery:  fmt.Sprintf(deleteQuery, ""),
+			wantArg:    sql.NullString{String: "other", Valid: true},
+			deleted:    1,
+		},
+		"must orphan the expenses": {
+			orphan:    true,
+			wantQuery: fmt.Sprintf(deleteQuery, ""),
+			deleted:   1,
+		},
+		"must refuse to delete a category with expenses": {
+			wantQuery:   fmt.Sprintf(deleteQuery, guard),
+			mockGetRows: sqlmock.NewRows([]string{"name", "parent_id"}).AddRow("Food", nil),
+			wantErr:     entity.ErrConflict,
+		},
+		"must return not found": {
+			orphan:      true,
+			wantQuery:   fmt.Sprintf(deleteQuery, ""),
+			mockGetRows: sqlmock.NewRows([]string{"name", "parent_id"}),
+			wantErr:     entity.ErrNotFound,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			mock.ExpectQuery(tc.wantQuery).
+				WithArgs(sql.Named("id", "food"), sql.Named("category_id", tc.wantArg)).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(tc.deleted))
+			if tc.mockGetRows != nil {
+				mock.ExpectQuery(getQuery).
+					WithArgs(sql.Named("id", "food")).
+					WillReturnRows(tc.mockGetRows)
+			}
+			err := repo.Delete(ctx, "food", tc.reassignTo, tc.orphan)
+			assert.NoError(t, mock.ExpectationsWereMet())
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCategoryList(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	repo := NewCategoryRepository(db)
+
+	mock.ExpectQuery("SELECT id, name, parent_id FROM tb_category ORDER BY name, id;").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "parent_id"}).
+			AddRow("bakery", "Bakery", "groceries").
+			AddRow("food", "Food", nil),
+		)
+	categories, err := repo.List(context.Background())
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.NoError(t, err)
+	assert.Equal(t, []entity.Category{
+		{Id: "bakery", Name: "Bakery", ParentId: "groceries"},
+		{Id: "food", Name: "Food"},
+	}, categories)
+}
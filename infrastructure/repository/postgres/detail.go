@@ -0,0 +1,116 @@
+package postgres
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/axpira/backend/entity"
+)
+
+const DETAIL_TABLE_NAME = "tb_expense_detail"
+
+// detailJSON is a line item as written by jsonb_build_object and read by jsonb_to_recordset
+type detailJSON struct {
+	Position    int      `json:"position"`
+	Description string   `json:"description"`
+	Quantity    int64    `json:"quantity"`
+	UnitPrice   int64    `json:"unit_price"`
+	CategoryId  *string  `json:"category_id"`
+	Tags        []string `json:"tags"`
+}
+
+var (
+	// detailsSelect reads the details of every expense row as a JSON array, NULL when it has none
+	detailsSelect = fmt.Sprintf(
+		"(SELECT jsonb_agg(jsonb_build_object('position', d.position, 'description', d.description, "+
+			"'quantity', d.quantity, 'unit_price', d.unit_price, 'category_id', d.category_id, 'tags', d.tags) "+
+			"ORDER BY d.position) FROM %s d WHERE d.expense_id = %s.id)",
+		DETAIL_TABLE_NAME,
+		TABLE_NAME,
+	)
+	expenseSelectColumns = expenseRowColumns + "," + detailsSelect
+)
+
+// detailsInsert inserts the details sent as a JSON array in placeholder for the rows of the expense CTE
+func detailsInsert(placeholder string) string {
+	return fmt.Sprintf(
+		"INSERT INTO %s (expense_id,position,description,quantity,unit_price,category_id,tags) "+
+			"SELECT e.id, d.position, d.description, d.quantity, d.unit_price, d.category_id, coalesce(d.tags, '{}') "+
+			"FROM expense e, jsonb_to_recordset(%s::jsonb) "+
+			"AS d(position INT, description TEXT, quantity BIGINT, unit_price BIGINT, category_id TEXT, tags TEXT[])",
+		DETAIL_TABLE_NAME,
+		placeholder,
+	)
+}
+
+func newDetailsJSON(details []entity.Detail) []detailJSON {
+	res := make([]detailJSON, len(details))
+	for i, d := range details {
+		res[i] = detailJSON{
+			Position:    i,
+			Description: d.Description,
+			Quantity:    d.Quantity,
+			UnitPrice:   d.UnitPrice,
+			Tags:        []string{},
+		}
+		if d.CategoryId != "" {
+			categoryId := d.CategoryId
+			res[i].CategoryId = &categoryId
+		}
+		if d.Tags != nil {
+			res[i].Tags = d.Tags.Value()
+		}
+	}
+	return res
+}
+
+// detailsArg never fails, the struct only has strings and numbers
+func detailsArg(details []entity.Detail) sql.NullString {
+	b, _ := json.Marshal(newDetailsJSON(details))
+	return sql.NullString{String: string(b), Valid: true}
+}
+
+func toDetails(details []detailJSON) ([]entity.Detail, error) {
+	res := make([]entity.Detail, len(details))
+	for i, d := range details {
+		res[i] = entity.Detail{
+			Description: d.Description,
+			Quantity:    d.Quantity,
+			UnitPrice:   d.UnitPrice,
+		}
+		if d.CategoryId != nil {
+			res[i].CategoryId = *d.CategoryId
+		}
+		if len(d.Tags) > 0 {
+			tags, err := entity.NewTags(d.Tags...)
+			if err != nil {
+				return nil, err
+			}
+			res[i].Tags = tags
+		}
+	}
+	return res, nil
+}
+
+func parseDetailsJSON(value string) ([]entity.Detail, error) {
+	var details []detailJSON
+	if err := json.Unmarshal([]byte(value), &details); err != nil {
+		return nil, err
+	}
+	return toDetails(details)
+}
+
+// detailFilters matches the expenses with any line item matching each filter
+func (w *whereClause) detailFilters(err error, filters []entity.StrFilter) error {
+	for _, f := range filters {
+		op, ok := strFilterSQL[f.Type]
+		if !ok {
+			err = entity.NewFieldError(err, "detail", "invalid_operator", fmt.Sprintf("unknown operator %d", f.Type))
+			continue
+		}
+		w.add(fmt.Sprintf("EXISTS (SELECT 1 FROM %s d WHERE d.expense_id = %s.id AND d.description %s %s)",
+			DETAIL_TABLE_NAME, TABLE_NAME, op, w.arg("detail", f.Value)))
+	}
+	return err
+}
@@ -0,0 +1,48 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/axpira/backend/entity"
+	"github.com/jackc/pgconn"
+)
+
+const (
+	pgForeignKeyViolation = "23503"
+	pgUniqueViolation     = "23505"
+)
+
+// constraintFields maps the constraints of ops/db/create_tables.sql to the entity field they protect
+var constraintFields = map[string]string{
+	"fk_expense_category":          "categoryId",
+	"fk_category_parent":           "parentId",
+	"fk_expense_payment_method":    "paymentMethodId",
+	"fk_expense_to_payment_method": "toPaymentMethodId",
+	"fk_expense_recurring":         "recurringId",
+	"fk_expense_detail_category":   "details.categoryId",
+	"fk_expense_place":             "whereUrn",
+	"fk_expense_person":            "whoUrn",
+	"fk_budget_category":           "categoryId",
+}
+
+// translateError turns constraint violations into errors the caller can act on,
+// everything else is unknown
+func translateError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		field, ok := constraintFields[pgErr.ConstraintName]
+		if pgErr.Code == pgForeignKeyViolation && ok {
+			return entity.NewFieldError(nil, field, "not_found", "reference not found")
+		}
+		if pgErr.Code == pgUniqueViolation {
+			return fmt.Errorf("%s: %w", pgErr.ConstraintName, entity.ErrConflict)
+		}
+	}
+	return fmt.Errorf("%w: %v", ErrUnknown, err)
+}
+
+func isForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == pgForeignKeyViolation
+}
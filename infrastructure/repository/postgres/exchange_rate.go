@@ -0,0 +1,193 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/axpira/backend/entity"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	EXCHANGE_RATE_TABLE_NAME = "tb_exchange_rate"
+	// rateDecimals is the scale of the rate column
+	rateDecimals = 12
+	// exchangeRateBatch keeps each insert under the postgres limit of parameters
+	exchangeRateBatch = 1000
+)
+
+type ExchangeRateRepository interface {
+	Save(ctx context.Context, rates ...entity.ExchangeRate) error
+	Find(ctx context.Context, from, to entity.Currency, on time.Time) (entity.ExchangeRate, error)
+	List(ctx context.Context, from, to entity.Currency) ([]entity.ExchangeRate, error)
+}
+
+type exchangeRateRepository struct {
+	db DB
+}
+
+func NewExchangeRateRepository(db DB) ExchangeRateRepository {
+	return exchangeRateRepository{
+		db: db,
+	}
+}
+
+// Save inserts the rates replacing the ones with the same date, from and to, the last one wins
+// when they are repeated. The rates are saved in batches of exchangeRateBatch in one transaction
+func (r exchangeRateRepository) Save(ctx context.Context, rates ...entity.ExchangeRate) error {
+	for i, rate := range rates {
+		if err := rate.Validate(); err != nil {
+			return fmt.Errorf("rate %d: %w", i+1, err)
+		}
+	}
+	rates = uniqueExchangeRates(rates)
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+	}
+	defer tx.Rollback()
+	for start := 0; start < len(rates); start += exchangeRateBatch {
+		end := start + exchangeRateBatch
+		if end > len(rates) {
+			end = len(rates)
+		}
+		if err := r.save(ctx, tx, rates[start:end]); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+	}
+	return nil
+}
+
+// uniqueExchangeRates keeps the last rate of every date, from and to, where the first one was,
+// an insert can't update the same row twice
+func uniqueExchangeRates(rates []entity.ExchangeRate) []entity.ExchangeRate {
+	type rateKey struct {
+		date     string
+		from, to entity.Currency
+	}
+	positions := make(map[rateKey]int, len(rates))
+	res := make([]entity.ExchangeRate, 0, len(rates))
+	for _, rate := range rates {
+		key := rateKey{date: rate.Date.UTC().Format("2006-01-02"), from: rate.From, to: rate.To}
+		if i, ok := positions[key]; ok {
+			res[i] = rate
+			continue
+		}
+		positions[key] = len(res)
+		res = append(res, rate)
+	}
+	return res
+}
+
+func (r exchangeRateRepository) save(ctx context.Context, tx *sql.Tx, rates []entity.ExchangeRate) error {
+	now := time.Now().UTC()
+	w := whereClause{}
+	values := make([]string, len(rates))
+	for i, rate := range rates {
+		values[i] = fmt.Sprintf("(%s, %s, %s, %s, %s, %s)",
+			w.arg("date", rate.Date.UTC().Format("2006-01-02")),
+			w.arg("from_currency", string(rate.From)),
+			w.arg("to_currency", string(rate.To)),
+			w.arg("rate", rate.Rate.FloatString(rateDecimals)),
+			w.arg("createdAt", now),
+			w.arg("updatedAt", now),
+		)
+	}
+	query := fmt.Sprintf(
+		"INSERT INTO %s (date,from_currency,to_currency,rate,createdAt,updatedAt) VALUES %s "+
+			"ON CONFLICT (from_currency, to_currency, date) DO UPDATE SET rate = EXCLUDED.rate, updatedAt = EXCLUDED.updatedAt;",
+		EXCHANGE_RATE_TABLE_NAME,
+		strings.Join(values, ", "),
+	)
+	log.Ctx(ctx).Debug().Msgf("runing: %v", query)
+	_, err := tx.ExecContext(ctx, query, w.args...)
+	if err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+func (r exchangeRateRepository) Find(ctx context.Context, from, to entity.Currency, on time.Time) (entity.ExchangeRate, error) {
+	query := fmt.Sprintf(
+		"SELECT date, from_currency, to_currency, rate FROM %s "+
+			"WHERE from_currency = $1 AND to_currency = $2 AND date <= $3 ORDER BY date DESC LIMIT 1;",
+		EXCHANGE_RATE_TABLE_NAME,
+	)
+	log.Ctx(ctx).Debug().Msgf("runing: %v", query)
+	row := r.db.QueryRowContext(ctx, query,
+		sql.Named("from_currency", string(from)),
+		sql.Named("to_currency", string(to)),
+		sql.Named("date", on.UTC().Format("2006-01-02")),
+	)
+	rate, err := scanExchangeRate(row.Scan)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entity.ExchangeRate{}, fmt.Errorf("rate %s to %s was %w", from, to, entity.ErrNotFound)
+		}
+		return entity.ExchangeRate{}, fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+	}
+	return rate, nil
+}
+
+// List returns the rates ordered by date, an empty from or to matches every currency
+func (r exchangeRateRepository) List(ctx context.Context, from, to entity.Currency) ([]entity.ExchangeRate, error) {
+	w := whereClause{}
+	if from != "" {
+		w.add("from_currency = " + w.arg("from_currency", string(from)))
+	}
+	if to != "" {
+		w.add("to_currency = " + w.arg("to_currency", string(to)))
+	}
+	query := fmt.Sprintf(
+		"SELECT date, from_currency, to_currency, rate FROM %s%s ORDER BY date, from_currency, to_currency;",
+		EXCHANGE_RATE_TABLE_NAME,
+		w,
+	)
+	log.Ctx(ctx).Debug().Msgf("runing: %v", query)
+	rows, err := r.db.QueryContext(ctx, query, w.args...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+	}
+	defer rows.Close()
+	rates := make([]entity.ExchangeRate, 0)
+	for rows.Next() {
+		rate, err := scanExchangeRate(rows.Scan)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+		}
+		rates = append(rates, rate)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+	}
+	return rates, nil
+}
+
+// scanExchangeRate reads the NUMERIC rate as text so it is kept exact
+func scanExchangeRate(scan func(...interface{}) error) (entity.ExchangeRate, error) {
+	var (
+		rate       entity.ExchangeRate
+		from, to   string
+		rateString string
+	)
+	if err := scan(&rate.Date, &from, &to, &rateString); err != nil {
+		return entity.ExchangeRate{}, err
+	}
+	value, ok := new(big.Rat).SetString(rateString)
+	if !ok {
+		return entity.ExchangeRate{}, fmt.Errorf("invalid rate %q", rateString)
+	}
+	rate.Date = rate.Date.UTC()
+	rate.From = entity.Currency(from)
+	rate.To = entity.Currency(to)
+	rate.Rate = value
+	return rate, nil
+}
@@ -0,0 +1,139 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/axpira/backend/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExchangeRateSave(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	repo := NewExchangeRateRepository(db)
+	ctx := context.Background()
+	date := time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	err = repo.Save(ctx, entity.ExchangeRate{Date: date, From: "USD", To: "USD", Rate: big.NewRat(1, 1)})
+	fieldErrors := entity.UnwrapFieldErrors(err)
+	if assert.Len(t, fieldErrors, 1, "must validate the currencies") {
+		assert.Equal(t, "to", fieldErrors[0].Field())
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO tb_exchange_rate \\(date,from_currency,to_currency,rate,createdAt,updatedAt\\) "+
+		"VALUES \\(\\$1, \\$2, \\$3, \\$4, \\$5, \\$6\\), \\(\\$7, \\$8, \\$9, \\$10, \\$11, \\$12\\) "+
+		"ON CONFLICT \\(from_currency, to_currency, date\\) DO UPDATE SET rate = EXCLUDED.rate, updatedAt = EXCLUDED.updatedAt;").
+		WithArgs(
+			sql.Named("date", "2021-05-01"),
+			sql.Named("from_currency", "USD"),
+			sql.Named("to_currency", "BRL"),
+			sql.Named("rate", "5.123400000000"),
+			timeMatch{time.Now().UTC()},
+			timeMatch{time.Now().UTC()},
+			sql.Named("date", "2021-05-01"),
+			sql.Named("from_currency", "JPY"),
+			sql.Named("to_currency", "BRL"),
+			sql.Named("rate", "0.333333333333"),
+			timeMatch{time.Now().UTC()},
+			timeMatch{time.Now().UTC()},
+		).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+	err = repo.Save(ctx,
+		entity.ExchangeRate{Date: date, From: "USD", To: "BRL", Rate: big.NewRat(5, 1)},
+		entity.ExchangeRate{Date: date, From: "JPY", To: "BRL", Rate: big.NewRat(1, 3)},
+		entity.ExchangeRate{Date: date, From: "USD", To: "BRL", Rate: big.NewRat(51234, 10000)},
+	)
+	assert.NoError(t, mock.ExpectationsWereMet(), "must save the last of the repeated rates once")
+	assert.NoError(t, err)
+}
+
+func TestExchangeRateSaveBatches(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	repo := NewExchangeRateRepository(db)
+	ctx := context.Background()
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	rates := make([]entity.ExchangeRate, exchangeRateBatch+1)
+	for i := range rates {
+		rates[i] = entity.ExchangeRate{Date: start.AddDate(0, 0, i), From: "USD", To: "BRL", Rate: big.NewRat(5, 1)}
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO tb_exchange_rate").WillReturnResult(sqlmock.NewResult(0, exchangeRateBatch))
+	mock.ExpectExec("INSERT INTO tb_exchange_rate").WillReturnError(errors.New("connection reset"))
+	mock.ExpectRollback()
+	err = repo.Save(ctx, rates...)
+	assert.ErrorIs(t, err, entity.ErrTechnical)
+	assert.NoError(t, mock.ExpectationsWereMet(), "must roll back the batches already saved")
+}
+
+func TestExchangeRateFind(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	repo := NewExchangeRateRepository(db)
+	ctx := context.Background()
+	on := time.Date(2021, 5, 3, 15, 0, 0, 0, time.UTC)
+	const wantQuery = "SELECT date, from_currency, to_currency, rate FROM tb_exchange_rate " +
+		"WHERE from_currency = \\$1 AND to_currency = \\$2 AND date <= \\$3 ORDER BY date DESC LIMIT 1;"
+	args := []driver.Value{
+		sql.Named("from_currency", "USD"),
+		sql.Named("to_currency", "BRL"),
+		sql.Named("date", "2021-05-03"),
+	}
+
+	mock.ExpectQuery(wantQuery).
+		WithArgs(args...).
+		WillReturnRows(sqlmock.NewRows([]string{"date", "from_currency", "to_currency", "rate"}).
+			AddRow(time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC), "USD", "BRL", "5.123400000000"))
+	rate, err := repo.Find(ctx, "USD", "BRL", on)
+	assert.NoError(t, err)
+	assert.Equal(t, "USD", string(rate.From))
+	assert.Equal(t, "BRL", string(rate.To))
+	assert.Equal(t, 0, rate.Rate.Cmp(big.NewRat(25617, 5000)))
+
+	mock.ExpectQuery(wantQuery).
+		WithArgs(args...).
+		WillReturnError(sql.ErrNoRows)
+	_, err = repo.Find(ctx, "USD", "BRL", on)
+	assert.ErrorIs(t, err, entity.ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExchangeRateList(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	repo := NewExchangeRateRepository(db)
+	ctx := context.Background()
+
+	mock.ExpectQuery("SELECT date, from_currency, to_currency, rate FROM tb_exchange_rate " +
+		"WHERE to_currency = \\$1 ORDER BY date, from_currency, to_currency;").
+		WithArgs(sql.Named("to_currency", "BRL")).
+		WillReturnRows(sqlmock.NewRows([]string{"date", "from_currency", "to_currency", "rate"}).
+			AddRow(time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC), "USD", "BRL", "5.1").
+			AddRow(time.Date(2021, 5, 2, 0, 0, 0, 0, time.UTC), "EUR", "BRL", "6.2"))
+	rates, err := repo.List(ctx, "", "BRL")
+	assert.NoError(t, err)
+	assert.Len(t, rates, 2)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
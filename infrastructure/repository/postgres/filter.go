@@ -0,0 +1,182 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/axpira/backend/entity"
+)
+
+var (
+	opFilterSQL = map[entity.OpFilterType]string{
+		entity.EQ: "=",
+		entity.LT: "<",
+		entity.GT: ">",
+		entity.LE: "<=",
+		entity.GE: ">=",
+	}
+	strFilterSQL = map[entity.StrFilterType]string{
+		entity.EQUALS: "=",
+		entity.REGEX:  "~",
+	}
+)
+
+type whereClause struct {
+	conditions []string
+	args       []interface{}
+}
+
+// arg registers a new positional argument and returns its placeholder
+func (w *whereClause) arg(name string, value interface{}) string {
+	w.args = append(w.args, sql.Named(name, value))
+	return fmt.Sprintf("$%d", len(w.args))
+}
+
+func (w *whereClause) add(condition string) {
+	w.conditions = append(w.conditions, condition)
+}
+
+func (w whereClause) String() string {
+	if len(w.conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(w.conditions, " AND ")
+}
+
+// kindFilters matches any of the kinds
+func (w *whereClause) kindFilters(column string, kinds []entity.TransactionKind) {
+	if len(kinds) == 0 {
+		return
+	}
+	placeholders := make([]string, len(kinds))
+	for i, kind := range kinds {
+		placeholders[i] = w.arg(column, string(kind))
+	}
+	w.add(fmt.Sprintf("%s IN (%s)", column, strings.Join(placeholders, ", ")))
+}
+
+func (w *whereClause) intFilters(err error, column string, filters []entity.IntFilter) error {
+	for _, f := range filters {
+		op, ok := opFilterSQL[f.Type]
+		if !ok {
+			err = entity.NewFieldError(err, column, "invalid_operator", fmt.Sprintf("unknown operator %d", f.Type))
+			continue
+		}
+		w.add(fmt.Sprintf("%s %s %s", column, op, w.arg(column, f.Value)))
+	}
+	return err
+}
+
+func (w *whereClause) timeFilters(err error, column string, filters []entity.TimeFilter) error {
+	for _, f := range filters {
+		op, ok := opFilterSQL[f.Type]
+		if !ok {
+			err = entity.NewFieldError(err, column, "invalid_operator", fmt.Sprintf("unknown operator %d", f.Type))
+			continue
+		}
+		w.add(fmt.Sprintf("%s %s %s", column, op, w.arg(column, f.Value)))
+	}
+	return err
+}
+
+func (w *whereClause) strFilters(err error, column string, filters []entity.StrFilter) error {
+	for _, f := range filters {
+		op, ok := strFilterSQL[f.Type]
+		if !ok {
+			err = entity.NewFieldError(err, column, "invalid_operator", fmt.Sprintf("unknown operator %d", f.Type))
+			continue
+		}
+		w.add(fmt.Sprintf("%s %s %s", column, op, w.arg(column, f.Value)))
+	}
+	return err
+}
+
+func (w *whereClause) tagFilters(err error, column string, filters []entity.StrFilter) error {
+	for _, f := range filters {
+		switch f.Type {
+		case entity.EQUALS:
+			w.add(fmt.Sprintf("%s = ANY(%s)", w.arg(column, f.Value), column))
+		case entity.REGEX:
+			w.add(fmt.Sprintf("EXISTS (SELECT 1 FROM unnest(%s) AS tag WHERE tag ~ %s)", column, w.arg(column, f.Value)))
+		default:
+			err = entity.NewFieldError(err, column, "invalid_operator", fmt.Sprintf("unknown operator %d", f.Type))
+		}
+	}
+	return err
+}
+
+// categoryFilters matches the categories found by id or name and all their descendants
+func (w *whereClause) categoryFilters(err error, column string, filters []entity.StrFilter) error {
+	for _, f := range filters {
+		var root string
+		switch f.Type {
+		case entity.EQUALS:
+			root = "id = " + w.arg("category", f.Value)
+		case entity.REGEX:
+			root = "name ~ " + w.arg("category", f.Value)
+		default:
+			err = entity.NewFieldError(err, "category", "invalid_operator", fmt.Sprintf("unknown operator %d", f.Type))
+			continue
+		}
+		w.add(fmt.Sprintf("%s IN (%s)", column, categoryTreeQuery(root)))
+	}
+	return err
+}
+
+func (w *whereClause) paymentMethodFilters(err error, column string, filters []entity.StrFilter) error {
+	for _, f := range filters {
+		switch f.Type {
+		case entity.EQUALS:
+			w.add(fmt.Sprintf("%s = %s", column, w.arg("paymentMethod", f.Value)))
+		case entity.REGEX:
+			w.add(fmt.Sprintf("%s IN (SELECT id FROM %s WHERE name ~ %s)", column, PAYMENT_METHOD_TABLE_NAME, w.arg("paymentMethod", f.Value)))
+		default:
+			err = entity.NewFieldError(err, "paymentMethod", "invalid_operator", fmt.Sprintf("unknown operator %d", f.Type))
+		}
+	}
+	return err
+}
+
+// urnFilters matches the ids referenced by the URNs of kind
+func (w *whereClause) urnFilters(err error, column string, kind entity.URNKind, urns []entity.URN) error {
+	for _, urn := range urns {
+		id, urnErr := urn.Id(kind)
+		if urnErr != nil {
+			err = entity.NewFieldError(err, column, "invalid_urn", urnErr.Error())
+			continue
+		}
+		w.add(fmt.Sprintf("%s = %s", column, w.arg(column, id)))
+	}
+	return err
+}
+
+func newExpenseWhere(filter *entity.ExpenseFilter) (whereClause, error) {
+	w := whereClause{}
+	if filter == nil {
+		return w, nil
+	}
+	var err error
+	err = w.strFilters(err, "id", filter.Id)
+	w.kindFilters("kind", filter.Kind)
+	err = w.intFilters(err, "amount", filter.Amount)
+	err = w.strFilters(err, "currency", filter.Currency)
+	err = w.timeFilters(err, "timestamp", filter.When)
+	err = w.urnFilters(err, "place_id", entity.PLACE, filter.Where)
+	err = w.urnFilters(err, "person_id", entity.PERSON, filter.Who)
+	err = w.strFilters(err, "what", filter.What)
+	err = w.detailFilters(err, filter.Detail)
+	err = w.tagFilters(err, "tags", filter.Tag)
+	err = w.categoryFilters(err, "category_id", filter.Category)
+	err = w.paymentMethodFilters(err, "payment_method_id", filter.PaymentMethod)
+	err = w.metadataFilters(err, "metadata", filter.Metadata)
+	err = w.timeFilters(err, "createdAt", filter.CreatedAt)
+	err = w.timeFilters(err, "updatedAt", filter.UpdatedAt)
+	if filter.Split {
+		w.add("splits IS NOT NULL")
+	}
+	if err != nil {
+		return whereClause{}, err
+	}
+	return w, nil
+}
@@ -0,0 +1,124 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/axpira/backend/entity"
+	"github.com/rs/zerolog/log"
+)
+
+const IDEMPOTENCY_TABLE_NAME = "tb_idempotency_key"
+
+type IdempotencyRepository interface {
+	Reserve(ctx context.Context, key entity.IdempotencyKey) error
+	Get(ctx context.Context, key string) (entity.IdempotencyKey, error)
+	Save(ctx context.Context, key entity.IdempotencyKey) error
+	Delete(ctx context.Context, key string) error
+}
+
+type idempotencyRepository struct {
+	db DB
+}
+
+func NewIdempotencyRepository(db DB) IdempotencyRepository {
+	return idempotencyRepository{
+		db: db,
+	}
+}
+
+// Reserve stores the key without a response, it's a conflict when the key is stored and not expired yet
+func (r idempotencyRepository) Reserve(ctx context.Context, key entity.IdempotencyKey) error {
+	if err := key.Validate(); err != nil {
+		return err
+	}
+	query := fmt.Sprintf(
+		"INSERT INTO %[1]s (key,request_hash,status,content_type,response,expires_at) VALUES ($1, $2, 0, NULL, NULL, $3) "+
+			"ON CONFLICT (key) DO UPDATE SET request_hash = EXCLUDED.request_hash, status = 0, content_type = NULL, response = NULL, expires_at = EXCLUDED.expires_at "+
+			"WHERE %[1]s.expires_at <= $4;",
+		IDEMPOTENCY_TABLE_NAME,
+	)
+	log.Ctx(ctx).Debug().Msgf("runing: %v", query)
+	result, err := r.db.ExecContext(ctx, query,
+		sql.Named("key", key.Key),
+		sql.Named("request_hash", key.RequestHash),
+		sql.Named("expires_at", key.ExpiresAt.UTC()),
+		sql.Named("now", time.Now().UTC()),
+	)
+	if err != nil {
+		return translateError(err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("idempotency key %s was already %w", key.Key, entity.ErrConflict)
+	}
+	return nil
+}
+
+// Get returns the key while it's not expired
+func (r idempotencyRepository) Get(ctx context.Context, key string) (entity.IdempotencyKey, error) {
+	if strings.TrimSpace(key) == "" {
+		return entity.IdempotencyKey{}, entity.NewFieldError(nil, "idempotencyKey", "no_empty", "can't be empty")
+	}
+	query := fmt.Sprintf("SELECT request_hash,status,content_type,response,expires_at FROM %s WHERE key = $1 AND expires_at > $2;", IDEMPOTENCY_TABLE_NAME)
+	log.Ctx(ctx).Debug().Msgf("runing: %v", query)
+	k := entity.IdempotencyKey{Key: key}
+	var contentType sql.NullString
+	err := r.db.QueryRowContext(ctx, query, sql.Named("key", key), sql.Named("now", time.Now().UTC())).
+		Scan(&k.RequestHash, &k.Status, &contentType, &k.Response, &k.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entity.IdempotencyKey{}, fmt.Errorf("id %s was %w", key, entity.ErrNotFound)
+		}
+		return entity.IdempotencyKey{}, fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+	}
+	k.ContentType = contentType.String
+	k.ExpiresAt = k.ExpiresAt.UTC()
+	return k, nil
+}
+
+// Save stores the response of a reserved key
+func (r idempotencyRepository) Save(ctx context.Context, key entity.IdempotencyKey) error {
+	if err := key.Validate(); err != nil {
+		return err
+	}
+	query := fmt.Sprintf("UPDATE %s SET status = $2, content_type = $3, response = $4 WHERE key = $1;", IDEMPOTENCY_TABLE_NAME)
+	log.Ctx(ctx).Debug().Msgf("runing: %v", query)
+	result, err := r.db.ExecContext(ctx, query,
+		sql.Named("key", key.Key),
+		sql.Named("status", key.Status),
+		sql.Named("content_type", sql.NullString{String: key.ContentType, Valid: key.ContentType != ""}),
+		sql.Named("response", key.Response),
+	)
+	if err != nil {
+		return translateError(err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("id %s was %w", key.Key, entity.ErrNotFound)
+	}
+	return nil
+}
+
+// Delete releases the key, so the request can be sent again with it
+func (r idempotencyRepository) Delete(ctx context.Context, key string) error {
+	if strings.TrimSpace(key) == "" {
+		return entity.NewFieldError(nil, "idempotencyKey", "no_empty", "can't be empty")
+	}
+	query := fmt.Sprintf("DELETE FROM %s WHERE key = $1;", IDEMPOTENCY_TABLE_NAME)
+	log.Ctx(ctx).Debug().Msgf("runing: %v", query)
+	if _, err := r.db.ExecContext(ctx, query, sql.Named("key", key)); err != nil {
+		return fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/axpira/backend/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotencyReserve(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	repo := NewIdempotencyRepository(db)
+	ctx := context.Background()
+	expiresAt := time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC)
+	key := entity.IdempotencyKey{Key: "abc", RequestHash: "hash", ExpiresAt: expiresAt}
+	query := "INSERT INTO tb_idempotency_key \\(key,request_hash,status,content_type,response,expires_at\\) VALUES \\(\\$1, \\$2, 0, NULL, NULL, \\$3\\) " +
+		"ON CONFLICT \\(key\\) DO UPDATE SET request_hash = EXCLUDED.request_hash, status = 0, content_type = NULL, response = NULL, expires_at = EXCLUDED.expires_at " +
+		"WHERE tb_idempotency_key.expires_at <= \\$4;"
+	args := []driver.Value{
+		sql.Named("key", "abc"),
+		sql.Named("request_hash", "hash"),
+		sql.Named("expires_at", expiresAt),
+		timeMatch{time.Now().UTC()},
+	}
+
+	mock.ExpectExec(query).WithArgs(args...).WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, repo.Reserve(ctx, key))
+
+	mock.ExpectExec(query).WithArgs(args...).WillReturnResult(sqlmock.NewResult(0, 0))
+	err = repo.Reserve(ctx, key)
+	assert.ErrorIs(t, err, entity.ErrConflict, "must not reserve a key stored and not expired")
+
+	err = repo.Reserve(ctx, entity.IdempotencyKey{RequestHash: "hash", ExpiresAt: expiresAt})
+	fieldErrors := entity.UnwrapFieldErrors(err)
+	if assert.Len(t, fieldErrors, 1, "must validate the key") {
+		assert.Equal(t, "idempotencyKey", fieldErrors[0].Field())
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIdempotencyGet(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	repo := NewIdempotencyRepository(db)
+	ctx := context.Background()
+	expiresAt := time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC)
+	columns := []string{"request_hash", "status", "content_type", "response", "expires_at"}
+	query := "SELECT request_hash,status,content_type,response,expires_at FROM tb_idempotency_key WHERE key = \\$1 AND expires_at > \\$2;"
+
+	mock.ExpectQuery(query).
+		WithArgs(sql.Named("key", "abc"), timeMatch{time.Now().UTC()}).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("hash", 200, "application/json", []byte(`{"id":"1"}`), expiresAt))
+	got, err := repo.Get(ctx, "abc")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.IdempotencyKey{
+		Key:         "abc",
+		RequestHash: "hash",
+		Status:      200,
+		ContentType: "application/json",
+		Response:    []byte(`{"id":"1"}`),
+		ExpiresAt:   expiresAt,
+	}, got)
+
+	mock.ExpectQuery(query).
+		WithArgs(sql.Named("key", "expired"), timeMatch{time.Now().UTC()}).
+		WillReturnRows(sqlmock.NewRows(columns))
+	_, err = repo.Get(ctx, "expired")
+	assert.ErrorIs(t, err, entity.ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIdempotencySave(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	repo := NewIdempotencyRepository(db)
+	ctx := context.Background()
+	key := entity.IdempotencyKey{Key: "abc", RequestHash: "hash", Status: 200, Response: []byte(`{"id":"1"}`)}
+	query := "UPDATE tb_idempotency_key SET status = \\$2, content_type = \\$3, response = \\$4 WHERE key = \\$1;"
+	args := []driver.Value{
+		sql.Named("key", "abc"),
+		sql.Named("status", 200),
+		sql.Named("content_type", sql.NullString{}),
+		sql.Named("response", []byte(`{"id":"1"}`)),
+	}
+
+	mock.ExpectExec(query).WithArgs(args...).WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, repo.Save(ctx, key))
+
+	mock.ExpectExec(query).WithArgs(args...).WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.ErrorIs(t, repo.Save(ctx, key), entity.ErrNotFound, "must not save a key not reserved")
+
+	mock.ExpectExec("DELETE FROM tb_idempotency_key WHERE key = \\$1;").
+		WithArgs(sql.Named("key", "abc")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, repo.Delete(ctx, "abc"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,41 @@
+package postgres
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/axpira/backend/entity"
+)
+
+// metadataArg never fails, the map only has strings
+func metadataArg(metadata map[string]string) sql.NullString {
+	b, _ := json.Marshal(metadata)
+	return sql.NullString{String: string(b), Valid: true}
+}
+
+func parseMetadataJSON(value string) (map[string]string, error) {
+	var metadata map[string]string
+	if err := json.Unmarshal([]byte(value), &metadata); err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
+// metadataFilters matches the key existence, or its value when the filter has an operator.
+// Existence and equality use the GIN index of the column
+func (w *whereClause) metadataFilters(err error, column string, filters []entity.MetadataFilter) error {
+	for _, f := range filters {
+		switch f.Type {
+		case 0:
+			w.add(fmt.Sprintf("%s ? %s", column, w.arg(column, f.Key)))
+		case entity.EQUALS:
+			w.add(fmt.Sprintf("%s @> jsonb_build_object(%s::text, %s::text)", column, w.arg(column, f.Key), w.arg(column, f.Value)))
+		case entity.REGEX:
+			w.add(fmt.Sprintf("%s ->> %s ~ %s", column, w.arg(column, f.Key), w.arg(column, f.Value)))
+		default:
+			err = entity.NewFieldError(err, column+"."+f.Key, "invalid_operator", fmt.Sprintf("unknown operator %d", f.Type))
+		}
+	}
+	return err
+}
@@ -0,0 +1,195 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/axpira/backend/entity"
+	"github.com/rs/zerolog/log"
+)
+
+// sortKeySQL never returns NULL, so the rows without a value still compare in the cursor, first on ascending order
+var sortKeySQL = map[entity.SortField]struct {
+	key  string
+	time bool
+}{
+	entity.SORT_WHEN:       {key: "coalesce(timestamp, 'epoch'::timestamptz)", time: true},
+	entity.SORT_AMOUNT:     {key: "coalesce(amount, 0)"},
+	entity.SORT_CREATED_AT: {key: "coalesce(createdAt, 'epoch'::timestamptz)", time: true},
+}
+
+// pageCursor is the position of the sort key and id of an expense, read from there on
+// or backwards from there. It goes to the clients as base64 JSON, they shouldn't rely on its content
+type pageCursor struct {
+	Sort     string `json:"s"`
+	Value    string `json:"v"`
+	Id       string `json:"i"`
+	Backward bool   `json:"b,omitempty"`
+}
+
+func invalidCursor() error {
+	return entity.NewFieldError(nil, "cursor", "invalid", "must be the next or prev of a page with the same sort")
+}
+
+func (c pageCursor) String() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func parsePageCursor(value string, sort entity.Sort) (pageCursor, error) {
+	var c pageCursor
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return c, invalidCursor()
+	}
+	if err := json.Unmarshal(b, &c); err != nil || c.Sort != sort.String() || c.Id == "" {
+		return c, invalidCursor()
+	}
+	return c, nil
+}
+
+// arg converts the value back to the type of the sort key
+func (c pageCursor) arg(isTime bool) (interface{}, error) {
+	if isTime {
+		t, err := time.Parse(time.RFC3339Nano, c.Value)
+		if err != nil {
+			return nil, invalidCursor()
+		}
+		return t, nil
+	}
+	amount, err := strconv.ParseInt(c.Value, 10, 64)
+	if err != nil {
+		return nil, invalidCursor()
+	}
+	return amount, nil
+}
+
+func newPageCursor(sort entity.Sort, key interface{}, id string, backward bool) string {
+	c := pageCursor{Sort: sort.String(), Id: id, Backward: backward}
+	switch v := key.(type) {
+	case time.Time:
+		c.Value = v.UTC().Format(time.RFC3339Nano)
+	default:
+		c.Value = fmt.Sprintf("%v", v)
+	}
+	return c.String()
+}
+
+// SearchPage reads a page of the expenses matching filter by keyset, the sort key and then the id,
+// so the pages don't shift when expenses are created or deleted between them
+func (r expenseRepository) SearchPage(ctx context.Context, filter *entity.ExpenseFilter, page entity.PageRequest) (entity.Page, error) {
+	l := log.Ctx(ctx)
+	if page.Sort.Field == "" {
+		page.Sort.Field = entity.SORT_WHEN
+	}
+	if err := page.Validate(); err != nil {
+		return entity.Page{}, err
+	}
+	where, err := newExpenseWhere(filter)
+	if err != nil {
+		return entity.Page{}, err
+	}
+	res := entity.Page{Expenses: make([]entity.Expense, 0)}
+	if page.Count {
+		total, err := r.count(ctx, where)
+		if err != nil {
+			return entity.Page{}, err
+		}
+		res.Total = &total
+	}
+
+	s := sortKeySQL[page.Sort.Field]
+	var cursor pageCursor
+	if page.Cursor != "" {
+		if cursor, err = parsePageCursor(page.Cursor, page.Sort); err != nil {
+			return entity.Page{}, err
+		}
+		value, err := cursor.arg(s.time)
+		if err != nil {
+			return entity.Page{}, err
+		}
+		op := ">"
+		if page.Sort.Desc != cursor.Backward {
+			op = "<"
+		}
+		where.add(fmt.Sprintf("(%s, id) %s (%s, %s)", s.key, op, where.arg("cursor", value), where.arg("cursor_id", cursor.Id)))
+	}
+	direction := "ASC"
+	if page.Sort.Desc != cursor.Backward {
+		direction = "DESC"
+	}
+	query := fmt.Sprintf(
+		"SELECT id,%s,%s FROM %s%s ORDER BY %s %s, id %s LIMIT %d;",
+		expenseSelectColumns, s.key, TABLE_NAME, where.String(), s.key, direction, direction, page.Limit+1,
+	)
+	if e := l.Debug(); e.Enabled() {
+		for i, a := range where.args {
+			n := a.(sql.NamedArg)
+			e = e.Str(fmt.Sprintf("param_%d_%s", i+1, n.Name), fmt.Sprintf("%v", n.Value))
+		}
+		e.Msgf("runing: %v", query)
+	}
+	rows, err := r.db.QueryContext(ctx, query, where.args...)
+	if err != nil {
+		return entity.Page{}, fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+	}
+	defer rows.Close()
+
+	var keys []interface{}
+	for rows.Next() {
+		row := ExpenseRow{}
+		var key interface{}
+		if err := rows.Scan(append(append([]interface{}{&row.Id}, row.Scan()...), &key)...); err != nil {
+			return entity.Page{}, fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+		}
+		expense, err := row.ToExpense()
+		if err != nil {
+			return entity.Page{}, err
+		}
+		res.Expenses = append(res.Expenses, expense)
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return entity.Page{}, fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+	}
+
+	more := len(res.Expenses) > page.Limit
+	if more {
+		res.Expenses, keys = res.Expenses[:page.Limit], keys[:page.Limit]
+	}
+	if cursor.Backward {
+		for i, j := 0, len(keys)-1; i < j; i, j = i+1, j-1 {
+			res.Expenses[i], res.Expenses[j] = res.Expenses[j], res.Expenses[i]
+			keys[i], keys[j] = keys[j], keys[i]
+		}
+	}
+	if len(keys) == 0 {
+		return res, nil
+	}
+	first, last := 0, len(keys)-1
+	// going backwards there's always the page it came from after it, and going forward
+	// there's one before it when it started from a cursor
+	if more || cursor.Backward {
+		res.Next = newPageCursor(page.Sort, keys[last], res.Expenses[last].Id, false)
+	}
+	if (cursor.Backward && more) || (!cursor.Backward && page.Cursor != "") {
+		res.Prev = newPageCursor(page.Sort, keys[first], res.Expenses[first].Id, true)
+	}
+	return res, nil
+}
+
+func (r expenseRepository) count(ctx context.Context, where whereClause) (int64, error) {
+	l := log.Ctx(ctx)
+	query := fmt.Sprintf("SELECT count(*) FROM %s%s;", TABLE_NAME, where.String())
+	l.Debug().Msgf("runing: %v", query)
+	var total int64
+	if err := r.db.QueryRowContext(ctx, query, where.args...).Scan(&total); err != nil {
+		return 0, fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+	}
+	return total, nil
+}
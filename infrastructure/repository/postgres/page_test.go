@@ -0,0 +1,130 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/axpira/backend/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchPage(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	repo := NewExpenseRepository(db)
+	ctx := context.Background()
+	when := time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+	expenses := []entity.Expense{newRandomExpense(), newRandomExpense(), newRandomExpense()}
+	whenKey := regexp.QuoteMeta(sortKeySQL[entity.SORT_WHEN].key)
+	amountKey := regexp.QuoteMeta(sortKeySQL[entity.SORT_AMOUNT].key)
+	byWhen := entity.Sort{Field: entity.SORT_WHEN}
+	byAmountDesc := entity.Sort{Field: entity.SORT_AMOUNT, Desc: true}
+
+	tests := map[string]struct {
+		filter    *entity.ExpenseFilter
+		page      entity.PageRequest
+		wantQuery string
+		args      []driver.Value
+		// rows are the expenses returned by the query, keys their sort keys
+		rows      []entity.Expense
+		keys      []driver.Value
+		wantTotal *int64
+		wantIds   []string
+		wantNext  string
+		wantPrev  string
+		wantField string
+	}{
+		"must read the first page by when": {
+			page:      entity.PageRequest{Limit: 2},
+			wantQuery: "SELECT id," + selectColumns + "," + whenKey + " FROM tb_expense ORDER BY " + whenKey + " ASC, id ASC LIMIT 3;",
+			rows:      expenses,
+			keys:      []driver.Value{when, when, when.Add(time.Hour)},
+			wantIds:   []string{expenses[0].Id, expenses[1].Id},
+			wantNext:  newPageCursor(byWhen, when, expenses[1].Id, false),
+		},
+		"must read the next page from the cursor": {
+			filter: &entity.ExpenseFilter{What: []entity.StrFilter{{Type: entity.EQUALS, Value: "coffee"}}},
+			page:   entity.PageRequest{Limit: 2, Sort: byWhen, Cursor: newPageCursor(byWhen, when, expenses[1].Id, false)},
+			wantQuery: "SELECT id," + selectColumns + "," + whenKey + " FROM tb_expense WHERE what = \\$1 AND " +
+				"\\(" + whenKey + ", id\\) > \\(\\$2, \\$3\\) ORDER BY " + whenKey + " ASC, id ASC LIMIT 3;",
+			args:     []driver.Value{sql.Named("what", "coffee"), sql.Named("cursor", when), sql.Named("cursor_id", expenses[1].Id)},
+			rows:     expenses[2:],
+			keys:     []driver.Value{when.Add(time.Hour)},
+			wantIds:  []string{expenses[2].Id},
+			wantPrev: newPageCursor(byWhen, when.Add(time.Hour), expenses[2].Id, true),
+		},
+		"must read the previous page backwards and return it in order": {
+			page: entity.PageRequest{Limit: 2, Sort: byAmountDesc, Cursor: newPageCursor(byAmountDesc, int64(100), expenses[2].Id, true)},
+			wantQuery: "SELECT id," + selectColumns + "," + amountKey + " FROM tb_expense WHERE " +
+				"\\(" + amountKey + ", id\\) > \\(\\$1, \\$2\\) ORDER BY " + amountKey + " ASC, id ASC LIMIT 3;",
+			args:     []driver.Value{sql.Named("cursor", int64(100)), sql.Named("cursor_id", expenses[2].Id)},
+			rows:     []entity.Expense{expenses[1], expenses[0]},
+			keys:     []driver.Value{int64(200), int64(300)},
+			wantIds:  []string{expenses[0].Id, expenses[1].Id},
+			wantNext: newPageCursor(byAmountDesc, int64(200), expenses[1].Id, false),
+		},
+		"must read the first page descending with the total": {
+			page:      entity.PageRequest{Limit: 5, Sort: byAmountDesc, Count: true},
+			wantQuery: "SELECT id," + selectColumns + "," + amountKey + " FROM tb_expense ORDER BY " + amountKey + " DESC, id DESC LIMIT 6;",
+			rows:      expenses[:1],
+			keys:      []driver.Value{int64(300)},
+			wantTotal: func(i int64) *int64 { return &i }(1),
+			wantIds:   []string{expenses[0].Id},
+		},
+		"must not accept a cursor that isn't one": {
+			page:      entity.PageRequest{Limit: 2, Cursor: "not a cursor"},
+			wantField: "cursor",
+		},
+		"must not accept a cursor of another sort": {
+			page:      entity.PageRequest{Limit: 2, Sort: byAmountDesc, Cursor: newPageCursor(byWhen, when, expenses[1].Id, false)},
+			wantField: "cursor",
+		},
+		"must validate the limit": {
+			page:      entity.PageRequest{Limit: entity.MaxPageLimit + 1},
+			wantField: "limit",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if tc.wantTotal != nil {
+				mock.ExpectQuery("SELECT count\\(\\*\\) FROM tb_expense;").
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(*tc.wantTotal))
+			}
+			if tc.wantQuery != "" {
+				rows := sqlmock.NewRows(append(append([]string{"id"}, selectColumnsArr...), "key"))
+				for i, e := range tc.rows {
+					rows.AddRow(append(append([]driver.Value{e.Id}, expenseValues(e)...), tc.keys[i])...)
+				}
+				mock.ExpectQuery(tc.wantQuery).WithArgs(tc.args...).WillReturnRows(rows)
+			}
+			page, err := repo.SearchPage(ctx, tc.filter, tc.page)
+			assert.NoError(t, mock.ExpectationsWereMet())
+			if tc.wantField != "" {
+				fieldErrors := entity.UnwrapFieldErrors(err)
+				if assert.Len(t, fieldErrors, 1) {
+					assert.Equal(t, tc.wantField, fieldErrors[0].Field())
+				}
+				return
+			}
+			if !assert.NoError(t, err) {
+				return
+			}
+			ids := make([]string, len(page.Expenses))
+			for i, e := range page.Expenses {
+				ids[i] = e.Id
+			}
+			assert.Equal(t, tc.wantIds, ids)
+			assert.Equal(t, tc.wantNext, page.Next, "next")
+			assert.Equal(t, tc.wantPrev, page.Prev, "prev")
+			assert.Equal(t, tc.wantTotal, page.Total, "total")
+		})
+	}
+}
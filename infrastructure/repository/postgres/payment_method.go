@@ -0,0 +1,151 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/axpira/backend/entity"
+	"github.com/oklog/ulid/v2"
+	"github.com/rs/zerolog/log"
+)
+
+const PAYMENT_METHOD_TABLE_NAME = "tb_payment_method"
+
+type PaymentMethodRepository interface {
+	Create(ctx context.Context, paymentMethod entity.PaymentMethod) (string, error)
+	Update(ctx context.Context, paymentMethod entity.PaymentMethod) error
+	Delete(ctx context.Context, id string) error
+	Get(ctx context.Context, id string) (entity.PaymentMethod, error)
+	List(ctx context.Context) ([]entity.PaymentMethod, error)
+}
+
+type paymentMethodRepository struct {
+	db      DB
+	entropy io.Reader
+}
+
+func NewPaymentMethodRepository(db DB) PaymentMethodRepository {
+	return paymentMethodRepository{
+		db:      db,
+		entropy: defaultEntropy(),
+	}
+}
+
+func (r paymentMethodRepository) Create(ctx context.Context, paymentMethod entity.PaymentMethod) (string, error) {
+	if err := paymentMethod.Validate(); err != nil {
+		return "", err
+	}
+	id, err := ulid.New(ulid.Timestamp(time.Now().UTC()), r.entropy)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now().UTC()
+	query := fmt.Sprintf("INSERT INTO %s (id,name,type,createdAt,updatedAt) VALUES ($1, $2, $3, $4, $5);", PAYMENT_METHOD_TABLE_NAME)
+	log.Ctx(ctx).Debug().Msgf("runing: %v", query)
+	_, err = r.db.ExecContext(ctx, query,
+		sql.Named("id", id.String()),
+		sql.Named("name", strings.TrimSpace(paymentMethod.Name)),
+		sql.Named("type", string(paymentMethod.Type)),
+		sql.Named("createdAt", now),
+		sql.Named("updatedAt", now),
+	)
+	if err != nil {
+		return "", translateError(err)
+	}
+	return id.String(), nil
+}
+
+func (r paymentMethodRepository) Update(ctx context.Context, paymentMethod entity.PaymentMethod) error {
+	if strings.TrimSpace(paymentMethod.Id) == "" {
+		return entity.NewFieldError(nil, "id", "empty", "can't be empty")
+	}
+	if err := paymentMethod.Validate(); err != nil {
+		return err
+	}
+	query := fmt.Sprintf("UPDATE %s SET name = $2, type = $3, updatedAt = $4 WHERE id = $1;", PAYMENT_METHOD_TABLE_NAME)
+	log.Ctx(ctx).Debug().Msgf("runing: %v", query)
+	result, err := r.db.ExecContext(ctx, query,
+		sql.Named("id", paymentMethod.Id),
+		sql.Named("name", strings.TrimSpace(paymentMethod.Name)),
+		sql.Named("type", string(paymentMethod.Type)),
+		sql.Named("updatedAt", time.Now().UTC()),
+	)
+	if err != nil {
+		return translateError(err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnknown, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("id %s was %w", paymentMethod.Id, entity.ErrNotFound)
+	}
+	return nil
+}
+
+func (r paymentMethodRepository) Delete(ctx context.Context, id string) error {
+	if strings.TrimSpace(id) == "" {
+		return entity.NewFieldError(nil, "id", "empty", "can't be empty")
+	}
+	query := fmt.Sprintf("DELETE FROM %s WHERE id = $1;", PAYMENT_METHOD_TABLE_NAME)
+	log.Ctx(ctx).Debug().Msgf("runing: %v", query)
+	result, err := r.db.ExecContext(ctx, query, sql.Named("id", id))
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return fmt.Errorf("payment method %s has expenses: %w", id, entity.ErrConflict)
+		}
+		return fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("id %s was %w", id, entity.ErrNotFound)
+	}
+	return nil
+}
+
+func (r paymentMethodRepository) Get(ctx context.Context, id string) (entity.PaymentMethod, error) {
+	if strings.TrimSpace(id) == "" {
+		return entity.PaymentMethod{}, entity.NewFieldError(nil, "id", "empty", "can't be empty")
+	}
+	query := fmt.Sprintf("SELECT name, type FROM %s WHERE id = $1;", PAYMENT_METHOD_TABLE_NAME)
+	log.Ctx(ctx).Debug().Msgf("runing: %v", query)
+	p := entity.PaymentMethod{Id: id}
+	err := r.db.QueryRowContext(ctx, query, sql.Named("id", id)).Scan(&p.Name, &p.Type)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entity.PaymentMethod{}, fmt.Errorf("id %s was %w", id, entity.ErrNotFound)
+		}
+		return entity.PaymentMethod{}, fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+	}
+	return p, nil
+}
+
+func (r paymentMethodRepository) List(ctx context.Context) ([]entity.PaymentMethod, error) {
+	query := fmt.Sprintf("SELECT id, name, type FROM %s ORDER BY name, id;", PAYMENT_METHOD_TABLE_NAME)
+	log.Ctx(ctx).Debug().Msgf("runing: %v", query)
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+	}
+	defer rows.Close()
+	paymentMethods := make([]entity.PaymentMethod, 0)
+	for rows.Next() {
+		var p entity.PaymentMethod
+		if err := rows.Scan(&p.Id, &p.Name, &p.Type); err != nil {
+			return nil, fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+		}
+		paymentMethods = append(paymentMethods, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+	}
+	return paymentMethods, nil
+}
@@ -0,0 +1,117 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/axpira/backend/entity"
+	"github.com/jackc/pgconn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPaymentMethodCreate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	repo := NewPaymentMethodRepository(db)
+	ctx := context.Background()
+
+	_, err = repo.Create(ctx, entity.PaymentMethod{Name: "Wallet", Type: "gold"})
+	fieldErrors := entity.UnwrapFieldErrors(err)
+	if assert.Len(t, fieldErrors, 1, "must validate the type") {
+		assert.Equal(t, "type", fieldErrors[0].Field())
+	}
+
+	mock.ExpectExec("INSERT INTO tb_payment_method \\(id,name,type,createdAt,updatedAt\\) VALUES \\(\\$1, \\$2, \\$3, \\$4, \\$5\\);").
+		WithArgs(
+			anyULID{},
+			sql.Named("name", "Visa"),
+			sql.Named("type", "credit_card"),
+			timeMatch{time.Now().UTC()},
+			timeMatch{time.Now().UTC()},
+		).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	id, err := repo.Create(ctx, entity.PaymentMethod{Name: "Visa", Type: entity.CREDIT_CARD})
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.NoError(t, err)
+	assert.NotEmpty(t, id)
+}
+
+func TestPaymentMethodDelete(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	repo := NewPaymentMethodRepository(db)
+	ctx := context.Background()
+
+	assert.Error(t, repo.Delete(ctx, ""), "must return error on empty id")
+
+	tests := map[string]struct {
+		affected int64
+		mockErr  error
+		wantErr  error
+	}{
+		"must delete": {
+			affected: 1,
+		},
+		"must return not found": {
+			affected: 0,
+			wantErr:  entity.ErrNotFound,
+		},
+		"must return conflict when used by expenses": {
+			mockErr: &pgconn.PgError{Code: pgForeignKeyViolation, ConstraintName: "fk_expense_payment_method"},
+			wantErr: entity.ErrConflict,
+		},
+		"must return error on database error": {
+			mockErr: errors.New(String(10)),
+			wantErr: entity.ErrUnknown,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			mock.ExpectExec("DELETE FROM tb_payment_method WHERE id = \\$1;").
+				WithArgs(sql.Named("id", "card")).
+				WillReturnResult(sqlmock.NewResult(0, tc.affected)).
+				WillReturnError(tc.mockErr)
+			err := repo.Delete(ctx, "card")
+			assert.NoError(t, mock.ExpectationsWereMet())
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestPaymentMethodGet(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	repo := NewPaymentMethodRepository(db)
+	ctx := context.Background()
+
+	mock.ExpectQuery("SELECT name, type FROM tb_payment_method WHERE id = \\$1;").
+		WithArgs(sql.Named("id", "card")).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "type"}).AddRow("Visa", "credit_card"))
+	got, err := repo.Get(ctx, "card")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.PaymentMethod{Id: "card", Name: "Visa", Type: entity.CREDIT_CARD}, got)
+
+	mock.ExpectQuery("SELECT name, type FROM tb_payment_method WHERE id = \\$1;").
+		WithArgs(sql.Named("id", "unknown")).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "type"}))
+	_, err = repo.Get(ctx, "unknown")
+	assert.ErrorIs(t, err, entity.ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
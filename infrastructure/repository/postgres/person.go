@@ -0,0 +1,170 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/axpira/backend/entity"
+	"github.com/jackc/pgtype"
+	"github.com/oklog/ulid/v2"
+	"github.com/rs/zerolog/log"
+)
+
+const PERSON_TABLE_NAME = "tb_person"
+
+type PersonRepository interface {
+	Create(ctx context.Context, person entity.Person) (string, error)
+	Update(ctx context.Context, person entity.Person) error
+	Delete(ctx context.Context, id string) error
+	Get(ctx context.Context, id string) (entity.Person, error)
+	List(ctx context.Context) ([]entity.Person, error)
+	Resolve(ctx context.Context, create bool) (int64, error)
+}
+
+type personRepository struct {
+	db      DB
+	entropy io.Reader
+}
+
+func NewPersonRepository(db DB) PersonRepository {
+	return personRepository{
+		db:      db,
+		entropy: defaultEntropy(),
+	}
+}
+
+func (r personRepository) Create(ctx context.Context, person entity.Person) (string, error) {
+	if err := person.Validate(); err != nil {
+		return "", err
+	}
+	id, err := ulid.New(ulid.Timestamp(time.Now().UTC()), r.entropy)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now().UTC()
+	query := fmt.Sprintf("INSERT INTO %s (id,name,aliases,createdAt,updatedAt) VALUES ($1, $2, $3, $4, $5);", PERSON_TABLE_NAME)
+	log.Ctx(ctx).Debug().Msgf("runing: %v", query)
+	_, err = r.db.ExecContext(ctx, query,
+		sql.Named("id", id.String()),
+		sql.Named("name", strings.TrimSpace(person.Name)),
+		sql.Named("aliases", aliasesArg(person.Aliases)),
+		sql.Named("createdAt", now),
+		sql.Named("updatedAt", now),
+	)
+	if err != nil {
+		return "", translateError(err)
+	}
+	return id.String(), nil
+}
+
+func (r personRepository) Update(ctx context.Context, person entity.Person) error {
+	if strings.TrimSpace(person.Id) == "" {
+		return entity.NewFieldError(nil, "id", "empty", "can't be empty")
+	}
+	if err := person.Validate(); err != nil {
+		return err
+	}
+	query := fmt.Sprintf("UPDATE %s SET name = $2, aliases = $3, updatedAt = $4 WHERE id = $1;", PERSON_TABLE_NAME)
+	log.Ctx(ctx).Debug().Msgf("runing: %v", query)
+	result, err := r.db.ExecContext(ctx, query,
+		sql.Named("id", person.Id),
+		sql.Named("name", strings.TrimSpace(person.Name)),
+		sql.Named("aliases", aliasesArg(person.Aliases)),
+		sql.Named("updatedAt", time.Now().UTC()),
+	)
+	if err != nil {
+		return translateError(err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnknown, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("id %s was %w", person.Id, entity.ErrNotFound)
+	}
+	return nil
+}
+
+func (r personRepository) Delete(ctx context.Context, id string) error {
+	if strings.TrimSpace(id) == "" {
+		return entity.NewFieldError(nil, "id", "empty", "can't be empty")
+	}
+	query := fmt.Sprintf("DELETE FROM %s WHERE id = $1;", PERSON_TABLE_NAME)
+	log.Ctx(ctx).Debug().Msgf("runing: %v", query)
+	result, err := r.db.ExecContext(ctx, query, sql.Named("id", id))
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return fmt.Errorf("person %s has expenses: %w", id, entity.ErrConflict)
+		}
+		return fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("id %s was %w", id, entity.ErrNotFound)
+	}
+	return nil
+}
+
+func (r personRepository) Get(ctx context.Context, id string) (entity.Person, error) {
+	if strings.TrimSpace(id) == "" {
+		return entity.Person{}, entity.NewFieldError(nil, "id", "empty", "can't be empty")
+	}
+	query := fmt.Sprintf("SELECT name, aliases FROM %s WHERE id = $1;", PERSON_TABLE_NAME)
+	log.Ctx(ctx).Debug().Msgf("runing: %v", query)
+	p := entity.Person{Id: id}
+	var aliases pgtype.TextArray
+	err := r.db.QueryRowContext(ctx, query, sql.Named("id", id)).Scan(&p.Name, &aliases)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entity.Person{}, fmt.Errorf("id %s was %w", id, entity.ErrNotFound)
+		}
+		return entity.Person{}, fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+	}
+	p.Aliases = aliasesValue(aliases)
+	return p, nil
+}
+
+func (r personRepository) List(ctx context.Context) ([]entity.Person, error) {
+	query := fmt.Sprintf("SELECT id, name, aliases FROM %s ORDER BY name, id;", PERSON_TABLE_NAME)
+	log.Ctx(ctx).Debug().Msgf("runing: %v", query)
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+	}
+	defer rows.Close()
+	persons := make([]entity.Person, 0)
+	for rows.Next() {
+		var p entity.Person
+		var aliases pgtype.TextArray
+		if err := rows.Scan(&p.Id, &p.Name, &aliases); err != nil {
+			return nil, fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+		}
+		p.Aliases = aliasesValue(aliases)
+		persons = append(persons, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+	}
+	return persons, nil
+}
+
+// Resolve links the expenses without a person to the person their Who names, creating
+// the missing persons when create is set
+func (r personRepository) Resolve(ctx context.Context, create bool) (int64, error) {
+	var createPerson func(string) error
+	if create {
+		createPerson = func(name string) error {
+			_, err := r.Create(ctx, entity.Person{Name: name})
+			return err
+		}
+	}
+	return personResolver.resolve(ctx, r.db, createPerson)
+}
@@ -0,0 +1,200 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/axpira/backend/entity"
+	"github.com/jackc/pgtype"
+	"github.com/oklog/ulid/v2"
+	"github.com/rs/zerolog/log"
+)
+
+const PLACE_TABLE_NAME = "tb_place"
+
+type PlaceRepository interface {
+	Create(ctx context.Context, place entity.Place) (string, error)
+	Update(ctx context.Context, place entity.Place) error
+	Delete(ctx context.Context, id string) error
+	Get(ctx context.Context, id string) (entity.Place, error)
+	List(ctx context.Context) ([]entity.Place, error)
+	Resolve(ctx context.Context, create bool) (int64, error)
+}
+
+type placeRepository struct {
+	db      DB
+	entropy io.Reader
+}
+
+func NewPlaceRepository(db DB) PlaceRepository {
+	return placeRepository{
+		db:      db,
+		entropy: defaultEntropy(),
+	}
+}
+
+// locationArgs are NULL when the place has no location
+func locationArgs(location *entity.GeoPoint) (sql.NullFloat64, sql.NullFloat64) {
+	if location == nil {
+		return sql.NullFloat64{}, sql.NullFloat64{}
+	}
+	return sql.NullFloat64{Float64: location.Latitude, Valid: true}, sql.NullFloat64{Float64: location.Longitude, Valid: true}
+}
+
+func (r placeRepository) Create(ctx context.Context, place entity.Place) (string, error) {
+	if err := place.Validate(); err != nil {
+		return "", err
+	}
+	id, err := ulid.New(ulid.Timestamp(time.Now().UTC()), r.entropy)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now().UTC()
+	latitude, longitude := locationArgs(place.Location)
+	query := fmt.Sprintf("INSERT INTO %s (id,name,aliases,latitude,longitude,createdAt,updatedAt) VALUES ($1, $2, $3, $4, $5, $6, $7);", PLACE_TABLE_NAME)
+	log.Ctx(ctx).Debug().Msgf("runing: %v", query)
+	_, err = r.db.ExecContext(ctx, query,
+		sql.Named("id", id.String()),
+		sql.Named("name", strings.TrimSpace(place.Name)),
+		sql.Named("aliases", aliasesArg(place.Aliases)),
+		sql.Named("latitude", latitude),
+		sql.Named("longitude", longitude),
+		sql.Named("createdAt", now),
+		sql.Named("updatedAt", now),
+	)
+	if err != nil {
+		return "", translateError(err)
+	}
+	return id.String(), nil
+}
+
+func (r placeRepository) Update(ctx context.Context, place entity.Place) error {
+	if strings.TrimSpace(place.Id) == "" {
+		return entity.NewFieldError(nil, "id", "empty", "can't be empty")
+	}
+	if err := place.Validate(); err != nil {
+		return err
+	}
+	latitude, longitude := locationArgs(place.Location)
+	query := fmt.Sprintf("UPDATE %s SET name = $2, aliases = $3, latitude = $4, longitude = $5, updatedAt = $6 WHERE id = $1;", PLACE_TABLE_NAME)
+	log.Ctx(ctx).Debug().Msgf("runing: %v", query)
+	result, err := r.db.ExecContext(ctx, query,
+		sql.Named("id", place.Id),
+		sql.Named("name", strings.TrimSpace(place.Name)),
+		sql.Named("aliases", aliasesArg(place.Aliases)),
+		sql.Named("latitude", latitude),
+		sql.Named("longitude", longitude),
+		sql.Named("updatedAt", time.Now().UTC()),
+	)
+	if err != nil {
+		return translateError(err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnknown, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("id %s was %w", place.Id, entity.ErrNotFound)
+	}
+	return nil
+}
+
+func (r placeRepository) Delete(ctx context.Context, id string) error {
+	if strings.TrimSpace(id) == "" {
+		return entity.NewFieldError(nil, "id", "empty", "can't be empty")
+	}
+	query := fmt.Sprintf("DELETE FROM %s WHERE id = $1;", PLACE_TABLE_NAME)
+	log.Ctx(ctx).Debug().Msgf("runing: %v", query)
+	result, err := r.db.ExecContext(ctx, query, sql.Named("id", id))
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return fmt.Errorf("place %s has expenses: %w", id, entity.ErrConflict)
+		}
+		return fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("id %s was %w", id, entity.ErrNotFound)
+	}
+	return nil
+}
+
+type placeRow struct {
+	id        string
+	name      string
+	aliases   pgtype.TextArray
+	latitude  sql.NullFloat64
+	longitude sql.NullFloat64
+}
+
+func (p placeRow) toPlace() entity.Place {
+	place := entity.Place{
+		Id:      p.id,
+		Name:    p.name,
+		Aliases: aliasesValue(p.aliases),
+	}
+	if p.latitude.Valid && p.longitude.Valid {
+		place.Location = &entity.GeoPoint{Latitude: p.latitude.Float64, Longitude: p.longitude.Float64}
+	}
+	return place
+}
+
+func (r placeRepository) Get(ctx context.Context, id string) (entity.Place, error) {
+	if strings.TrimSpace(id) == "" {
+		return entity.Place{}, entity.NewFieldError(nil, "id", "empty", "can't be empty")
+	}
+	query := fmt.Sprintf("SELECT name, aliases, latitude, longitude FROM %s WHERE id = $1;", PLACE_TABLE_NAME)
+	log.Ctx(ctx).Debug().Msgf("runing: %v", query)
+	p := placeRow{id: id}
+	err := r.db.QueryRowContext(ctx, query, sql.Named("id", id)).Scan(&p.name, &p.aliases, &p.latitude, &p.longitude)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entity.Place{}, fmt.Errorf("id %s was %w", id, entity.ErrNotFound)
+		}
+		return entity.Place{}, fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+	}
+	return p.toPlace(), nil
+}
+
+func (r placeRepository) List(ctx context.Context) ([]entity.Place, error) {
+	query := fmt.Sprintf("SELECT id, name, aliases, latitude, longitude FROM %s ORDER BY name, id;", PLACE_TABLE_NAME)
+	log.Ctx(ctx).Debug().Msgf("runing: %v", query)
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+	}
+	defer rows.Close()
+	places := make([]entity.Place, 0)
+	for rows.Next() {
+		var p placeRow
+		if err := rows.Scan(&p.id, &p.name, &p.aliases, &p.latitude, &p.longitude); err != nil {
+			return nil, fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+		}
+		places = append(places, p.toPlace())
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+	}
+	return places, nil
+}
+
+// Resolve links the expenses without a place to the place their Where names, creating
+// the missing places when create is set
+func (r placeRepository) Resolve(ctx context.Context, create bool) (int64, error) {
+	var createPlace func(string) error
+	if create {
+		createPlace = func(name string) error {
+			_, err := r.Create(ctx, entity.Place{Name: name})
+			return err
+		}
+	}
+	return placeResolver.resolve(ctx, r.db, createPlace)
+}
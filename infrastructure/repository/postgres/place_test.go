@@ -0,0 +1,146 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/axpira/backend/entity"
+	"github.com/jackc/pgconn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlaceCreate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	repo := NewPlaceRepository(db)
+	ctx := context.Background()
+
+	_, err = repo.Create(ctx, entity.Place{Name: "Starbucks", Aliases: []string{"STARBUCKS "}, Location: &entity.GeoPoint{Latitude: 91}})
+	var fields []string
+	for _, f := range entity.UnwrapFieldErrors(err) {
+		fields = append(fields, f.Field())
+	}
+	assert.ElementsMatch(t, []string{"aliases[0]", "location.latitude"}, fields, "must validate the aliases and the location")
+
+	mock.ExpectExec("INSERT INTO tb_place \\(id,name,aliases,latitude,longitude,createdAt,updatedAt\\) VALUES \\(\\$1, \\$2, \\$3, \\$4, \\$5, \\$6, \\$7\\);").
+		WithArgs(
+			anyULID{},
+			sql.Named("name", "Starbucks"),
+			sql.Named("aliases", "{STARBUCKS #12}"),
+			sql.Named("latitude", sql.NullFloat64{Float64: -23.56, Valid: true}),
+			sql.Named("longitude", sql.NullFloat64{Float64: -46.65, Valid: true}),
+			timeMatch{time.Now().UTC()},
+			timeMatch{time.Now().UTC()},
+		).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	id, err := repo.Create(ctx, entity.Place{Name: " Starbucks", Aliases: []string{"STARBUCKS #12 "}, Location: &entity.GeoPoint{Latitude: -23.56, Longitude: -46.65}})
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.NoError(t, err)
+	assert.NotEmpty(t, id)
+}
+
+func TestPlaceGet(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	repo := NewPlaceRepository(db)
+	ctx := context.Background()
+
+	const query = "SELECT name, aliases, latitude, longitude FROM tb_place WHERE id = \\$1;"
+	mock.ExpectQuery(query).
+		WithArgs(sql.Named("id", "starbucks")).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "aliases", "latitude", "longitude"}).AddRow("Starbucks", "{\"STARBUCKS #12\"}", -23.56, -46.65))
+	got, err := repo.Get(ctx, "starbucks")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.Place{
+		Id:       "starbucks",
+		Name:     "Starbucks",
+		Aliases:  []string{"STARBUCKS #12"},
+		Location: &entity.GeoPoint{Latitude: -23.56, Longitude: -46.65},
+	}, got)
+
+	mock.ExpectQuery(query).
+		WithArgs(sql.Named("id", "market")).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "aliases", "latitude", "longitude"}).AddRow("Market", "{}", nil, nil))
+	got, err = repo.Get(ctx, "market")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.Place{Id: "market", Name: "Market"}, got, "must read a place without aliases nor location")
+
+	mock.ExpectQuery(query).
+		WithArgs(sql.Named("id", "unknown")).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "aliases", "latitude", "longitude"}))
+	_, err = repo.Get(ctx, "unknown")
+	assert.ErrorIs(t, err, entity.ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPlaceDelete(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	repo := NewPlaceRepository(db)
+
+	mock.ExpectExec("DELETE FROM tb_place WHERE id = \\$1;").
+		WithArgs(sql.Named("id", "starbucks")).
+		WillReturnError(&pgconn.PgError{Code: pgForeignKeyViolation, ConstraintName: "fk_expense_place"})
+	err = repo.Delete(context.Background(), "starbucks")
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.ErrorIs(t, err, entity.ErrConflict, "must not delete a place with expenses")
+}
+
+func TestPlaceResolve(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	repo := NewPlaceRepository(db)
+	ctx := context.Background()
+
+	const (
+		normalized = "lower(trim(regexp_replace(e.place, '\\s+', ' ', 'g')))"
+		match      = normalized + " IN (SELECT lower(trim(regexp_replace(n, '\\s+', ' ', 'g'))) FROM unnest(array_append(r.aliases, r.name)) AS n)"
+	)
+	resolveQuery := regexp.QuoteMeta("UPDATE tb_expense e SET place_id = r.id, updatedAt = $1 FROM tb_place r WHERE e.place_id IS NULL AND " + match + ";")
+
+	t.Run("must only link the matching places", func(t *testing.T) {
+		mock.ExpectExec(resolveQuery).
+			WithArgs(timeMatch{time.Now().UTC()}).
+			WillReturnResult(sqlmock.NewResult(0, 3))
+		resolved, err := repo.Resolve(ctx, false)
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), resolved)
+	})
+
+	t.Run("must create the missing places before linking", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(
+			"SELECT DISTINCT ON (" + normalized + ") trim(e.place) FROM tb_expense e WHERE e.place_id IS NULL AND " + normalized + " <> '' " +
+				"AND NOT EXISTS (SELECT 1 FROM tb_place r WHERE " + match + ") ORDER BY " + normalized + ", trim(e.place);",
+		)).
+			WillReturnRows(sqlmock.NewRows([]string{"place"}).AddRow("Starbucks").AddRow("bakery"))
+		for _, name := range []string{"Starbucks", "bakery"} {
+			mock.ExpectExec("INSERT INTO tb_place ").
+				WithArgs(anyULID{}, sql.Named("name", name), sql.Named("aliases", "{}"), sql.Named("latitude", sql.NullFloat64{}), sql.Named("longitude", sql.NullFloat64{}), timeMatch{time.Now().UTC()}, timeMatch{time.Now().UTC()}).
+				WillReturnResult(sqlmock.NewResult(1, 1))
+		}
+		mock.ExpectExec(resolveQuery).
+			WithArgs(timeMatch{time.Now().UTC()}).
+			WillReturnResult(sqlmock.NewResult(0, 5))
+		resolved, err := repo.Resolve(ctx, true)
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.NoError(t, err)
+		assert.Equal(t, int64(5), resolved)
+	})
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/axpira/backend/entity"
 	"github.com/axpira/backend/entity/config"
 
+	"github.com/jackc/pgtype"
 	_ "github.com/jackc/pgx/v4/stdlib"
 
 	// _ "github.com/lib/pq"
@@ -23,24 +24,52 @@ import (
 const TABLE_NAME = "tb_expense"
 
 var (
-	ErrUnknown           = errors.New("uknown error")
+	ErrUnknown           = fmt.Errorf("%wunknown error", entity.ErrTechnical)
 	expenseRowColumnsArr = []string{
 		"amount",
+		"currency",
 		"timestamp",
 		"place",
 		"who",
 		"what",
+		"tags",
+		"category_id",
+		"payment_method_id",
+		"to_payment_method_id",
+		"kind",
+		"recurring_id",
+		"occurrence",
+		"splits",
+		"metadata",
+		"place_id",
+		"person_id",
+		"external_id",
 	}
 	expenseRowColumns = strings.Join(expenseRowColumnsArr, ",")
 )
 
 type ExpenseRow struct {
-	Id     string
-	Amount sql.NullInt64
-	When   sql.NullTime
-	Where  sql.NullString
-	Who    sql.NullString
-	What   sql.NullString
+	Id                string
+	Amount            sql.NullInt64
+	Currency          sql.NullString
+	When              sql.NullTime
+	Where             sql.NullString
+	Who               sql.NullString
+	What              sql.NullString
+	Tags              pgtype.TextArray
+	CategoryId        sql.NullString
+	PaymentMethodId   sql.NullString
+	ToPaymentMethodId sql.NullString
+	Kind              sql.NullString
+	RecurringId       sql.NullString
+	Occurrence        sql.NullInt64
+	Splits            sql.NullString
+	Metadata          sql.NullString
+	PlaceId           sql.NullString
+	PersonId          sql.NullString
+	ExternalId        sql.NullString
+	// Details is the JSON array of line items, they are stored on DETAIL_TABLE_NAME
+	Details sql.NullString
 }
 
 func NewExpenseRowFromExpense(e entity.Expense) ExpenseRow {
@@ -50,6 +79,9 @@ func NewExpenseRowFromExpense(e entity.Expense) ExpenseRow {
 	if e.Amount > 0 {
 		row.Amount = sql.NullInt64{Int64: e.Amount, Valid: true}
 	}
+	if e.Currency != "" {
+		row.Currency = sql.NullString{String: string(e.Currency), Valid: true}
+	}
 	if !e.When.IsZero() {
 		row.When = sql.NullTime{Time: e.When, Valid: true}
 	}
@@ -62,16 +94,67 @@ func NewExpenseRowFromExpense(e entity.Expense) ExpenseRow {
 	if e.What != "" {
 		row.What = sql.NullString{String: e.What, Valid: true}
 	}
+	if e.Tags != nil {
+		row.Tags.Set(e.Tags.Value())
+	}
+	if e.CategoryId != "" {
+		row.CategoryId = sql.NullString{String: e.CategoryId, Valid: true}
+	}
+	if e.PaymentMethodId != "" {
+		row.PaymentMethodId = sql.NullString{String: e.PaymentMethodId, Valid: true}
+	}
+	if e.ToPaymentMethodId != "" {
+		row.ToPaymentMethodId = sql.NullString{String: e.ToPaymentMethodId, Valid: true}
+	}
+	if e.Kind != "" {
+		row.Kind = sql.NullString{String: string(e.Kind), Valid: true}
+	}
+	if e.RecurringId != "" {
+		row.RecurringId = sql.NullString{String: e.RecurringId, Valid: true}
+		row.Occurrence = sql.NullInt64{Int64: int64(e.Occurrence), Valid: true}
+	}
+	if e.Split != nil {
+		row.Splits = sql.NullString{String: newSplitJSON(*e.Split).String(), Valid: true}
+	}
+	if e.Metadata != nil {
+		row.Metadata = metadataArg(e.Metadata)
+	}
+	if e.PlaceId != "" {
+		row.PlaceId = sql.NullString{String: e.PlaceId, Valid: true}
+	}
+	if e.PersonId != "" {
+		row.PersonId = sql.NullString{String: e.PersonId, Valid: true}
+	}
+	if e.ExternalId != "" {
+		row.ExternalId = sql.NullString{String: e.ExternalId, Valid: true}
+	}
+	if e.Details != nil {
+		row.Details = detailsArg(e.Details)
+	}
 	return row
 }
 
 func (e *ExpenseRow) Scan() []interface{} {
 	return []interface{}{
 		&e.Amount,
+		&e.Currency,
 		&e.When,
 		&e.Where,
 		&e.Who,
 		&e.What,
+		&e.Tags,
+		&e.CategoryId,
+		&e.PaymentMethodId,
+		&e.ToPaymentMethodId,
+		&e.Kind,
+		&e.RecurringId,
+		&e.Occurrence,
+		&e.Splits,
+		&e.Metadata,
+		&e.PlaceId,
+		&e.PersonId,
+		&e.ExternalId,
+		&e.Details,
 	}
 }
 
@@ -79,18 +162,107 @@ func (e ExpenseRow) ToExpense() (entity.Expense, error) {
 	expense := entity.Expense{}
 	expense.Id = e.Id
 	expense.Amount = e.Amount.Int64
+	expense.Currency = entity.Currency(e.Currency.String)
 	expense.When = e.When.Time.UTC()
 	expense.Where = e.Where.String
 	expense.Who = e.Who.String
 	expense.What = e.What.String
+	expense.CategoryId = e.CategoryId.String
+	expense.PaymentMethodId = e.PaymentMethodId.String
+	expense.ToPaymentMethodId = e.ToPaymentMethodId.String
+	expense.PlaceId = e.PlaceId.String
+	expense.PersonId = e.PersonId.String
+	expense.Kind = entity.TransactionKind(e.Kind.String)
+	expense.RecurringId = e.RecurringId.String
+	expense.Occurrence = int(e.Occurrence.Int64)
+	expense.ExternalId = e.ExternalId.String
+	if e.Splits.Valid {
+		split, err := parseSplitJSON(e.Splits.String)
+		if err != nil {
+			return entity.Expense{}, err
+		}
+		expense.Split = &split
+	}
+	if e.Metadata.Valid {
+		metadata, err := parseMetadataJSON(e.Metadata.String)
+		if err != nil {
+			return entity.Expense{}, err
+		}
+		expense.Metadata = metadata
+	}
+	if e.Details.Valid {
+		details, err := parseDetailsJSON(e.Details.String)
+		if err != nil {
+			return entity.Expense{}, err
+		}
+		expense.Details = details
+	}
+	if len(e.Tags.Elements) > 0 {
+		tags := make([]string, len(e.Tags.Elements))
+		for i, tag := range e.Tags.Elements {
+			tags[i] = tag.String
+		}
+		t, err := entity.NewTags(tags...)
+		if err != nil {
+			return entity.Expense{}, err
+		}
+		expense.Tags = t
+	}
 	return expense, nil
 }
 
+// tagsArg never returns a NULL array, the column default is an empty one
+func (e ExpenseRow) tagsArg() pgtype.TextArray {
+	if e.Tags.Status != pgtype.Present {
+		tags := pgtype.TextArray{}
+		tags.Set([]string{})
+		return tags
+	}
+	return e.Tags
+}
+
+// AllNamedArgs returns every column, including the NULL ones, to replace a whole row.
+// The currency and the kind can't be NULL and the link to a recurring expense or to the
+// statement it was imported from is kept, so unset ones keep the stored value
+func (e ExpenseRow) AllNamedArgs() []sql.NamedArg {
+	args := []sql.NamedArg{
+		sql.Named("amount", e.Amount),
+		sql.Named("timestamp", e.When),
+		sql.Named("place", e.Where),
+		sql.Named("who", e.Who),
+		sql.Named("what", e.What),
+		sql.Named("tags", e.tagsArg()),
+		sql.Named("category_id", e.CategoryId),
+		sql.Named("payment_method_id", e.PaymentMethodId),
+		sql.Named("to_payment_method_id", e.ToPaymentMethodId),
+		sql.Named("splits", e.Splits),
+		sql.Named("metadata", e.Metadata),
+		sql.Named("place_id", e.PlaceId),
+		sql.Named("person_id", e.PersonId),
+	}
+	if e.Currency.Valid {
+		args = append(args, sql.Named("currency", e.Currency))
+	}
+	if e.Kind.Valid {
+		args = append(args, sql.Named("kind", e.Kind))
+	}
+	if e.RecurringId.Valid {
+		args = append(args, sql.Named("recurring_id", e.RecurringId), sql.Named("occurrence", e.Occurrence))
+	}
+	if e.ExternalId.Valid {
+		args = append(args, sql.Named("external_id", e.ExternalId))
+	}
+	return args
+}
+
 func (e ExpenseRow) NamedArgs() []sql.NamedArg {
 	var args []sql.NamedArg
 	if e.Amount.Valid {
 		args = append(args, sql.Named("amount", e.Amount))
 	}
+	if e.Currency.Valid {
+		args = append(args, sql.Named("currency", e.Currency))
+	}
 	if e.When.Valid {
 		args = append(args, sql.Named("timestamp", e.When))
 	}
@@ -103,6 +275,39 @@ func (e ExpenseRow) NamedArgs() []sql.NamedArg {
 	if e.What.Valid {
 		args = append(args, sql.Named("what", e.What))
 	}
+	if e.Tags.Status == pgtype.Present {
+		args = append(args, sql.Named("tags", e.Tags))
+	}
+	if e.CategoryId.Valid {
+		args = append(args, sql.Named("category_id", e.CategoryId))
+	}
+	if e.PaymentMethodId.Valid {
+		args = append(args, sql.Named("payment_method_id", e.PaymentMethodId))
+	}
+	if e.ToPaymentMethodId.Valid {
+		args = append(args, sql.Named("to_payment_method_id", e.ToPaymentMethodId))
+	}
+	if e.Kind.Valid {
+		args = append(args, sql.Named("kind", e.Kind))
+	}
+	if e.RecurringId.Valid {
+		args = append(args, sql.Named("recurring_id", e.RecurringId), sql.Named("occurrence", e.Occurrence))
+	}
+	if e.Splits.Valid {
+		args = append(args, sql.Named("splits", e.Splits))
+	}
+	if e.Metadata.Valid {
+		args = append(args, sql.Named("metadata", e.Metadata))
+	}
+	if e.PlaceId.Valid {
+		args = append(args, sql.Named("place_id", e.PlaceId))
+	}
+	if e.PersonId.Valid {
+		args = append(args, sql.Named("person_id", e.PersonId))
+	}
+	if e.ExternalId.Valid {
+		args = append(args, sql.Named("external_id", e.ExternalId))
+	}
 	return args
 }
 
@@ -111,13 +316,18 @@ type Repository interface {
 	Update(ctx context.Context, expense entity.Expense) error
 	Delete(ctx context.Context, id string) error
 	Get(ctx context.Context, id string) (entity.Expense, error)
+	Replace(ctx context.Context, expense entity.Expense) error
 	Search(context.Context, *entity.ExpenseFilter) ([]entity.Expense, error)
+	Stream(context.Context, *entity.ExpenseFilter, func(entity.Expense) error) error
+	SearchPage(context.Context, *entity.ExpenseFilter, entity.PageRequest) (entity.Page, error)
+	UpdateTags(context.Context, *entity.ExpenseFilter, entity.TagAction, *entity.Tags) (int64, error)
 }
 
 type DB interface {
 	ExecContext(_ context.Context, query string, args ...interface{}) (sql.Result, error)
 	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
 	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
 }
 
 type expenseRepository struct {
@@ -125,7 +335,7 @@ type expenseRepository struct {
 	entropy io.Reader
 }
 
-func NewExpenseRepository(ctx context.Context) (Repository, error) {
+func Open(ctx context.Context) (*sql.DB, error) {
 	db, err := sql.Open("pgx", config.Config.DatabaseUrl)
 	if err != nil {
 		return nil, err
@@ -134,10 +344,14 @@ func NewExpenseRepository(ctx context.Context) (Repository, error) {
 	if err != nil {
 		return nil, err
 	}
+	return db, nil
+}
+
+func NewExpenseRepository(db DB) Repository {
 	return expenseRepository{
 		db:      db,
 		entropy: defaultEntropy(),
-	}, nil
+	}
 }
 
 func defaultEntropy() io.Reader {
@@ -170,6 +384,10 @@ func (r expenseRepository) Create(ctx context.Context, expense entity.Expense) (
 	}
 
 	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s);", TABLE_NAME, keyStr.String()[1:], valueStr.String()[1:])
+	if len(expense.Details) > 0 {
+		args = append(args, sql.Named("details", row.Details))
+		query = fmt.Sprintf("WITH expense AS (%s RETURNING id) %s;", query[:len(query)-1], detailsInsert(fmt.Sprintf("$%d", len(args))))
+	}
 	if e := l.Debug(); e.Enabled() {
 		for i, a := range args {
 			n := a.(sql.NamedArg)
@@ -179,29 +397,52 @@ func (r expenseRepository) Create(ctx context.Context, expense entity.Expense) (
 	}
 	_, err = r.db.ExecContext(ctx, query, args...)
 	if err != nil {
-		return "", fmt.Errorf("%w: %v", ErrUnknown, err)
+		return "", translateError(err)
 	}
 	return expense.Id, nil
 }
 
+// Update replaces the details only when they are sent
 func (r expenseRepository) Update(ctx context.Context, expense entity.Expense) error {
-	if strings.TrimSpace(expense.Id) == "" {
+	row := NewExpenseRowFromExpense(expense)
+	return r.update(ctx, expense.Id, row.NamedArgs(), row.Details)
+}
+
+func (r expenseRepository) Replace(ctx context.Context, expense entity.Expense) error {
+	row := NewExpenseRowFromExpense(expense)
+	if !row.Details.Valid {
+		row.Details = detailsArg(nil)
+	}
+	return r.update(ctx, expense.Id, row.AllNamedArgs(), row.Details)
+}
+
+func (r expenseRepository) update(ctx context.Context, id string, namedArgs []sql.NamedArg, details sql.NullString) error {
+	if strings.TrimSpace(id) == "" {
 		return entity.NewFieldError(nil, "id", "empty", "can't be empty")
 	}
-	row := NewExpenseRowFromExpense(expense)
-	namedArgs := append(
-		row.NamedArgs(),
+	namedArgs = append(
+		namedArgs,
 		sql.Named("updatedAt", time.Now().UTC()),
 	)
 	var fieldsStr strings.Builder
 	args := make([]interface{}, len(namedArgs)+1)
-	args[0] = sql.Named("id", expense.Id)
+	args[0] = sql.Named("id", id)
 	for i, namedArg := range namedArgs {
 		fieldsStr.WriteString(fmt.Sprintf(", %s = $%d ", namedArg.Name, i+2))
 		args[i+1] = namedArg
 	}
 
 	query := fmt.Sprintf("UPDATE %s SET%sWHERE id = $1;", TABLE_NAME, fieldsStr.String()[1:])
+	if details.Valid {
+		args = append(args, sql.Named("details", details))
+		query = fmt.Sprintf(
+			"WITH expense AS (%s RETURNING id), deleted AS (DELETE FROM %s WHERE expense_id IN (SELECT id FROM expense)), "+
+				"details AS (%s) SELECT count(*) FROM expense;",
+			query[:len(query)-1],
+			DETAIL_TABLE_NAME,
+			detailsInsert(fmt.Sprintf("$%d", len(args))),
+		)
+	}
 	l := log.Ctx(ctx)
 	if e := l.Debug(); e.Enabled() {
 		for i, a := range args {
@@ -210,10 +451,24 @@ func (r expenseRepository) Update(ctx context.Context, expense entity.Expense) e
 		}
 		e.Msgf("runing: %v", query)
 	}
-	// l.Debug().Msgf("%+v", args)
-	_, err := r.db.ExecContext(ctx, query, args...)
-	if err != nil {
-		return fmt.Errorf("%w: %v", ErrUnknown, err)
+	var affected int64
+	if details.Valid {
+		err := r.db.QueryRowContext(ctx, query, args...).Scan(&affected)
+		if err != nil {
+			return translateError(err)
+		}
+	} else {
+		result, err := r.db.ExecContext(ctx, query, args...)
+		if err != nil {
+			return translateError(err)
+		}
+		affected, err = result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrUnknown, err)
+		}
+	}
+	if affected == 0 {
+		return fmt.Errorf("id %s was %w", id, entity.ErrNotFound)
 	}
 	return nil
 }
@@ -245,7 +500,7 @@ func (r expenseRepository) Get(ctx context.Context, id string) (entity.Expense,
 	row := ExpenseRow{
 		Id: id,
 	}
-	query := fmt.Sprintf("SELECT %s FROM %s WHERE id = $1;", expenseRowColumns, TABLE_NAME)
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE id = $1;", expenseSelectColumns, TABLE_NAME)
 	l.Debug().Msgf("executing: %s", query)
 	if e := l.Debug(); e.Enabled() {
 		e.Str("param_1_id", fmt.Sprintf("%v", sql.Named("id", id))).Msgf("runing: %v", query)
@@ -264,6 +519,115 @@ func (r expenseRepository) Get(ctx context.Context, id string) (entity.Expense,
 	return row.ToExpense()
 }
 
-func (r expenseRepository) Search(context.Context, *entity.ExpenseFilter) ([]entity.Expense, error) {
-	return nil, nil
+func (r expenseRepository) Search(ctx context.Context, filter *entity.ExpenseFilter) ([]entity.Expense, error) {
+	expenses := make([]entity.Expense, 0)
+	err := r.Stream(ctx, filter, func(expense entity.Expense) error {
+		expenses = append(expenses, expense)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return expenses, nil
+}
+
+// Stream calls fn with every expense matching filter as the rows are read, without holding them all.
+// It stops on the first error of fn and returns it
+func (r expenseRepository) Stream(ctx context.Context, filter *entity.ExpenseFilter, fn func(entity.Expense) error) error {
+	l := log.Ctx(ctx)
+	where, err := newExpenseWhere(filter)
+	if err != nil {
+		return err
+	}
+	query := fmt.Sprintf("SELECT id,%s FROM %s%s ORDER BY timestamp, id;", expenseSelectColumns, TABLE_NAME, where.String())
+	if e := l.Debug(); e.Enabled() {
+		for i, a := range where.args {
+			n := a.(sql.NamedArg)
+			e = e.Str(fmt.Sprintf("param_%d_%s", i+1, n.Name), fmt.Sprintf("%v", n.Value))
+		}
+		e.Msgf("runing: %v", query)
+	}
+	rows, err := r.db.QueryContext(ctx, query, where.args...)
+	if err != nil {
+		return fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		row := ExpenseRow{}
+		if err := rows.Scan(append([]interface{}{&row.Id}, row.Scan()...)...); err != nil {
+			return fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+		}
+		expense, err := row.ToExpense()
+		if err != nil {
+			return err
+		}
+		if err := fn(expense); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+	}
+	return nil
+}
+
+var updateTagsSQL = map[entity.TagAction]struct {
+	set     string
+	changed string
+}{
+	entity.SET: {
+		set:     "%[1]s",
+		changed: "tags IS DISTINCT FROM %[1]s",
+	},
+	entity.ADD: {
+		set:     "tags || ARRAY(SELECT t FROM unnest(%[1]s::text[]) WITH ORDINALITY AS x(t, i) WHERE NOT t = ANY(tags) ORDER BY i)",
+		changed: "NOT tags @> %[1]s",
+	},
+	entity.DEL: {
+		set:     "ARRAY(SELECT t FROM unnest(tags) WITH ORDINALITY AS x(t, i) WHERE NOT t = ANY(%[1]s::text[]) ORDER BY i)",
+		changed: "tags && %[1]s",
+	},
+}
+
+func (r expenseRepository) UpdateTags(ctx context.Context, filter *entity.ExpenseFilter, action entity.TagAction, tags *entity.Tags) (int64, error) {
+	l := log.Ctx(ctx)
+	statement, ok := updateTagsSQL[action]
+	if !ok {
+		return 0, entity.NewFieldError(nil, "action", "invalid", fmt.Sprintf("unknown tag action %d", action))
+	}
+	if tags == nil {
+		tags = new(entity.Tags)
+	}
+	where, err := newExpenseWhere(filter)
+	if err != nil {
+		return 0, err
+	}
+	value := pgtype.TextArray{}
+	value.Set(append([]string{}, tags.Value()...))
+	tagsArg := where.arg("tags", value)
+	where.add(fmt.Sprintf(statement.changed, tagsArg))
+	query := fmt.Sprintf(
+		"UPDATE %s SET tags = %s, updatedAt = %s%s;",
+		TABLE_NAME,
+		fmt.Sprintf(statement.set, tagsArg),
+		where.arg("updatedAt", time.Now().UTC()),
+		where.String(),
+	)
+	if e := l.Debug(); e.Enabled() {
+		for i, a := range where.args {
+			n := a.(sql.NamedArg)
+			e = e.Str(fmt.Sprintf("param_%d_%s", i+1, n.Name), fmt.Sprintf("%v", n.Value))
+		}
+		e.Msgf("runing: %v", query)
+	}
+	result, err := r.db.ExecContext(ctx, query, where.args...)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrUnknown, err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrUnknown, err)
+	}
+	return affected, nil
 }
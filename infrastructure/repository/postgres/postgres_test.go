@@ -6,10 +6,13 @@ import (
 	"database/sql"
 	"database/sql/driver"
 	"errors"
+	"fmt"
 	"math"
 	"math/big"
 	"math/rand"
 	"os"
+	"regexp"
+	"strings"
 	"testing"
 	"time"
 
@@ -17,6 +20,7 @@ import (
 	"github.com/axpira/backend/entity"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/jackc/pgconn"
 	"github.com/oklog/ulid/v2"
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
@@ -62,6 +66,53 @@ func newRandomExpense() entity.Expense {
 	}
 }
 
+// selectColumns is the quoted column list read by Get and Search
+var selectColumns = regexp.QuoteMeta(expenseSelectColumns)
+
+// selectColumnsArr names the columns of selectColumns, the details come last
+var selectColumnsArr = append(append([]string{}, expenseRowColumnsArr...), "details")
+
+// expenseValues returns the values of e in the order of selectColumnsArr
+func expenseValues(e entity.Expense) []driver.Value {
+	tags := "{}"
+	if e.Tags != nil {
+		tags = "{" + strings.Join(e.Tags.Value(), ",") + "}"
+	}
+	var splits driver.Value
+	if e.Split != nil {
+		splits = newSplitJSON(*e.Split).String()
+	}
+	var metadata driver.Value
+	if e.Metadata != nil {
+		metadata = metadataArg(e.Metadata).String
+	}
+	var details driver.Value
+	if e.Details != nil {
+		details = detailsArg(e.Details).String
+	}
+	return []driver.Value{
+		e.Amount,
+		sql.NullString{String: string(e.Currency), Valid: e.Currency != ""},
+		e.When,
+		e.Where,
+		e.Who,
+		e.What,
+		tags,
+		sql.NullString{String: e.CategoryId, Valid: e.CategoryId != ""},
+		sql.NullString{String: e.PaymentMethodId, Valid: e.PaymentMethodId != ""},
+		sql.NullString{String: e.ToPaymentMethodId, Valid: e.ToPaymentMethodId != ""},
+		sql.NullString{String: string(e.Kind), Valid: e.Kind != ""},
+		sql.NullString{String: e.RecurringId, Valid: e.RecurringId != ""},
+		sql.NullInt64{Int64: int64(e.Occurrence), Valid: e.RecurringId != ""},
+		splits,
+		metadata,
+		sql.NullString{String: e.PlaceId, Valid: e.PlaceId != ""},
+		sql.NullString{String: e.PersonId, Valid: e.PersonId != ""},
+		sql.NullString{String: e.ExternalId, Valid: e.ExternalId != ""},
+		details,
+	}
+}
+
 func TestCreate(t *testing.T) {
 	l := zerolog.New(zerolog.ConsoleWriter{Out: os.Stderr}).With().
 		Timestamp().
@@ -97,7 +148,7 @@ func TestCreate(t *testing.T) {
 			},
 			wantQuery: "INSERT INTO tb_expense \\(amount,id,createdAt,updatedAt\\) VALUES \\( \\$1, \\$2, \\$3, \\$4\\);",
 			args: []driver.Value{
-				sql.Named("amount", sql.NullInt64{120, true}),
+				sql.Named("amount", sql.NullInt64{Int64: 120, Valid: true}),
 				anyULID{},
 				timeMatch{time.Now().UTC()},
 				timeMatch{time.Now().UTC()},
@@ -109,16 +160,53 @@ func TestCreate(t *testing.T) {
 			wantErr:   ErrUnknown,
 			mockErr:   errors.New(String(10)),
 		},
+		"must return conflict on an occurrence already created": {
+			expense: entity.Expense{
+				Amount:      120,
+				RecurringId: "rent",
+				Occurrence:  2,
+			},
+			wantQuery: "INSERT INTO tb_expense \\(amount,recurring_id,occurrence,id,createdAt,updatedAt\\) VALUES \\( \\$1, \\$2, \\$3, \\$4, \\$5, \\$6\\);",
+			args: []driver.Value{
+				sql.Named("amount", sql.NullInt64{Int64: 120, Valid: true}),
+				sql.Named("recurring_id", sql.NullString{String: "rent", Valid: true}),
+				sql.Named("occurrence", sql.NullInt64{Int64: 2, Valid: true}),
+				anyULID{},
+				timeMatch{time.Now().UTC()},
+				timeMatch{time.Now().UTC()},
+			},
+			wantErr: entity.ErrConflict,
+			mockErr: &pgconn.PgError{Code: pgUniqueViolation, ConstraintName: "uq_expense_recurring_occurrence"},
+		},
+		"must insert the details with the expense": {
+			expense: entity.Expense{
+				Amount: 750,
+				Details: []entity.Detail{
+					{Description: "bread", Quantity: 1500, UnitPrice: 300, CategoryId: "bakery"},
+					{Description: "milk", Quantity: 1000, UnitPrice: 300},
+				},
+			},
+			wantQuery: "WITH expense AS \\(INSERT INTO tb_expense \\(amount,id,createdAt,updatedAt\\) VALUES \\( \\$1, \\$2, \\$3, \\$4\\) RETURNING id\\) " +
+				"INSERT INTO tb_expense_detail \\(expense_id,position,description,quantity,unit_price,category_id,tags\\) .* FROM expense e, jsonb_to_recordset\\(\\$5::jsonb\\) .*;",
+			args: []driver.Value{
+				sql.Named("amount", sql.NullInt64{Int64: 750, Valid: true}),
+				anyULID{},
+				timeMatch{time.Now().UTC()},
+				timeMatch{time.Now().UTC()},
+				sql.Named("details", sql.NullString{String: `[{"position":0,"description":"bread","quantity":1500,"unit_price":300,"category_id":"bakery","tags":[]},` +
+					`{"position":1,"description":"milk","quantity":1000,"unit_price":300,"category_id":null,"tags":[]}]`, Valid: true}),
+			},
+		},
 	}
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
 			args := tc.args
 			if args == nil {
-				args = append(args, sql.Named("amount", sql.NullInt64{tc.expense.Amount, true}))
-				args = append(args, sql.Named("timestamp", sql.NullTime{tc.expense.When, true}))
-				args = append(args, sql.Named("place", sql.NullString{tc.expense.Where, true}))
-				args = append(args, sql.Named("who", sql.NullString{tc.expense.Who, true}))
-				args = append(args, sql.Named("what", sql.NullString{tc.expense.What, true}))
+				args = append(args, sql.Named("amount", sql.NullInt64{Int64: tc.expense.Amount, Valid: true}))
+				args = append(args, sql.Named("timestamp", sql.NullTime{Time: tc.expense.When, Valid: true}))
+				args = append(args, sql.Named("place", sql.NullString{String: tc.expense.Where, Valid: true}))
+				args = append(args, sql.Named("who", sql.NullString{String: tc.expense.Who, Valid: true}))
+				args = append(args, sql.Named("what", sql.NullString{String: tc.expense.What, Valid: true}))
 				args = append(args, anyULID{})
 				args = append(args, timeMatch{time.Now().UTC()})
 				args = append(args, timeMatch{time.Now().UTC()})
@@ -138,10 +226,10 @@ func TestCreate(t *testing.T) {
 				t.Errorf("unmet expectation error: %s", err)
 			}
 
-			if gotErr != nil {
-				if !errors.As(gotErr, &tc.wantErr) {
-					t.Errorf("want error %q and got %q", tc.wantErr, gotErr)
-				}
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, gotErr, tc.wantErr)
+			} else {
+				assert.NoError(t, gotErr)
 			}
 		})
 	}
@@ -169,11 +257,12 @@ func TestUpdate(t *testing.T) {
 		t.Errorf("must return error on empty id")
 	}
 	tests := map[string]struct {
-		expense   entity.Expense
-		wantQuery string
-		args      []driver.Value
-		wantErr   error
-		mockErr   error
+		expense    entity.Expense
+		wantQuery  string
+		args       []driver.Value
+		wantErr    error
+		mockErr    error
+		mockResult driver.Result
 	}{
 		"must execute the update query with all named args": {
 			expense:   newRandomExpense(),
@@ -187,7 +276,7 @@ func TestUpdate(t *testing.T) {
 			wantQuery: "UPDATE tb_expense SET amount = \\$2 , updatedAt = \\$3 WHERE id = \\$1;",
 			args: []driver.Value{
 				sql.Named("id", "123456"),
-				sql.Named("amount", sql.NullInt64{120, true}),
+				sql.Named("amount", sql.NullInt64{Int64: 120, Valid: true}),
 				timeMatch{time.Now().UTC()},
 			},
 		},
@@ -197,37 +286,151 @@ func TestUpdate(t *testing.T) {
 			wantErr:   ErrUnknown,
 			mockErr:   errors.New(String(10)),
 		},
+		"must return not found when no row was updated": {
+			expense:    newRandomExpense(),
+			wantQuery:  "UPDATE tb_expense SET amount = \\$2 , timestamp = \\$3 , place = \\$4 , who = \\$5 , what = \\$6 , updatedAt = \\$7 WHERE id = \\$1;",
+			wantErr:    entity.ErrNotFound,
+			mockResult: sqlmock.NewResult(0, 0),
+		},
 	}
 
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
+			result := tc.mockResult
+			if result == nil {
+				result = sqlmock.NewResult(1, 1)
+			}
 			args := tc.args
 			if args == nil {
 				args = append(args, sql.Named("id", tc.expense.Id))
-				args = append(args, sql.Named("amount", sql.NullInt64{tc.expense.Amount, true}))
-				args = append(args, sql.Named("timestamp", sql.NullTime{tc.expense.When, true}))
-				args = append(args, sql.Named("place", sql.NullString{tc.expense.Where, true}))
-				args = append(args, sql.Named("who", sql.NullString{tc.expense.Who, true}))
-				args = append(args, sql.Named("what", sql.NullString{tc.expense.What, true}))
+				args = append(args, sql.Named("amount", sql.NullInt64{Int64: tc.expense.Amount, Valid: true}))
+				args = append(args, sql.Named("timestamp", sql.NullTime{Time: tc.expense.When, Valid: true}))
+				args = append(args, sql.Named("place", sql.NullString{String: tc.expense.Where, Valid: true}))
+				args = append(args, sql.Named("who", sql.NullString{String: tc.expense.Who, Valid: true}))
+				args = append(args, sql.Named("what", sql.NullString{String: tc.expense.What, Valid: true}))
 				args = append(args, timeMatch{time.Now().UTC()})
 			}
 			mock.
 				ExpectExec(tc.wantQuery).
 				WithArgs(args...).
 				WillReturnError(tc.mockErr).
-				WillReturnResult(sqlmock.NewResult(1, 1))
-			repo.Update(ctx, tc.expense)
-
+				WillReturnResult(result)
 			gotErr := repo.Update(ctx, tc.expense)
 			// db.AssertExpectations(t)
 			if err := mock.ExpectationsWereMet(); err != nil {
 				t.Errorf("unmet expectation error: %s", err)
 			}
 
-			if gotErr != nil {
-				if !errors.As(gotErr, &tc.wantErr) {
-					t.Errorf("want error %q and got %q", tc.wantErr, gotErr)
-				}
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, gotErr, tc.wantErr)
+			} else {
+				assert.NoError(t, gotErr)
+			}
+		})
+	}
+}
+
+func TestReplace(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error %q was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	repo := expenseRepository{
+		db:      db,
+		entropy: defaultEntropy(),
+	}
+
+	err = repo.Replace(context.Background(), entity.Expense{})
+	if err == nil {
+		t.Errorf("must return error on empty id")
+	}
+
+	const details = " RETURNING id\\), deleted AS \\(DELETE FROM tb_expense_detail WHERE expense_id IN \\(SELECT id FROM expense\\)\\), details AS \\(INSERT INTO tb_expense_detail .* FROM expense e, jsonb_to_recordset\\(\\$%d::jsonb\\) .*\\) SELECT count\\(\\*\\) FROM expense;"
+	const allColumns = "WITH expense AS \\(UPDATE tb_expense SET amount = \\$2 , timestamp = \\$3 , place = \\$4 , who = \\$5 , what = \\$6 , tags = \\$7 , category_id = \\$8 , payment_method_id = \\$9 , to_payment_method_id = \\$10 , splits = \\$11 , metadata = \\$12 , place_id = \\$13 , person_id = \\$14 , "
+	tests := map[string]struct {
+		wantQuery string
+		expense   entity.Expense
+		args      []driver.Value
+		wantErr   error
+		affected  int64
+	}{
+		"must set every column, even the empty ones": {
+			wantQuery: allColumns + "currency = \\$15 , kind = \\$16 , updatedAt = \\$17 WHERE id = \\$1" + fmt.Sprintf(details, 18),
+			expense: entity.Expense{
+				Id:       "123456",
+				Amount:   120,
+				Currency: "JPY",
+				Kind:     entity.INCOME,
+				Who:      "ana",
+				Tags:     entity.MustNewTags("trip", "work"),
+				Split:    &entity.Split{Kind: entity.EQUAL, Shares: []entity.Share{{Who: "ana", Amount: 60}, {Who: "bob", Amount: 60}}},
+				Details:  []entity.Detail{{Description: "sushi", Quantity: 2000, UnitPrice: 60, Tags: entity.MustNewTags("food")}},
+				Metadata: map[string]string{"invoice": "42"},
+				PlaceId:  "sushi-bar",
+			},
+			args: []driver.Value{
+				sql.Named("id", "123456"),
+				sql.Named("amount", sql.NullInt64{Int64: 120, Valid: true}),
+				sql.Named("timestamp", sql.NullTime{}),
+				sql.Named("place", sql.NullString{}),
+				sql.Named("who", sql.NullString{String: "ana", Valid: true}),
+				sql.Named("what", sql.NullString{}),
+				sql.Named("tags", "{trip,work}"),
+				sql.Named("category_id", sql.NullString{}),
+				sql.Named("payment_method_id", sql.NullString{}),
+				sql.Named("to_payment_method_id", sql.NullString{}),
+				sql.Named("splits", sql.NullString{String: `{"kind":"equal","shares":[{"who":"ana","amount":60},{"who":"bob","amount":60}]}`, Valid: true}),
+				sql.Named("metadata", sql.NullString{String: `{"invoice":"42"}`, Valid: true}),
+				sql.Named("place_id", sql.NullString{String: "sushi-bar", Valid: true}),
+				sql.Named("person_id", sql.NullString{}),
+				sql.Named("currency", sql.NullString{String: "JPY", Valid: true}),
+				sql.Named("kind", sql.NullString{String: "income", Valid: true}),
+				timeMatch{time.Now().UTC()},
+				sql.Named("details", sql.NullString{String: `[{"position":0,"description":"sushi","quantity":2000,"unit_price":60,"category_id":null,"tags":["food"]}]`, Valid: true}),
+			},
+			affected: 1,
+		},
+		"must remove the details and return not found when no row was replaced": {
+			wantQuery: allColumns + "updatedAt = \\$15 WHERE id = \\$1" + fmt.Sprintf(details, 16),
+			expense: entity.Expense{
+				Id: "123456",
+			},
+			args: []driver.Value{
+				sql.Named("id", "123456"),
+				sql.Named("amount", sql.NullInt64{}),
+				sql.Named("timestamp", sql.NullTime{}),
+				sql.Named("place", sql.NullString{}),
+				sql.Named("who", sql.NullString{}),
+				sql.Named("what", sql.NullString{}),
+				sql.Named("tags", "{}"),
+				sql.Named("category_id", sql.NullString{}),
+				sql.Named("payment_method_id", sql.NullString{}),
+				sql.Named("to_payment_method_id", sql.NullString{}),
+				sql.Named("splits", sql.NullString{}),
+				sql.Named("metadata", sql.NullString{}),
+				sql.Named("place_id", sql.NullString{}),
+				sql.Named("person_id", sql.NullString{}),
+				timeMatch{time.Now().UTC()},
+				sql.Named("details", sql.NullString{String: "[]", Valid: true}),
+			},
+			wantErr: entity.ErrNotFound,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			mock.
+				ExpectQuery(tc.wantQuery).
+				WithArgs(tc.args...).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(tc.affected))
+			gotErr := repo.Replace(context.Background(), tc.expense)
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unmet expectation error: %s", err)
+			}
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, gotErr, tc.wantErr)
+			} else {
+				assert.NoError(t, gotErr)
 			}
 		})
 	}
@@ -330,19 +533,57 @@ func TestGet(t *testing.T) {
 		wantExpense entity.Expense
 	}{
 		"must execute the query": {
-			wantQuery:   "SELECT amount,timestamp,place,who,what FROM tb_expense WHERE id = \\$1;",
-			columns:     []string{"amount", "when", "where", "who", "what"},
-			id:          String(36),
-			wantExpense: newRandomExpense(),
+			wantQuery: "SELECT " + selectColumns + " FROM tb_expense WHERE id = \\$1;",
+			columns:   selectColumnsArr,
+			id:        String(36),
+			wantExpense: func() entity.Expense {
+				e := newRandomExpense()
+				e.Currency = "JPY"
+				e.Kind = entity.TRANSFER
+				e.PaymentMethodId = "checking"
+				e.ToPaymentMethodId = "savings"
+				e.Metadata = map[string]string{"invoice": "42", "project": "home"}
+				e.PlaceId = "starbucks"
+				e.PersonId = "ana"
+				return e
+			}(),
+		},
+		"must read the split": {
+			wantQuery: "SELECT " + selectColumns + " FROM tb_expense WHERE id = \\$1;",
+			columns:   selectColumnsArr,
+			id:        String(36),
+			wantExpense: func() entity.Expense {
+				e := newRandomExpense()
+				e.Currency = "BRL"
+				e.Kind = entity.EXPENSE
+				e.Split = &entity.Split{Kind: entity.PERCENT, Shares: []entity.Share{
+					{Who: "ana", Percent: 7000, Amount: 864},
+					{Who: "bob", Percent: 3000, Amount: 370},
+				}}
+				return e
+			}(),
+		},
+		"must read the details": {
+			wantQuery: "SELECT " + selectColumns + " FROM tb_expense WHERE id = \\$1;",
+			columns:   selectColumnsArr,
+			id:        String(36),
+			wantExpense: func() entity.Expense {
+				e := newRandomExpense()
+				e.Details = []entity.Detail{
+					{Description: "bread", Quantity: 1500, UnitPrice: 300, CategoryId: "bakery", Tags: entity.MustNewTags("breakfast")},
+					{Description: "milk", Quantity: 1000, UnitPrice: 784},
+				}
+				return e
+			}(),
 		},
 		"must return error on database error ": {
-			columns: []string{"amount", "when", "where", "who", "what"},
+			columns: selectColumnsArr,
 			id:      String(36),
 			wantErr: entity.ErrUnknown,
 			mockErr: errors.New(String(10)),
 		},
 		"must return error on not found": {
-			columns: []string{"amount", "when", "where", "who", "what"},
+			columns: selectColumnsArr,
 			id:      String(36),
 			wantErr: entity.ErrNotFound,
 			mockErr: sql.ErrNoRows,
@@ -354,13 +595,7 @@ func TestGet(t *testing.T) {
 				ExpectQuery(tc.wantQuery).
 				WithArgs(sql.Named("id", tc.id)).
 				WillReturnRows(
-					sqlmock.NewRows(tc.columns).AddRow(
-						tc.wantExpense.Amount,
-						tc.wantExpense.When,
-						tc.wantExpense.Where,
-						tc.wantExpense.Who,
-						tc.wantExpense.What,
-					),
+					sqlmock.NewRows(tc.columns).AddRow(expenseValues(tc.wantExpense)...),
 				).
 				WillReturnError(tc.mockErr)
 
@@ -388,6 +623,317 @@ func TestGet(t *testing.T) {
 	}
 }
 
+func TestSearch(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	l := zerolog.New(zerolog.ConsoleWriter{Out: os.Stderr}).With().
+		Timestamp().
+		Caller().
+		Str("service", "backend-test").
+		Logger()
+	ctx := l.WithContext(context.Background())
+	repo := expenseRepository{
+		db:      db,
+		entropy: defaultEntropy(),
+	}
+	when := time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+	expense := newRandomExpense()
+	taggedExpense := newRandomExpense()
+	taggedExpense.Tags = entity.MustNewTags("trip", "work")
+
+	tests := map[string]struct {
+		filter       *entity.ExpenseFilter
+		wantQuery    string
+		args         []driver.Value
+		wantErr      error
+		mockErr      error
+		wantExpenses []entity.Expense
+		noQuery      bool
+	}{
+		"must search without filter": {
+			filter:       nil,
+			wantQuery:    "SELECT id," + selectColumns + " FROM tb_expense ORDER BY timestamp, id;",
+			wantExpenses: []entity.Expense{expense},
+		},
+		"must search with all filters": {
+			filter: &entity.ExpenseFilter{
+				Amount:    []entity.IntFilter{{Type: entity.GE, Value: 1000}, {Type: entity.LT, Value: 2000}},
+				When:      []entity.TimeFilter{{Type: entity.GT, Value: when}},
+				What:      []entity.StrFilter{{Type: entity.EQUALS, Value: "coffee"}, {Type: entity.REGEX, Value: "^co"}},
+				CreatedAt: []entity.TimeFilter{{Type: entity.LE, Value: when}},
+				UpdatedAt: []entity.TimeFilter{{Type: entity.EQ, Value: when}},
+			},
+			wantQuery: "SELECT id," + selectColumns + " FROM tb_expense " +
+				"WHERE amount >= \\$1 AND amount < \\$2 AND timestamp > \\$3 AND what = \\$4 AND what ~ \\$5 " +
+				"AND createdAt <= \\$6 AND updatedAt = \\$7 ORDER BY timestamp, id;",
+			args: []driver.Value{
+				sql.Named("amount", 1000),
+				sql.Named("amount", 2000),
+				sql.Named("timestamp", when),
+				sql.Named("what", "coffee"),
+				sql.Named("what", "^co"),
+				sql.Named("createdAt", when),
+				sql.Named("updatedAt", when),
+			},
+			wantExpenses: []entity.Expense{expense},
+		},
+		"must search by currency": {
+			filter: &entity.ExpenseFilter{
+				Amount:   []entity.IntFilter{{Type: entity.GE, Value: 500}},
+				Currency: []entity.StrFilter{{Type: entity.EQUALS, Value: "JPY"}},
+			},
+			wantQuery: "SELECT id," + selectColumns + " FROM tb_expense " +
+				"WHERE amount >= \\$1 AND currency = \\$2 ORDER BY timestamp, id;",
+			args: []driver.Value{
+				sql.Named("amount", 500),
+				sql.Named("currency", "JPY"),
+			},
+			wantExpenses: []entity.Expense{expense},
+		},
+		"must search by kind": {
+			filter: &entity.ExpenseFilter{
+				Kind: []entity.TransactionKind{entity.EXPENSE, entity.INCOME},
+			},
+			wantQuery: "SELECT id," + selectColumns + " FROM tb_expense " +
+				"WHERE kind IN \\(\\$1, \\$2\\) ORDER BY timestamp, id;",
+			args: []driver.Value{
+				sql.Named("kind", "expense"),
+				sql.Named("kind", "income"),
+			},
+			wantExpenses: []entity.Expense{expense},
+		},
+		"must search the split ones": {
+			filter: &entity.ExpenseFilter{
+				Kind:  []entity.TransactionKind{entity.EXPENSE},
+				Split: true,
+			},
+			wantQuery: "SELECT id," + selectColumns + " FROM tb_expense " +
+				"WHERE kind IN \\(\\$1\\) AND splits IS NOT NULL ORDER BY timestamp, id;",
+			args: []driver.Value{
+				sql.Named("kind", "expense"),
+			},
+			wantExpenses: []entity.Expense{expense},
+		},
+		"must search by detail": {
+			filter: &entity.ExpenseFilter{
+				Detail: []entity.StrFilter{{Type: entity.REGEX, Value: "^bread"}},
+			},
+			wantQuery: "SELECT id," + selectColumns + " FROM tb_expense " +
+				"WHERE EXISTS \\(SELECT 1 FROM tb_expense_detail d WHERE d.expense_id = tb_expense.id AND d.description ~ \\$1\\) ORDER BY timestamp, id;",
+			args: []driver.Value{
+				sql.Named("detail", "^bread"),
+			},
+			wantExpenses: []entity.Expense{expense},
+		},
+		"must search by place and person": {
+			filter: entity.MustNewExpenseFilter(
+				entity.FilterWhere(entity.NewURN(entity.PLACE, "starbucks")),
+				entity.FilterWho(entity.NewURN(entity.PERSON, "ana")),
+			),
+			wantQuery: "SELECT id," + selectColumns + " FROM tb_expense " +
+				"WHERE place_id = \\$1 AND person_id = \\$2 ORDER BY timestamp, id;",
+			args: []driver.Value{
+				sql.Named("place_id", "starbucks"),
+				sql.Named("person_id", "ana"),
+			},
+			wantExpenses: []entity.Expense{expense},
+		},
+		"must search by metadata": {
+			filter: entity.MustNewExpenseFilter(
+				entity.FilterMetadataKey("invoice"),
+				entity.FilterMetadata(entity.EQUALS, "project", "home"),
+				entity.FilterMetadata(entity.REGEX, "invoice", "^20"),
+			),
+			wantQuery: "SELECT id," + selectColumns + " FROM tb_expense " +
+				"WHERE metadata \\? \\$1 AND metadata @> jsonb_build_object\\(\\$2::text, \\$3::text\\) AND metadata ->> \\$4 ~ \\$5 ORDER BY timestamp, id;",
+			args: []driver.Value{
+				sql.Named("metadata", "invoice"),
+				sql.Named("metadata", "project"),
+				sql.Named("metadata", "home"),
+				sql.Named("metadata", "invoice"),
+				sql.Named("metadata", "^20"),
+			},
+			wantExpenses: []entity.Expense{expense},
+		},
+		"must search by tags": {
+			filter: &entity.ExpenseFilter{
+				Tag: []entity.StrFilter{{Type: entity.EQUALS, Value: "trip"}, {Type: entity.REGEX, Value: "^wo"}},
+			},
+			wantQuery: "SELECT id," + selectColumns + " FROM tb_expense " +
+				"WHERE \\$1 = ANY\\(tags\\) AND EXISTS \\(SELECT 1 FROM unnest\\(tags\\) AS tag WHERE tag ~ \\$2\\) ORDER BY timestamp, id;",
+			args: []driver.Value{
+				sql.Named("tags", "trip"),
+				sql.Named("tags", "^wo"),
+			},
+			wantExpenses: []entity.Expense{taggedExpense},
+		},
+		"must search by category and its descendants": {
+			filter: &entity.ExpenseFilter{
+				Category: []entity.StrFilter{{Type: entity.EQUALS, Value: "food"}, {Type: entity.REGEX, Value: "^Bak"}},
+			},
+			wantQuery: "SELECT id," + selectColumns + " FROM tb_expense WHERE " +
+				"category_id IN \\(WITH RECURSIVE tree AS \\(SELECT id FROM tb_category WHERE id = \\$1 UNION ALL SELECT c.id FROM tb_category c JOIN tree ON c.parent_id = tree.id\\) SELECT id FROM tree\\) AND " +
+				"category_id IN \\(WITH RECURSIVE tree AS \\(SELECT id FROM tb_category WHERE name ~ \\$2 UNION ALL SELECT c.id FROM tb_category c JOIN tree ON c.parent_id = tree.id\\) SELECT id FROM tree\\) " +
+				"ORDER BY timestamp, id;",
+			args: []driver.Value{
+				sql.Named("category", "food"),
+				sql.Named("category", "^Bak"),
+			},
+		},
+		"must search by payment method": {
+			filter: &entity.ExpenseFilter{
+				PaymentMethod: []entity.StrFilter{{Type: entity.EQUALS, Value: "card"}, {Type: entity.REGEX, Value: "^Visa"}},
+			},
+			wantQuery: "SELECT id," + selectColumns + " FROM tb_expense WHERE " +
+				"payment_method_id = \\$1 AND payment_method_id IN \\(SELECT id FROM tb_payment_method WHERE name ~ \\$2\\) " +
+				"ORDER BY timestamp, id;",
+			args: []driver.Value{
+				sql.Named("paymentMethod", "card"),
+				sql.Named("paymentMethod", "^Visa"),
+			},
+		},
+		"must return field error on invalid operator": {
+			filter: &entity.ExpenseFilter{
+				Amount: []entity.IntFilter{{Type: 0, Value: 1000}},
+			},
+			noQuery: true,
+			wantErr: entity.FieldError{},
+		},
+		"must return error on database error": {
+			wantQuery: "SELECT id," + selectColumns + " FROM tb_expense ORDER BY timestamp, id;",
+			mockErr:   errors.New(String(10)),
+			wantErr:   entity.ErrUnknown,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if !tc.noQuery {
+				rows := sqlmock.NewRows(append([]string{"id"}, selectColumnsArr...))
+				for _, e := range tc.wantExpenses {
+					rows.AddRow(append([]driver.Value{e.Id}, expenseValues(e)...)...)
+				}
+				mock.
+					ExpectQuery(tc.wantQuery).
+					WithArgs(tc.args...).
+					WillReturnRows(rows).
+					WillReturnError(tc.mockErr)
+			}
+
+			gotExpenses, gotErr := repo.Search(ctx, tc.filter)
+			if err = mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unmet expectation error: %s", err)
+			}
+
+			var fieldErr entity.FieldError
+			switch {
+			case tc.wantErr == nil:
+				assert.NoError(t, gotErr)
+			case errors.As(tc.wantErr, &fieldErr):
+				assert.True(t, errors.As(gotErr, &fieldErr), "must return a field error")
+			default:
+				assert.ErrorIs(t, gotErr, tc.wantErr)
+			}
+
+			if diff := cmp.Diff(tc.wantExpenses, gotExpenses, cmpopts.EquateEmpty(), cmp.AllowUnexported(entity.Tags{})); diff != "" {
+				t.Errorf("Expense mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestUpdateTags(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	repo := expenseRepository{
+		db:      db,
+		entropy: defaultEntropy(),
+	}
+	ctx := context.Background()
+
+	_, err = repo.UpdateTags(ctx, nil, entity.TagAction(42), entity.MustNewTags("trip"))
+	assert.Error(t, err, "must return error on unknown action")
+
+	filter := entity.MustNewExpenseFilter(entity.FilterWhat(entity.REGEX, "taxi"))
+	tests := map[string]struct {
+		action       entity.TagAction
+		tags         *entity.Tags
+		wantQuery    string
+		wantArgs     []driver.Value
+		mockErr      error
+		mockResult   driver.Result
+		wantErr      error
+		wantAffected int64
+	}{
+		"must add tags": {
+			action: entity.ADD,
+			tags:   entity.MustNewTags("reimbursable", "work"),
+			wantQuery: "UPDATE tb_expense SET tags = tags \\|\\| ARRAY\\(SELECT t FROM unnest\\(\\$2::text\\[\\]\\) WITH ORDINALITY AS x\\(t, i\\) WHERE NOT t = ANY\\(tags\\) ORDER BY i\\), " +
+				"updatedAt = \\$3 WHERE what ~ \\$1 AND NOT tags @> \\$2;",
+			wantArgs:     []driver.Value{sql.Named("what", "taxi"), sql.Named("tags", "{reimbursable,work}"), timeMatch{time.Now().UTC()}},
+			mockResult:   sqlmock.NewResult(0, 4),
+			wantAffected: 4,
+		},
+		"must delete tags": {
+			action: entity.DEL,
+			tags:   entity.MustNewTags("work"),
+			wantQuery: "UPDATE tb_expense SET tags = ARRAY\\(SELECT t FROM unnest\\(tags\\) WITH ORDINALITY AS x\\(t, i\\) WHERE NOT t = ANY\\(\\$2::text\\[\\]\\) ORDER BY i\\), " +
+				"updatedAt = \\$3 WHERE what ~ \\$1 AND tags && \\$2;",
+			wantArgs:     []driver.Value{sql.Named("what", "taxi"), sql.Named("tags", "{work}"), timeMatch{time.Now().UTC()}},
+			mockResult:   sqlmock.NewResult(0, 1),
+			wantAffected: 1,
+		},
+		"must set tags": {
+			action:     entity.SET,
+			tags:       nil,
+			wantQuery:  "UPDATE tb_expense SET tags = \\$2, updatedAt = \\$3 WHERE what ~ \\$1 AND tags IS DISTINCT FROM \\$2;",
+			wantArgs:   []driver.Value{sql.Named("what", "taxi"), sql.Named("tags", "{}"), timeMatch{time.Now().UTC()}},
+			mockResult: sqlmock.NewResult(0, 0),
+		},
+		"must return error on database error": {
+			action:    entity.SET,
+			tags:      entity.MustNewTags("work"),
+			wantQuery: "UPDATE tb_expense SET tags = \\$2, updatedAt = \\$3 WHERE what ~ \\$1 AND tags IS DISTINCT FROM \\$2;",
+			wantArgs:  []driver.Value{sql.Named("what", "taxi"), sql.Named("tags", "{work}"), timeMatch{time.Now().UTC()}},
+			mockErr:   errors.New(String(10)),
+			wantErr:   ErrUnknown,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			mock.
+				ExpectExec(tc.wantQuery).
+				WithArgs(tc.wantArgs...).
+				WillReturnResult(tc.mockResult).
+				WillReturnError(tc.mockErr)
+			gotAffected, gotErr := repo.UpdateTags(ctx, filter, tc.action, tc.tags)
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unmet expectation error: %s", err)
+			}
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, gotErr, tc.wantErr)
+			} else {
+				assert.NoError(t, gotErr)
+			}
+			assert.Equal(t, tc.wantAffected, gotAffected)
+		})
+	}
+
+	mock.
+		ExpectExec("UPDATE tb_expense SET tags = tags \\|\\| ARRAY\\(SELECT t FROM unnest\\(\\$2::text\\[\\]\\) WITH ORDINALITY AS x\\(t, i\\) WHERE NOT t = ANY\\(tags\\) ORDER BY i\\), "+
+			"updatedAt = \\$3 WHERE id = \\$1 AND NOT tags @> \\$2;").
+		WithArgs(sql.Named("id", "1"), sql.Named("tags", "{trip}"), timeMatch{time.Now().UTC()}).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	_, err = repo.UpdateTags(ctx, entity.MustNewExpenseFilter(entity.FilterId(entity.EQUALS, "1")), entity.ADD, entity.MustNewTags("trip"))
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet(), "must update the tags of a single expense by id")
+}
+
 const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_"
 
 func StringWithCharset(length int, charset string) string {
@@ -401,3 +947,32 @@ func StringWithCharset(length int, charset string) string {
 func String(length int) string {
 	return StringWithCharset(length, charset)
 }
+
+func TestStream(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	repo := NewExpenseRepository(db)
+	ctx := context.Background()
+	expenses := []entity.Expense{newRandomExpense(), newRandomExpense(), newRandomExpense()}
+
+	rows := sqlmock.NewRows(append([]string{"id"}, selectColumnsArr...))
+	for _, e := range expenses {
+		rows.AddRow(append([]driver.Value{e.Id}, expenseValues(e)...)...)
+	}
+	mock.ExpectQuery("SELECT id," + selectColumns + " FROM tb_expense ORDER BY timestamp, id;").WillReturnRows(rows)
+	stop := errors.New("stop")
+	var got []string
+	err = repo.Stream(ctx, nil, func(e entity.Expense) error {
+		got = append(got, e.Id)
+		if len(got) == 2 {
+			return stop
+		}
+		return nil
+	})
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.ErrorIs(t, err, stop, "must return the error of fn")
+	assert.Equal(t, []string{expenses[0].Id, expenses[1].Id}, got, "must stop on the first error of fn")
+}
@@ -0,0 +1,305 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/axpira/backend/entity"
+	"github.com/oklog/ulid/v2"
+	"github.com/rs/zerolog/log"
+)
+
+const RECURRING_TABLE_NAME = "tb_recurring_expense"
+
+var recurringColumns = "template,frequency,every,day_of_month,start_at,until_at,occurrences,next_occurrence"
+
+type RecurringExpenseRepository interface {
+	Create(ctx context.Context, recurring entity.RecurringExpense) (string, error)
+	Update(ctx context.Context, recurring entity.RecurringExpense) error
+	Delete(ctx context.Context, id string) error
+	Get(ctx context.Context, id string) (entity.RecurringExpense, error)
+	List(ctx context.Context) ([]entity.RecurringExpense, error)
+	// Advance moves NextOccurrence forward to next, it never goes back
+	Advance(ctx context.Context, id string, next int) error
+}
+
+// recurringTemplate is the JSONB template column
+type recurringTemplate struct {
+	Kind              string            `json:"kind,omitempty"`
+	Amount            int64             `json:"amount,omitempty"`
+	Currency          string            `json:"currency,omitempty"`
+	Where             string            `json:"where,omitempty"`
+	Who               string            `json:"who,omitempty"`
+	What              string            `json:"what,omitempty"`
+	Tags              []string          `json:"tags,omitempty"`
+	CategoryId        string            `json:"categoryId,omitempty"`
+	PaymentMethodId   string            `json:"paymentMethodId,omitempty"`
+	ToPaymentMethodId string            `json:"toPaymentMethodId,omitempty"`
+	PlaceId           string            `json:"placeId,omitempty"`
+	PersonId          string            `json:"personId,omitempty"`
+	Split             *splitJSON        `json:"split,omitempty"`
+	Details           []detailJSON      `json:"details,omitempty"`
+	Metadata          map[string]string `json:"metadata,omitempty"`
+}
+
+func newRecurringTemplate(e entity.Expense) recurringTemplate {
+	t := recurringTemplate{
+		Kind:              string(e.Kind),
+		Amount:            e.Amount,
+		Currency:          string(e.Currency),
+		Where:             e.Where,
+		Who:               e.Who,
+		What:              e.What,
+		CategoryId:        e.CategoryId,
+		PaymentMethodId:   e.PaymentMethodId,
+		ToPaymentMethodId: e.ToPaymentMethodId,
+		PlaceId:           e.PlaceId,
+		PersonId:          e.PersonId,
+		Metadata:          e.Metadata,
+	}
+	if e.Tags != nil {
+		t.Tags = e.Tags.Value()
+	}
+	if e.Split != nil {
+		split := newSplitJSON(*e.Split)
+		t.Split = &split
+	}
+	if len(e.Details) > 0 {
+		t.Details = newDetailsJSON(e.Details)
+	}
+	return t
+}
+
+func (t recurringTemplate) toExpense() (entity.Expense, error) {
+	e := entity.Expense{
+		Kind:              entity.TransactionKind(t.Kind),
+		Amount:            t.Amount,
+		Currency:          entity.Currency(t.Currency),
+		Where:             t.Where,
+		Who:               t.Who,
+		What:              t.What,
+		CategoryId:        t.CategoryId,
+		PaymentMethodId:   t.PaymentMethodId,
+		ToPaymentMethodId: t.ToPaymentMethodId,
+		PlaceId:           t.PlaceId,
+		PersonId:          t.PersonId,
+		Metadata:          t.Metadata,
+	}
+	if len(t.Tags) > 0 {
+		tags, err := entity.NewTags(t.Tags...)
+		if err != nil {
+			return entity.Expense{}, err
+		}
+		e.Tags = tags
+	}
+	if t.Split != nil {
+		split := t.Split.toSplit()
+		e.Split = &split
+	}
+	if len(t.Details) > 0 {
+		details, err := toDetails(t.Details)
+		if err != nil {
+			return entity.Expense{}, err
+		}
+		e.Details = details
+	}
+	return e, nil
+}
+
+type recurringRepository struct {
+	db      DB
+	entropy io.Reader
+}
+
+func NewRecurringExpenseRepository(db DB) RecurringExpenseRepository {
+	return recurringRepository{
+		db:      db,
+		entropy: defaultEntropy(),
+	}
+}
+
+// recurringArgs returns the template and schedule args, from $2 on
+func recurringArgs(r entity.RecurringExpense) ([]interface{}, error) {
+	template, err := json.Marshal(newRecurringTemplate(r.Template))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+	}
+	s := r.Schedule
+	return []interface{}{
+		sql.Named("template", string(template)),
+		sql.Named("frequency", string(s.Frequency)),
+		sql.Named("every", s.Interval),
+		sql.Named("day_of_month", s.DayOfMonth),
+		sql.Named("start_at", s.Start.UTC()),
+		sql.Named("until_at", sql.NullTime{Time: s.Until.UTC(), Valid: !s.Until.IsZero()}),
+		sql.Named("occurrences", s.Count),
+	}, nil
+}
+
+func (r recurringRepository) Create(ctx context.Context, recurring entity.RecurringExpense) (string, error) {
+	if err := recurring.Validate(); err != nil {
+		return "", err
+	}
+	id, err := ulid.New(ulid.Timestamp(time.Now().UTC()), r.entropy)
+	if err != nil {
+		return "", err
+	}
+	args, err := recurringArgs(recurring)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now().UTC()
+	args = append([]interface{}{sql.Named("id", id.String())}, args...)
+	args = append(args, sql.Named("createdAt", now), sql.Named("updatedAt", now))
+	query := fmt.Sprintf(
+		"INSERT INTO %s (id,template,frequency,every,day_of_month,start_at,until_at,occurrences,createdAt,updatedAt) "+
+			"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10);",
+		RECURRING_TABLE_NAME,
+	)
+	log.Ctx(ctx).Debug().Msgf("runing: %v", query)
+	_, err = r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return "", translateError(err)
+	}
+	return id.String(), nil
+}
+
+// Update replaces the template and the schedule, the occurrences already created are kept
+func (r recurringRepository) Update(ctx context.Context, recurring entity.RecurringExpense) error {
+	if strings.TrimSpace(recurring.Id) == "" {
+		return entity.NewFieldError(nil, "id", "empty", "can't be empty")
+	}
+	if err := recurring.Validate(); err != nil {
+		return err
+	}
+	args, err := recurringArgs(recurring)
+	if err != nil {
+		return err
+	}
+	args = append([]interface{}{sql.Named("id", recurring.Id)}, args...)
+	args = append(args, sql.Named("updatedAt", time.Now().UTC()))
+	query := fmt.Sprintf(
+		"UPDATE %s SET template = $2, frequency = $3, every = $4, day_of_month = $5, start_at = $6, "+
+			"until_at = $7, occurrences = $8, updatedAt = $9 WHERE id = $1;",
+		RECURRING_TABLE_NAME,
+	)
+	log.Ctx(ctx).Debug().Msgf("runing: %v", query)
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return translateError(err)
+	}
+	return checkAffected(result, recurring.Id)
+}
+
+func (r recurringRepository) Advance(ctx context.Context, id string, next int) error {
+	query := fmt.Sprintf(
+		"UPDATE %s SET next_occurrence = $2, updatedAt = $3 WHERE id = $1 AND next_occurrence < $2;",
+		RECURRING_TABLE_NAME,
+	)
+	log.Ctx(ctx).Debug().Msgf("runing: %v", query)
+	_, err := r.db.ExecContext(ctx, query,
+		sql.Named("id", id),
+		sql.Named("next_occurrence", next),
+		sql.Named("updatedAt", time.Now().UTC()),
+	)
+	if err != nil {
+		return fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+	}
+	return nil
+}
+
+// Delete keeps the expenses already created, they only lose the link to the recurring expense
+func (r recurringRepository) Delete(ctx context.Context, id string) error {
+	if strings.TrimSpace(id) == "" {
+		return entity.NewFieldError(nil, "id", "empty", "can't be empty")
+	}
+	query := fmt.Sprintf("DELETE FROM %s WHERE id = $1;", RECURRING_TABLE_NAME)
+	log.Ctx(ctx).Debug().Msgf("runing: %v", query)
+	result, err := r.db.ExecContext(ctx, query, sql.Named("id", id))
+	if err != nil {
+		return fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+	}
+	return checkAffected(result, id)
+}
+
+func (r recurringRepository) Get(ctx context.Context, id string) (entity.RecurringExpense, error) {
+	if strings.TrimSpace(id) == "" {
+		return entity.RecurringExpense{}, entity.NewFieldError(nil, "id", "empty", "can't be empty")
+	}
+	query := fmt.Sprintf("SELECT id,%s FROM %s WHERE id = $1;", recurringColumns, RECURRING_TABLE_NAME)
+	log.Ctx(ctx).Debug().Msgf("runing: %v", query)
+	recurring, err := scanRecurring(r.db.QueryRowContext(ctx, query, sql.Named("id", id)).Scan)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entity.RecurringExpense{}, fmt.Errorf("id %s was %w", id, entity.ErrNotFound)
+		}
+		return entity.RecurringExpense{}, fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+	}
+	return recurring, nil
+}
+
+func (r recurringRepository) List(ctx context.Context) ([]entity.RecurringExpense, error) {
+	query := fmt.Sprintf("SELECT id,%s FROM %s ORDER BY start_at, id;", recurringColumns, RECURRING_TABLE_NAME)
+	log.Ctx(ctx).Debug().Msgf("runing: %v", query)
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+	}
+	defer rows.Close()
+	recurrings := make([]entity.RecurringExpense, 0)
+	for rows.Next() {
+		recurring, err := scanRecurring(rows.Scan)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+		}
+		recurrings = append(recurrings, recurring)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+	}
+	return recurrings, nil
+}
+
+func scanRecurring(scan func(...interface{}) error) (entity.RecurringExpense, error) {
+	var (
+		r         entity.RecurringExpense
+		template  []byte
+		frequency string
+		until     sql.NullTime
+	)
+	err := scan(&r.Id, &template, &frequency, &r.Schedule.Interval, &r.Schedule.DayOfMonth,
+		&r.Schedule.Start, &until, &r.Schedule.Count, &r.NextOccurrence)
+	if err != nil {
+		return entity.RecurringExpense{}, err
+	}
+	var t recurringTemplate
+	if err := json.Unmarshal(template, &t); err != nil {
+		return entity.RecurringExpense{}, err
+	}
+	if r.Template, err = t.toExpense(); err != nil {
+		return entity.RecurringExpense{}, err
+	}
+	r.Schedule.Frequency = entity.Frequency(frequency)
+	r.Schedule.Start = r.Schedule.Start.UTC()
+	if until.Valid {
+		r.Schedule.Until = until.Time.UTC()
+	}
+	return r, nil
+}
+
+func checkAffected(result sql.Result, id string) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("id %s was %w", id, entity.ErrNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/axpira/backend/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func newRecurringRent() entity.RecurringExpense {
+	return entity.RecurringExpense{
+		Template: entity.Expense{Kind: entity.EXPENSE, Amount: 150000, Currency: "BRL", What: "rent", Tags: entity.MustNewTags("home")},
+		Schedule: entity.Schedule{Frequency: entity.MONTHLY, DayOfMonth: 5, Start: time.Date(2021, 1, 5, 0, 0, 0, 0, time.UTC), Count: 12},
+	}
+}
+
+const rentTemplate = `{"kind":"expense","amount":150000,"currency":"BRL","what":"rent","tags":["home"]}`
+
+func TestRecurringCreate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	repo := NewRecurringExpenseRepository(db)
+	ctx := context.Background()
+
+	invalid := newRecurringRent()
+	invalid.Schedule.Start = time.Time{}
+	_, err = repo.Create(ctx, invalid)
+	fieldErrors := entity.UnwrapFieldErrors(err)
+	if assert.Len(t, fieldErrors, 1, "must validate the schedule") {
+		assert.Equal(t, "start", fieldErrors[0].Field())
+	}
+
+	mock.ExpectExec("INSERT INTO tb_recurring_expense \\(id,template,frequency,every,day_of_month,start_at,until_at,occurrences,createdAt,updatedAt\\) "+
+		"VALUES \\(\\$1, \\$2, \\$3, \\$4, \\$5, \\$6, \\$7, \\$8, \\$9, \\$10\\);").
+		WithArgs(
+			anyULID{},
+			sql.Named("template", rentTemplate),
+			sql.Named("frequency", "monthly"),
+			sql.Named("every", 0),
+			sql.Named("day_of_month", 5),
+			sql.Named("start_at", time.Date(2021, 1, 5, 0, 0, 0, 0, time.UTC)),
+			sql.Named("until_at", sql.NullTime{Time: time.Time{}.UTC()}),
+			sql.Named("occurrences", 12),
+			timeMatch{time.Now().UTC()},
+			timeMatch{time.Now().UTC()},
+		).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	id, err := repo.Create(ctx, newRecurringRent())
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.NoError(t, err)
+	assert.NotEmpty(t, id)
+}
+
+func TestRecurringUpdate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	repo := NewRecurringExpenseRepository(db)
+	ctx := context.Background()
+
+	assert.Error(t, repo.Update(ctx, newRecurringRent()), "must return error on empty id")
+
+	tests := map[string]struct {
+		affected int64
+		wantErr  error
+	}{
+		"must update": {
+			affected: 1,
+		},
+		"must return not found": {
+			wantErr: entity.ErrNotFound,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			mock.ExpectExec("UPDATE tb_recurring_expense SET template = \\$2, frequency = \\$3, every = \\$4, day_of_month = \\$5, " +
+				"start_at = \\$6, until_at = \\$7, occurrences = \\$8, updatedAt = \\$9 WHERE id = \\$1;").
+				WillReturnResult(sqlmock.NewResult(0, tc.affected))
+			recurring := newRecurringRent()
+			recurring.Id = "rent"
+			err := repo.Update(ctx, recurring)
+			assert.NoError(t, mock.ExpectationsWereMet())
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestRecurringAdvance(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	repo := NewRecurringExpenseRepository(db)
+	ctx := context.Background()
+
+	mock.ExpectExec("UPDATE tb_recurring_expense SET next_occurrence = \\$2, updatedAt = \\$3 WHERE id = \\$1 AND next_occurrence < \\$2;").
+		WithArgs(sql.Named("id", "rent"), sql.Named("next_occurrence", 4), timeMatch{time.Now().UTC()}).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, repo.Advance(ctx, "rent", 4))
+
+	mock.ExpectExec("UPDATE tb_recurring_expense SET next_occurrence").
+		WillReturnError(errors.New(String(10)))
+	assert.ErrorIs(t, repo.Advance(ctx, "rent", 5), entity.ErrUnknown)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRecurringGet(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	repo := NewRecurringExpenseRepository(db)
+	ctx := context.Background()
+	const wantQuery = "SELECT id,template,frequency,every,day_of_month,start_at,until_at,occurrences,next_occurrence " +
+		"FROM tb_recurring_expense WHERE id = \\$1;"
+	columns := []string{"id", "template", "frequency", "every", "day_of_month", "start_at", "until_at", "occurrences", "next_occurrence"}
+
+	mock.ExpectQuery(wantQuery).
+		WithArgs(sql.Named("id", "rent")).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("rent", []byte(rentTemplate), "monthly", 0, 5, time.Date(2021, 1, 5, 0, 0, 0, 0, time.UTC), nil, 12, 3))
+	got, err := repo.Get(ctx, "rent")
+	assert.NoError(t, err)
+	want := newRecurringRent()
+	want.Id = "rent"
+	want.NextOccurrence = 3
+	assert.Equal(t, want, got)
+
+	mock.ExpectQuery(wantQuery).
+		WithArgs(sql.Named("id", "unknown")).
+		WillReturnRows(sqlmock.NewRows(columns))
+	_, err = repo.Get(ctx, "unknown")
+	assert.ErrorIs(t, err, entity.ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,79 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/axpira/backend/entity"
+	"github.com/rs/zerolog/log"
+)
+
+type ReportRepository interface {
+	Summary(ctx context.Context, filter *entity.ExpenseFilter, group entity.SummaryGroup) ([]entity.Summary, error)
+}
+
+type reportRepository struct {
+	db DB
+}
+
+func NewReportRepository(db DB) ReportRepository {
+	return reportRepository{db: db}
+}
+
+// summaryKeySQL is the key of each group, with the joins it needs. The time groups are in UTC,
+// who and where are the URN of the person and the place linked, the free text when there isn't one
+var summaryKeySQL = map[entity.SummaryGroup]struct {
+	key  string
+	join string
+}{
+	entity.BY_MONTH:    {key: "to_char(timestamp AT TIME ZONE 'UTC', 'YYYY-MM')"},
+	entity.BY_WEEK:     {key: "to_char(date_trunc('week', timestamp AT TIME ZONE 'UTC'), 'YYYY-MM-DD')"},
+	entity.BY_CATEGORY: {key: "coalesce(category_id, '')"},
+	entity.BY_TAG:      {key: "coalesce(t.tag, '')", join: " LEFT JOIN LATERAL unnest(tags) AS t(tag) ON true"},
+	entity.BY_WHO:      {key: "coalesce('urn:person:' || person_id, who, '')"},
+	entity.BY_WHERE:    {key: "coalesce('urn:place:' || place_id, place, '')"},
+}
+
+func (r reportRepository) Summary(ctx context.Context, filter *entity.ExpenseFilter, group entity.SummaryGroup) ([]entity.Summary, error) {
+	l := log.Ctx(ctx)
+	if err := group.Validate(); err != nil {
+		return nil, err
+	}
+	where, err := newExpenseWhere(filter)
+	if err != nil {
+		return nil, err
+	}
+	s := summaryKeySQL[group]
+	query := fmt.Sprintf(
+		"SELECT %s AS key, currency, sum(amount), count(*) FROM %s%s%s GROUP BY 1, 2 ORDER BY 1, 2;",
+		s.key, TABLE_NAME, s.join, where.String(),
+	)
+	if e := l.Debug(); e.Enabled() {
+		for i, a := range where.args {
+			n := a.(sql.NamedArg)
+			e = e.Str(fmt.Sprintf("param_%d_%s", i+1, n.Name), fmt.Sprintf("%v", n.Value))
+		}
+		e.Msgf("runing: %v", query)
+	}
+	rows, err := r.db.QueryContext(ctx, query, where.args...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+	}
+	defer rows.Close()
+
+	summaries := make([]entity.Summary, 0)
+	for rows.Next() {
+		var summary entity.Summary
+		var currency string
+		if err := rows.Scan(&summary.Key, &currency, &summary.Total, &summary.Count); err != nil {
+			return nil, fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+		}
+		summary.Currency = entity.Currency(currency)
+		summaries = append(summaries, summary)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", entity.ErrUnknown, err)
+	}
+	return summaries, nil
+}
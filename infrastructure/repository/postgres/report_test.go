@@ -0,0 +1,117 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/axpira/backend/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReportSummary(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	repo := NewReportRepository(db)
+	ctx := context.Background()
+	from := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	columns := []string{"key", "currency", "sum", "count"}
+
+	_, err = repo.Summary(ctx, nil, "day")
+	fieldErrors := entity.UnwrapFieldErrors(err)
+	if assert.Len(t, fieldErrors, 1, "must validate the group") {
+		assert.Equal(t, "groupBy", fieldErrors[0].Field())
+	}
+
+	tests := map[string]struct {
+		filter    []func(*entity.ExpenseFilter) error
+		group     entity.SummaryGroup
+		wantQuery string
+		wantArgs  []driver.Value
+		rows      *sqlmock.Rows
+		mockErr   error
+		want      []entity.Summary
+		wantErr   error
+	}{
+		"must total by month": {
+			filter: []func(*entity.ExpenseFilter) error{entity.FilterKind(entity.EXPENSE), entity.FilterWhen(entity.GE, from)},
+			group:  entity.BY_MONTH,
+			wantQuery: "SELECT to_char\\(timestamp AT TIME ZONE 'UTC', 'YYYY-MM'\\) AS key, currency, sum\\(amount\\), count\\(\\*\\) " +
+				"FROM tb_expense WHERE kind IN \\(\\$1\\) AND timestamp >= \\$2 GROUP BY 1, 2 ORDER BY 1, 2;",
+			wantArgs: []driver.Value{sql.Named("kind", "expense"), sql.Named("timestamp", from)},
+			rows: sqlmock.NewRows(columns).
+				AddRow("2021-01", "BRL", 150000, 12).
+				AddRow("2021-01", "USD", 2000, 1).
+				AddRow("2021-02", "BRL", 90000, 7),
+			want: []entity.Summary{
+				{Key: "2021-01", Currency: "BRL", Total: 150000, Count: 12},
+				{Key: "2021-01", Currency: "USD", Total: 2000, Count: 1},
+				{Key: "2021-02", Currency: "BRL", Total: 90000, Count: 7},
+			},
+		},
+		"must total by tag including untagged": {
+			filter: []func(*entity.ExpenseFilter) error{entity.FilterTag(entity.REGEX, "^trip")},
+			group:  entity.BY_TAG,
+			wantQuery: "SELECT coalesce\\(t.tag, ''\\) AS key, currency, sum\\(amount\\), count\\(\\*\\) " +
+				"FROM tb_expense LEFT JOIN LATERAL unnest\\(tags\\) AS t\\(tag\\) ON true " +
+				"WHERE EXISTS \\(SELECT 1 FROM unnest\\(tags\\) AS tag WHERE tag ~ \\$1\\) GROUP BY 1, 2 ORDER BY 1, 2;",
+			wantArgs: []driver.Value{sql.Named("tags", "^trip")},
+			rows: sqlmock.NewRows(columns).
+				AddRow("", "BRL", 1000, 1).
+				AddRow("trip", "BRL", 50000, 3),
+			want: []entity.Summary{
+				{Key: "", Currency: "BRL", Total: 1000, Count: 1},
+				{Key: "trip", Currency: "BRL", Total: 50000, Count: 3},
+			},
+		},
+		"must total by place": {
+			group:     entity.BY_WHERE,
+			wantQuery: "SELECT coalesce\\('urn:place:' \\|\\| place_id, place, ''\\) AS key, currency, sum\\(amount\\), count\\(\\*\\) FROM tb_expense GROUP BY 1, 2 ORDER BY 1, 2;",
+			rows: sqlmock.NewRows(columns).
+				AddRow("bakery", "BRL", 800, 1).
+				AddRow("urn:place:1", "BRL", 4500, 3),
+			want: []entity.Summary{
+				{Key: "bakery", Currency: "BRL", Total: 800, Count: 1},
+				{Key: "urn:place:1", Currency: "BRL", Total: 4500, Count: 3},
+			},
+		},
+		"must return error on database error": {
+			group:     entity.BY_WHO,
+			wantQuery: "SELECT coalesce\\('urn:person:' \\|\\| person_id, who, ''\\) AS key, currency, sum\\(amount\\), count\\(\\*\\) FROM tb_expense GROUP BY 1, 2 ORDER BY 1, 2;",
+			mockErr:   errors.New(String(10)),
+			wantErr:   entity.ErrUnknown,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			filter, err := entity.NewExpenseFilter(tc.filter...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			q := mock.ExpectQuery(tc.wantQuery)
+			if tc.wantArgs != nil {
+				q = q.WithArgs(tc.wantArgs...)
+			}
+			if tc.mockErr != nil {
+				q.WillReturnError(tc.mockErr)
+			} else {
+				q.WillReturnRows(tc.rows)
+			}
+			got, err := repo.Summary(ctx, filter, tc.group)
+			assert.NoError(t, mock.ExpectationsWereMet())
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
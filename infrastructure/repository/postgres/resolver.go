@@ -0,0 +1,113 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgtype"
+	"github.com/rs/zerolog/log"
+)
+
+// normalizedSQL is entity.NormalizeName in SQL
+func normalizedSQL(expr string) string {
+	return fmt.Sprintf("lower(trim(regexp_replace(%s, '\\s+', ' ', 'g')))", expr)
+}
+
+// aliasesArg trims the aliases and never returns a NULL array, the column default is an empty one
+func aliasesArg(aliases []string) pgtype.TextArray {
+	trimmed := make([]string, len(aliases))
+	for i, alias := range aliases {
+		trimmed[i] = strings.TrimSpace(alias)
+	}
+	arr := pgtype.TextArray{}
+	arr.Set(trimmed)
+	return arr
+}
+
+func aliasesValue(arr pgtype.TextArray) []string {
+	if len(arr.Elements) == 0 {
+		return nil
+	}
+	aliases := make([]string, len(arr.Elements))
+	for i, alias := range arr.Elements {
+		aliases[i] = alias.String
+	}
+	return aliases
+}
+
+// nameResolver links the expenses to the entities of table whose name or aliases match
+// the free text of textColumn. When the aliases of two entities match, any of them is linked
+type nameResolver struct {
+	table      string
+	textColumn string
+	idColumn   string
+}
+
+var (
+	placeResolver  = nameResolver{table: PLACE_TABLE_NAME, textColumn: "place", idColumn: "place_id"}
+	personResolver = nameResolver{table: PERSON_TABLE_NAME, textColumn: "who", idColumn: "person_id"}
+)
+
+// match is the condition of the expense e matching the entity r
+func (n nameResolver) match() string {
+	return fmt.Sprintf("%s IN (SELECT %s FROM unnest(array_append(r.aliases, r.name)) AS n)",
+		normalizedSQL("e."+n.textColumn), normalizedSQL("n"))
+}
+
+// unresolved returns the distinct free texts without an entity, as first written
+func (n nameResolver) unresolved(ctx context.Context, db DB) ([]string, error) {
+	query := fmt.Sprintf(
+		"SELECT DISTINCT ON (%[1]s) trim(e.%[2]s) FROM %[3]s e WHERE e.%[4]s IS NULL AND %[1]s <> '' "+
+			"AND NOT EXISTS (SELECT 1 FROM %[5]s r WHERE %[6]s) ORDER BY %[1]s, trim(e.%[2]s);",
+		normalizedSQL("e."+n.textColumn), n.textColumn, TABLE_NAME, n.idColumn, n.table, n.match(),
+	)
+	log.Ctx(ctx).Debug().Msgf("runing: %v", query)
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+	names := make([]string, 0)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrUnknown, err)
+		}
+		names = append(names, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnknown, err)
+	}
+	return names, nil
+}
+
+// resolve creates an entity for every unresolved text when create is set, then
+// links the expenses without one and returns how many were linked
+func (n nameResolver) resolve(ctx context.Context, db DB, create func(name string) error) (int64, error) {
+	if create != nil {
+		names, err := n.unresolved(ctx, db)
+		if err != nil {
+			return 0, err
+		}
+		for _, name := range names {
+			if err := create(name); err != nil {
+				return 0, err
+			}
+		}
+	}
+	query := fmt.Sprintf("UPDATE %s e SET %s = r.id, updatedAt = $1 FROM %s r WHERE e.%s IS NULL AND %s;",
+		TABLE_NAME, n.idColumn, n.table, n.idColumn, n.match())
+	log.Ctx(ctx).Debug().Msgf("runing: %v", query)
+	result, err := db.ExecContext(ctx, query, sql.Named("updatedAt", time.Now().UTC()))
+	if err != nil {
+		return 0, translateError(err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrUnknown, err)
+	}
+	return affected, nil
+}
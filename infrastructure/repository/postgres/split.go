@@ -0,0 +1,55 @@
+package postgres
+
+import (
+	"encoding/json"
+
+	"github.com/axpira/backend/entity"
+)
+
+// splitJSON is the JSONB splits column
+type splitJSON struct {
+	Kind   string      `json:"kind"`
+	Shares []shareJSON `json:"shares"`
+}
+
+type shareJSON struct {
+	Who     string `json:"who"`
+	Percent int64  `json:"percent,omitempty"`
+	Amount  int64  `json:"amount"`
+}
+
+func newSplitJSON(s entity.Split) splitJSON {
+	res := splitJSON{
+		Kind:   string(s.Kind),
+		Shares: make([]shareJSON, len(s.Shares)),
+	}
+	for i, share := range s.Shares {
+		res.Shares[i] = shareJSON(share)
+	}
+	return res
+}
+
+// String never fails, the struct only has strings and numbers
+func (s splitJSON) String() string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+func (s splitJSON) toSplit() entity.Split {
+	res := entity.Split{
+		Kind:   entity.SplitKind(s.Kind),
+		Shares: make([]entity.Share, len(s.Shares)),
+	}
+	for i, share := range s.Shares {
+		res.Shares[i] = entity.Share(share)
+	}
+	return res
+}
+
+func parseSplitJSON(value string) (entity.Split, error) {
+	var s splitJSON
+	if err := json.Unmarshal([]byte(value), &s); err != nil {
+		return entity.Split{}, err
+	}
+	return s.toSplit(), nil
+}
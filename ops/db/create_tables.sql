@@ -1,10 +1,143 @@
+CREATE TABLE tb_category (
+    id VARCHAR(128) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    parent_id VARCHAR(128),
+    createdAt TIMESTAMP WITH TIME ZONE,
+    updatedAt TIMESTAMP WITH TIME ZONE,
+    CONSTRAINT fk_category_parent FOREIGN KEY (parent_id) REFERENCES tb_category (id)
+);
+
+CREATE INDEX ix_category_parent ON tb_category (parent_id);
+
+CREATE TABLE tb_payment_method (
+    id VARCHAR(128) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    type VARCHAR(32) NOT NULL,
+    createdAt TIMESTAMP WITH TIME ZONE,
+    updatedAt TIMESTAMP WITH TIME ZONE
+);
+
+CREATE TABLE tb_place (
+    id VARCHAR(128) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    aliases TEXT[] NOT NULL DEFAULT '{}',
+    latitude DOUBLE PRECISION,
+    longitude DOUBLE PRECISION,
+    createdAt TIMESTAMP WITH TIME ZONE,
+    updatedAt TIMESTAMP WITH TIME ZONE
+);
+
+CREATE TABLE tb_person (
+    id VARCHAR(128) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    aliases TEXT[] NOT NULL DEFAULT '{}',
+    createdAt TIMESTAMP WITH TIME ZONE,
+    updatedAt TIMESTAMP WITH TIME ZONE
+);
+
+CREATE TABLE tb_budget (
+    id VARCHAR(128) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    category_id VARCHAR(128),
+    tag TEXT,
+    period VARCHAR(16) NOT NULL CHECK (period IN ('weekly', 'monthly', 'yearly')),
+    limit_amount BIGINT NOT NULL CHECK (limit_amount > 0),
+    currency CHAR(3) NOT NULL,
+    rollover BOOLEAN NOT NULL DEFAULT FALSE,
+    start_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    createdAt TIMESTAMP WITH TIME ZONE,
+    updatedAt TIMESTAMP WITH TIME ZONE,
+    CONSTRAINT fk_budget_category FOREIGN KEY (category_id) REFERENCES tb_category (id) ON DELETE CASCADE,
+    CONSTRAINT ck_budget_scope CHECK ((category_id IS NULL) <> (tag IS NULL))
+);
+
+CREATE TABLE tb_recurring_expense (
+    id VARCHAR(128) PRIMARY KEY,
+    template JSONB NOT NULL,
+    frequency VARCHAR(16) NOT NULL,
+    every INT NOT NULL DEFAULT 0,
+    day_of_month INT NOT NULL DEFAULT 0,
+    start_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    until_at TIMESTAMP WITH TIME ZONE,
+    occurrences INT NOT NULL DEFAULT 0,
+    next_occurrence INT NOT NULL DEFAULT 0,
+    createdAt TIMESTAMP WITH TIME ZONE,
+    updatedAt TIMESTAMP WITH TIME ZONE
+);
+
 CREATE TABLE tb_expense (
     id VARCHAR(128) PRIMARY KEY,
     amount BIGINT,
+    currency CHAR(3) NOT NULL,
     timestamp TIMESTAMP WITH TIME ZONE,
     place VARCHAR(255),
     who VARCHAR(255),
     what VARCHAR(255),
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    category_id VARCHAR(128),
+    payment_method_id VARCHAR(128),
+    to_payment_method_id VARCHAR(128),
+    kind VARCHAR(16) NOT NULL DEFAULT 'expense' CHECK (kind IN ('expense', 'income', 'transfer', 'settlement')),
+    recurring_id VARCHAR(128),
+    occurrence INT,
+    splits JSONB,
+    metadata JSONB,
+    place_id VARCHAR(128),
+    person_id VARCHAR(128),
+    external_id VARCHAR(255),
     createdAt TIMESTAMP WITH TIME ZONE,
-    updatedAt TIMESTAMP WITH TIME ZONE
+    updatedAt TIMESTAMP WITH TIME ZONE,
+    CONSTRAINT fk_expense_category FOREIGN KEY (category_id) REFERENCES tb_category (id),
+    CONSTRAINT fk_expense_payment_method FOREIGN KEY (payment_method_id) REFERENCES tb_payment_method (id),
+    CONSTRAINT fk_expense_to_payment_method FOREIGN KEY (to_payment_method_id) REFERENCES tb_payment_method (id),
+    CONSTRAINT fk_expense_place FOREIGN KEY (place_id) REFERENCES tb_place (id),
+    CONSTRAINT fk_expense_person FOREIGN KEY (person_id) REFERENCES tb_person (id),
+    CONSTRAINT fk_expense_recurring FOREIGN KEY (recurring_id) REFERENCES tb_recurring_expense (id) ON DELETE SET NULL,
+    CONSTRAINT uq_expense_recurring_occurrence UNIQUE (recurring_id, occurrence),
+    CONSTRAINT uq_expense_external_id UNIQUE (external_id)
+);
+
+CREATE INDEX ix_expense_tags ON tb_expense USING GIN (tags);
+CREATE INDEX ix_expense_metadata ON tb_expense USING GIN (metadata);
+CREATE INDEX ix_expense_category ON tb_expense (category_id);
+CREATE INDEX ix_expense_place ON tb_expense (place_id);
+CREATE INDEX ix_expense_person ON tb_expense (person_id);
+CREATE INDEX ix_expense_payment_method ON tb_expense (payment_method_id, timestamp);
+CREATE INDEX ix_expense_kind ON tb_expense (kind, timestamp);
+CREATE INDEX ix_expense_when ON tb_expense ((coalesce(timestamp, 'epoch'::timestamptz)), id);
+CREATE INDEX ix_expense_amount ON tb_expense ((coalesce(amount, 0)), id);
+CREATE INDEX ix_expense_created_at ON tb_expense ((coalesce(createdAt, 'epoch'::timestamptz)), id);
+
+CREATE TABLE tb_expense_detail (
+    id BIGSERIAL PRIMARY KEY,
+    expense_id VARCHAR(128) NOT NULL,
+    position INT NOT NULL,
+    description VARCHAR(255) NOT NULL,
+    quantity BIGINT NOT NULL CHECK (quantity > 0),
+    unit_price BIGINT NOT NULL,
+    category_id VARCHAR(128),
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    CONSTRAINT fk_expense_detail_expense FOREIGN KEY (expense_id) REFERENCES tb_expense (id) ON DELETE CASCADE,
+    CONSTRAINT fk_expense_detail_category FOREIGN KEY (category_id) REFERENCES tb_category (id)
+);
+
+CREATE INDEX ix_expense_detail_expense ON tb_expense_detail (expense_id, position);
+
+CREATE TABLE tb_exchange_rate (
+    date DATE NOT NULL,
+    from_currency CHAR(3) NOT NULL,
+    to_currency CHAR(3) NOT NULL,
+    rate NUMERIC(24, 12) NOT NULL CHECK (rate > 0),
+    createdAt TIMESTAMP WITH TIME ZONE,
+    updatedAt TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY (from_currency, to_currency, date)
+);
+
+CREATE TABLE tb_idempotency_key (
+    key VARCHAR(255) PRIMARY KEY,
+    request_hash CHAR(64) NOT NULL,
+    status INT NOT NULL DEFAULT 0,
+    content_type VARCHAR(255),
+    response BYTEA,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
 );